@@ -0,0 +1,18 @@
+terraform {
+  required_providers {
+    cloudrift = {
+      source = "berops/cloudrift"
+    }
+  }
+}
+
+provider "cloudrift" {
+  # Set CLOUDRIFT_TOKEN env var or uncomment:
+  # token = "rift_..."
+}
+
+data "cloudrift_account" "current" {}
+
+output "balance" {
+  value = data.cloudrift_account.current.balance
+}
@@ -0,0 +1,35 @@
+terraform {
+  required_providers {
+    cloudrift = {
+      source = "berops/cloudrift"
+    }
+  }
+}
+
+provider "cloudrift" {
+  # Set CLOUDRIFT_TOKEN env var or uncomment:
+  # token = "rift_..."
+}
+
+variable "instance_id" {
+  type = string
+}
+
+data "cloudrift_instance_metrics" "vm" {
+  instance_ids = [var.instance_id]
+
+  lifecycle {
+    postcondition {
+      condition     = self.instances[0].gpu_count > 0
+      error_message = "No GPU of the instance reports metrics."
+    }
+    postcondition {
+      condition     = alltrue([for g in self.instances[0].gpus : coalesce(g.gpu_utilization_percent, 0) < 5])
+      error_message = "The GPUs of the instance are not idle."
+    }
+  }
+}
+
+output "gpu_temperatures" {
+  value = [for g in data.cloudrift_instance_metrics.vm.instances[0].gpus : g.temperature_celsius]
+}
@@ -0,0 +1,33 @@
+terraform {
+  required_providers {
+    cloudrift = {
+      source = "berops/cloudrift"
+    }
+  }
+}
+
+provider "cloudrift" {
+  # Set CLOUDRIFT_TOKEN env var or uncomment:
+  # token = "rift_..."
+}
+
+# Environments left behind by a terminated Virtual Machine named "trainer".
+data "cloudrift_saved_environments" "trainer" {
+  name = "trainer"
+}
+
+resource "cloudrift_ssh_key" "primary" {
+  name       = "primary"
+  public_key = trimspace(file("~/.ssh/id_ed25519.pub"))
+}
+
+# Boot from the saved disk instead of a fresh OS. The instance type must be
+# the one of the environment.
+resource "cloudrift_virtual_machine" "trainer" {
+  name                 = "trainer"
+  recipe               = "ubuntu"
+  datacenter           = "us-east-nc-nr-1"
+  instance_type        = data.cloudrift_saved_environments.trainer.saved_environments[0].original_variant_name
+  ssh_key_id           = cloudrift_ssh_key.primary.id
+  reuse_environment_id = data.cloudrift_saved_environments.trainer.saved_environments[0].id
+}
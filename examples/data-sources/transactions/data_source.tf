@@ -0,0 +1,28 @@
+terraform {
+  required_providers {
+    cloudrift = {
+      source = "berops/cloudrift"
+    }
+  }
+}
+
+provider "cloudrift" {
+  # Set CLOUDRIFT_TOKEN env var or uncomment:
+  # token = "rift_..."
+}
+
+data "cloudrift_transactions" "january" {
+  from = "2025-01-01T00:00:00Z"
+  to   = "2025-01-31T23:59:59Z"
+}
+
+output "total_spent" {
+  value = data.cloudrift_transactions.january.total_spent
+}
+
+output "usage_by_instance" {
+  value = {
+    for t in data.cloudrift_transactions.january.transactions : t.resource_name => t.amount...
+    if t.kind == "Usage"
+  }
+}
@@ -0,0 +1,11 @@
+ephemeral "cloudrift_instance_credentials" "machine0" {
+  instance_id = cloudrift_virtual_machine.machine0.id
+}
+
+# The credentials are only available during the run, e.g. to configure a
+# provisioner or another provider, and are never written to the state.
+provider "ssh" {
+  host     = ephemeral.cloudrift_instance_credentials.machine0.host
+  user     = ephemeral.cloudrift_instance_credentials.machine0.username
+  password = ephemeral.cloudrift_instance_credentials.machine0.password
+}
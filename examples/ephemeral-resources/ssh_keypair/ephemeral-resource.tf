@@ -0,0 +1,31 @@
+ephemeral "cloudrift_ssh_keypair" "ci" {
+  algorithm = "ed25519"
+}
+
+# The public key is uploaded through the write-only attribute, ephemeral
+# values cannot be stored in public_key.
+resource "cloudrift_ssh_key" "ci" {
+  name                  = "ci"
+  public_key_wo         = ephemeral.cloudrift_ssh_keypair.ci.public_key
+  public_key_wo_version = 1
+}
+
+# The private key only lives for the duration of the run, enough for a
+# provisioner to log into the instance created along with the key.
+resource "cloudrift_virtual_machine" "ci" {
+  recipe        = "ubuntu"
+  datacenter    = "us-east-nc-nr-1"
+  instance_type = "rtx49-10c-kn.1"
+  ssh_key_id    = cloudrift_ssh_key.ci.id
+
+  provisioner "remote-exec" {
+    inline = ["nvidia-smi"]
+
+    connection {
+      type        = "ssh"
+      host        = self.public_ip
+      user        = self.virtual_machines[0].username
+      private_key = ephemeral.cloudrift_ssh_keypair.ci.private_key_openssh
+    }
+  }
+}
@@ -0,0 +1,15 @@
+data "cloudrift_instance_types" "all" {}
+
+locals {
+  variant = data.cloudrift_instance_types.all.instance_types[0].variants[0]
+}
+
+# Cost of the instance running the whole month
+output "monthly_cost" {
+  value = provider::cloudrift::monthly_cost(local.variant.cost_per_hour, null)
+}
+
+# Cost of the instance running 8 hours a day, 22 days a month
+output "office_hours_cost" {
+  value = provider::cloudrift::monthly_cost(local.variant.cost_per_hour, 8 * 22)
+}
@@ -0,0 +1,8 @@
+locals {
+  instance_type = provider::cloudrift::parse_instance_type("rtx49-7-50-500-nr.1")
+}
+
+# "rtx49 x1"
+output "gpus" {
+  value = "${local.instance_type.gpu_family} x${local.instance_type.count}"
+}
@@ -0,0 +1,6 @@
+data "cloudrift_instance_types" "all" {}
+
+# VRAM per GPU of the first variant, in GiB
+output "vram_gib" {
+  value = provider::cloudrift::vram_gib(data.cloudrift_instance_types.all.instance_types[0].variants[0].vram)
+}
@@ -0,0 +1,3 @@
+list "cloudrift_ssh_key" "all" {
+  provider = cloudrift
+}
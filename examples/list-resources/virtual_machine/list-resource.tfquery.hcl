@@ -0,0 +1,9 @@
+# Lists every running instance, run with `terraform query -generate-config-out=generated.tf`
+# to generate the resource and import blocks of the instances not yet managed.
+list "cloudrift_virtual_machine" "running" {
+  provider = cloudrift
+
+  config {
+    statuses = ["Active"]
+  }
+}
@@ -0,0 +1,3 @@
+# The ID is "personal" for the personal account, or the ID of the team
+# configured on the provider.
+terraform import cloudrift_auto_top_up.default personal
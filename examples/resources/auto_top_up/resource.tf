@@ -0,0 +1,24 @@
+terraform {
+  required_providers {
+    cloudrift = {
+      source = "berops/cloudrift"
+    }
+  }
+}
+
+provider "cloudrift" {
+  # Set CLOUDRIFT_TOKEN env var or uncomment:
+  # token = "rift_..."
+}
+
+# Top up $50 whenever the balance falls below $10, at most $200 per month.
+resource "cloudrift_auto_top_up" "default" {
+  enabled             = true
+  threshold_cents     = 1000
+  top_up_amount_cents = 5000
+  monthly_cap_cents   = 20000
+}
+
+output "auto_top_up_status" {
+  value = cloudrift_auto_top_up.default.status
+}
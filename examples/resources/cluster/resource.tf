@@ -0,0 +1,31 @@
+terraform {
+  required_providers {
+    cloudrift = {
+      source = "berops/cloudrift"
+    }
+  }
+}
+
+provider "cloudrift" {
+  # Set CLOUDRIFT_TOKEN env var or uncomment:
+  # token = "rift_..."
+}
+
+resource "cloudrift_ssh_key" "workers" {
+  name       = "workers"
+  public_key = trimspace(file("~/.ssh/id_ed25519.pub"))
+}
+
+# Four identical workers, change instance_count to scale the cluster in place.
+resource "cloudrift_cluster" "workers" {
+  name           = "workers"
+  instance_count = 4
+  recipe         = "ubuntu"
+  datacenter     = "us-east-nc-nr-1"
+  instance_type  = "rtx49-10c-kn.1"
+  ssh_key_id     = cloudrift_ssh_key.workers.id
+}
+
+output "worker_ips" {
+  value = cloudrift_cluster.workers.instances[*].public_ip
+}
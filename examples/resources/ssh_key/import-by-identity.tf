@@ -0,0 +1,6 @@
+import {
+  to = cloudrift_ssh_key.primary
+  identity = {
+    id = "1632996d-dec0-4c6f-90ad-519e0fb2a2e7"
+  }
+}
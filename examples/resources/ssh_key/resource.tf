@@ -15,3 +15,11 @@ resource "cloudrift_ssh_key" "primary" {
   name       = "primary"
   public_key = trimspace(file("~/.ssh/id_ed25519.pub"))
 }
+
+# Let the provider generate an ed25519 key pair, only the public key is
+# uploaded and stored in the state.
+resource "cloudrift_ssh_key" "generated" {
+  name             = "generated"
+  generate         = true
+  private_key_file = "${path.module}/id_ed25519"
+}
@@ -0,0 +1,7 @@
+import {
+  to = cloudrift_virtual_machine.machine0
+  identity = {
+    id      = "9936b568-6155-11f0-90b5-8338c8e977e5"
+    team_id = "my-team" # omit for instances of a personal account
+  }
+}
@@ -1 +1,5 @@
+# A Virtual Machine can be imported by its instance ID
 terraform import cloudrift_virtual_machine.machine0 9936b568-6155-11f0-90b5-8338c8e977e5
+
+# or by its name, as long as no other instance has the same name
+terraform import cloudrift_virtual_machine.machine0 mycluster-a3f2-pool1-01
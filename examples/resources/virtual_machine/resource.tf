@@ -28,9 +28,10 @@ resource "cloudrift_virtual_machine" "machine0" {
   # output "instance_types" { value = data.cloudrift_instance_types.all.instance_types }
   instance_type = "rtx49-7-50-500-nr.1"
   ssh_key_id    = cloudrift_ssh_key.primary.id
+  exposed_ports = [22, 8888]
 
-  metadata = {
-    startup_commands = base64encode(<<EOF
+  user_data = {
+    shell_script = <<EOF
 #!/bin/bash
 # allow login as root.
 echo 'PasswordAuthentication no' >> /etc/ssh/sshd_config
@@ -38,7 +39,6 @@ echo 'PermitRootLogin without-password' >> /etc/ssh/sshd_config
 echo 'PubkeyAuthentication yes' >> /etc/ssh/sshd_config
 echo -n '${cloudrift_ssh_key.primary.public_key}' > /root/.ssh/authorized_keys
 EOF
-    )
   }
 }
 
@@ -59,6 +59,11 @@ output "port_mappings" {
   value       = cloudrift_virtual_machine.machine0.port_mappings
 }
 
+output "jupyter_endpoint" {
+  description = "Public host:port the Jupyter port 8888 is reachable on"
+  value       = lookup(cloudrift_virtual_machine.machine0.port_endpoints, "8888", null)
+}
+
 output "virtual_machines" {
   value = cloudrift_virtual_machine.machine0.virtual_machines
 }
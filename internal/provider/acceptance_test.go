@@ -1,21 +1,151 @@
 package provider
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"math"
 	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 )
 
-// testAccPreCheck skips tests if CLOUDRIFT_TOKEN is not set.
-func testAccPreCheck(t *testing.T) {
+// testAccCassetteDir holds the cassettes of the acceptance tests, one per
+// test, recorded against the live API with CLOUDRIFT_CASSETTE_MODE=record and
+// replayed offline with CLOUDRIFT_CASSETTE_MODE=replay.
+const testAccCassetteDir = "testdata/cassettes"
+
+// testAccRequiredCassettes are the tests CI replays, replaying fails rather
+// than skips them when their cassette is missing.
+var testAccRequiredCassettes = []string{
+	"TestAcc_SSHKeyResource",
+	"TestAcc_VirtualMachineResource",
+}
+
+// The redacted values replace the secrets of the environment in the cassettes.
+const (
+	testAccRedactedTeamID    = "00000000-0000-0000-0000-000000000000"
+	testAccRedactedPublicKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIRedactedRedactedRedactedRedactedRedac ci-test"
+)
+
+// testAcc is the environment of an acceptance test, the live CloudRift API or
+// the cassette of the test.
+type testAcc struct {
+	t        *testing.T
+	cassette *cloudrifttest.Cassette
+}
+
+// testAccPreCheck skips tests if CLOUDRIFT_TOKEN is not set, or when replaying,
+// if the cassette of the test was not recorded and is not required.
+func testAccPreCheck(t *testing.T) *testAcc {
 	t.Helper()
-	if os.Getenv("CLOUDRIFT_TOKEN") == "" {
-		t.Skip("CLOUDRIFT_TOKEN must be set for acceptance tests")
+
+	mode, err := cloudrifttest.ParseCassetteMode(os.Getenv("CLOUDRIFT_CASSETTE_MODE"))
+	if err != nil {
+		t.Fatalf("CLOUDRIFT_CASSETTE_MODE: %v", err)
+	}
+	if mode != cloudrifttest.CassetteReplay && os.Getenv("CLOUDRIFT_TOKEN") == "" {
+		t.Skip("CLOUDRIFT_TOKEN must be set for acceptance tests, unless replaying cassettes")
+	}
+
+	acc := &testAcc{t: t}
+	if mode == "" {
+		return acc
+	}
+
+	path := filepath.Join(testAccCassetteDir, t.Name()+".json")
+	if _, err := os.Stat(path); mode == cloudrifttest.CassetteReplay && errors.Is(err, fs.ErrNotExist) {
+		if slices.Contains(testAccRequiredCassettes, t.Name()) {
+			t.Fatalf("no cassette recorded at %s, record it with make testacc-record", path)
+		}
+		t.Skipf("no cassette recorded at %s", path)
+	}
+	acc.cassette, err = cloudrifttest.NewCassette(path, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if mode == cloudrifttest.CassetteReplay {
+		// The token is never recorded, any one passes the provider validation.
+		t.Setenv("CLOUDRIFT_TOKEN", "replay")
+		return acc
+	}
+	t.Cleanup(func() {
+		// Failed runs are not worth replaying.
+		if t.Failed() {
+			return
+		}
+		if err := acc.cassette.Save(); err != nil {
+			t.Errorf("failed to save the cassette: %v", err)
+		}
+	})
+	return acc
+}
+
+// value returns v, or its recorded value when replaying.
+func (a *testAcc) value(name, v string) string {
+	a.t.Helper()
+
+	if a.cassette == nil {
+		return v
+	}
+	v, err := a.cassette.Value(name, v)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return v
+}
+
+// env returns the environment variable, skipping the test when it is unset.
+// When replaying, the recorded value is returned, and set for the provider.
+// A non-empty placeholder redacts the value in the cassette.
+func (a *testAcc) env(name, placeholder string) string {
+	a.t.Helper()
+
+	v := os.Getenv(name)
+	if a.cassette != nil && a.cassette.Mode() == cloudrifttest.CassetteReplay {
+		v = a.value(name, v)
+		a.t.Setenv(name, v)
+		return v
+	}
+	if v == "" {
+		a.t.Skipf("%s must be set", name)
+	}
+	if a.cassette != nil && placeholder != "" {
+		a.cassette.Redact(v, placeholder)
+	}
+	return a.value(name, v)
+}
+
+// clientOptions send the requests of the test through its cassette.
+func (a *testAcc) clientOptions() []cloudriftapi.HttpClientOption {
+	if a.cassette == nil {
+		return nil
+	}
+	opts := []cloudriftapi.HttpClientOption{cloudriftapi.WithTransportWrapper(a.cassette.Wrap)}
+	if a.cassette.Mode() == cloudrifttest.CassetteReplay {
+		// The recorded statuses are replayed in order, waiting is pointless.
+		opts = append(opts, cloudriftapi.WithPollInterval(10*time.Millisecond))
+	}
+	return opts
+}
+
+// providerFactories are testAccProtoV6ProviderFactories with the provider
+// sending its requests through the cassette of the test.
+func (a *testAcc) providerFactories() map[string]func() (tfprotov6.ProviderServer, error) {
+	return map[string]func() (tfprotov6.ProviderServer, error){
+		"cloudrift": providerserver.NewProtocol6WithError(&CloudRiftProvider{
+			version:       "test",
+			clientOptions: a.clientOptions(),
+		}),
 	}
 }
 
@@ -28,14 +158,14 @@ type cheapestInstance struct {
 
 // findCheapestAvailableInstance queries the CloudRift API and returns the
 // cheapest instance variant that has at least one available node.
-func findCheapestAvailableInstance(t *testing.T) cheapestInstance {
+func findCheapestAvailableInstance(t *testing.T, acc *testAcc) cheapestInstance {
 	t.Helper()
 
 	token := os.Getenv("CLOUDRIFT_TOKEN")
 	baseURL := os.Getenv("CLOUDRIFT_BASE_URL")
 	teamID := os.Getenv("CLOUDRIFT_TEAM_ID")
 
-	client, err := cloudriftapi.NewCustom(baseURL, token, cloudriftapi.ProtoUpcoming, teamID)
+	client, err := cloudriftapi.NewCustom(baseURL, token, cloudriftapi.ProtoUpcoming, teamID, acc.clientOptions()...)
 	if err != nil {
 		t.Fatalf("failed to create CloudRift client: %v", err)
 	}
@@ -78,10 +208,10 @@ func findCheapestAvailableInstance(t *testing.T) cheapestInstance {
 }
 
 func TestAcc_InstanceTypesDataSource(t *testing.T) {
-	testAccPreCheck(t)
+	acc := testAccPreCheck(t)
 
 	resource.Test(t, resource.TestCase{
-		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		ProtoV6ProviderFactories: acc.providerFactories(),
 		Steps: []resource.TestStep{
 			{
 				Config: `
@@ -97,10 +227,10 @@ func TestAcc_InstanceTypesDataSource(t *testing.T) {
 }
 
 func TestAcc_RecipesDataSource(t *testing.T) {
-	testAccPreCheck(t)
+	acc := testAccPreCheck(t)
 
 	resource.Test(t, resource.TestCase{
-		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		ProtoV6ProviderFactories: acc.providerFactories(),
 		Steps: []resource.TestStep{
 			{
 				Config: `
@@ -116,17 +246,14 @@ func TestAcc_RecipesDataSource(t *testing.T) {
 }
 
 func TestAcc_SSHKeyResource(t *testing.T) {
-	testAccPreCheck(t)
+	acc := testAccPreCheck(t)
 
-	sshPublicKey := os.Getenv("CLOUDRIFT_TEST_SSH_PUBLIC_KEY")
-	if sshPublicKey == "" {
-		t.Skip("CLOUDRIFT_TEST_SSH_PUBLIC_KEY must be set")
-	}
+	sshPublicKey := acc.env("CLOUDRIFT_TEST_SSH_PUBLIC_KEY", testAccRedactedPublicKey)
 
-	keyName := fmt.Sprintf("ci-test-%d", time.Now().UnixNano())
+	keyName := acc.value("key_name", fmt.Sprintf("ci-test-%d", time.Now().UnixNano()))
 
 	resource.Test(t, resource.TestCase{
-		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		ProtoV6ProviderFactories: acc.providerFactories(),
 		Steps: []resource.TestStep{
 			{
 				Config: fmt.Sprintf(`
@@ -155,20 +282,14 @@ func TestAcc_SSHKeyResource(t *testing.T) {
 }
 
 func TestAcc_VirtualMachineResource(t *testing.T) {
-	testAccPreCheck(t)
+	acc := testAccPreCheck(t)
 
-	if os.Getenv("CLOUDRIFT_TEAM_ID") == "" {
-		t.Skip("CLOUDRIFT_TEAM_ID must be set for VM tests")
-	}
+	acc.env("CLOUDRIFT_TEAM_ID", testAccRedactedTeamID)
+	sshPublicKey := acc.env("CLOUDRIFT_TEST_SSH_PUBLIC_KEY", testAccRedactedPublicKey)
 
-	sshPublicKey := os.Getenv("CLOUDRIFT_TEST_SSH_PUBLIC_KEY")
-	if sshPublicKey == "" {
-		t.Skip("CLOUDRIFT_TEST_SSH_PUBLIC_KEY must be set")
-	}
-
-	instance := findCheapestAvailableInstance(t)
+	instance := findCheapestAvailableInstance(t, acc)
 
-	keyName := fmt.Sprintf("ci-test-vm-%d", time.Now().UnixNano())
+	keyName := acc.value("key_name", fmt.Sprintf("ci-test-vm-%d", time.Now().UnixNano()))
 	recipe := "Ubuntu 24.04 Server"
 
 	// Name the VM after the PR (or "master" for non-PR runs) plus a random id,
@@ -177,9 +298,10 @@ func TestAcc_VirtualMachineResource(t *testing.T) {
 	if pr := os.Getenv("PR_NUMBER"); pr != "" {
 		vmName = fmt.Sprintf("provider-test-pr-%s-%d", pr, time.Now().UnixNano())
 	}
+	vmName = acc.value("vm_name", vmName)
 
 	resource.Test(t, resource.TestCase{
-		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		ProtoV6ProviderFactories: acc.providerFactories(),
 		Steps: []resource.TestStep{
 			{
 				Config: fmt.Sprintf(`
@@ -215,28 +337,23 @@ func TestAcc_VirtualMachineResource(t *testing.T) {
 const pinnedUbuntuCloudImageURL = "https://cloud-images.ubuntu.com/releases/noble/release-20260801/ubuntu-24.04-server-cloudimg-amd64.img"
 
 func TestAcc_VirtualMachineResource_RecipeUrl(t *testing.T) {
-	testAccPreCheck(t)
+	acc := testAccPreCheck(t)
 
-	if os.Getenv("CLOUDRIFT_TEAM_ID") == "" {
-		t.Skip("CLOUDRIFT_TEAM_ID must be set for VM tests")
-	}
-
-	sshPublicKey := os.Getenv("CLOUDRIFT_TEST_SSH_PUBLIC_KEY")
-	if sshPublicKey == "" {
-		t.Skip("CLOUDRIFT_TEST_SSH_PUBLIC_KEY must be set")
-	}
+	acc.env("CLOUDRIFT_TEAM_ID", testAccRedactedTeamID)
+	sshPublicKey := acc.env("CLOUDRIFT_TEST_SSH_PUBLIC_KEY", testAccRedactedPublicKey)
 
-	instance := findCheapestAvailableInstance(t)
+	instance := findCheapestAvailableInstance(t, acc)
 
-	keyName := fmt.Sprintf("ci-test-vm-image-url-%d", time.Now().UnixNano())
+	keyName := acc.value("key_name", fmt.Sprintf("ci-test-vm-image-url-%d", time.Now().UnixNano()))
 
 	vmName := fmt.Sprintf("provider-test-image-url-master-%d", time.Now().UnixNano())
 	if pr := os.Getenv("PR_NUMBER"); pr != "" {
 		vmName = fmt.Sprintf("provider-test-image-url-pr-%s-%d", pr, time.Now().UnixNano())
 	}
+	vmName = acc.value("vm_name", vmName)
 
 	resource.Test(t, resource.TestCase{
-		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		ProtoV6ProviderFactories: acc.providerFactories(),
 		Steps: []resource.TestStep{
 			{
 				Config: fmt.Sprintf(`
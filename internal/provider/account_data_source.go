@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ datasource.DataSource              = &accountDataSource{}
+	_ datasource.DataSourceWithConfigure = &accountDataSource{}
+)
+
+type accountDataModel struct {
+	TeamID          types.String  `tfsdk:"team_id"`
+	Balance         types.Float64 `tfsdk:"balance"`
+	CreditLimit     types.Float64 `tfsdk:"credit_limit"`
+	UnlimitedCredit types.Bool    `tfsdk:"unlimited_credit"`
+	TotalSpent      types.Float64 `tfsdk:"total_spent"`
+	InstanceCount   types.Int64   `tfsdk:"instance_count"`
+}
+
+type accountDataSource struct {
+	client *cloudriftapi.HttpClient
+}
+
+func NewAccountDataSource() datasource.DataSource {
+	return new(accountDataSource)
+}
+
+func (d *accountDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_account"
+}
+
+func (d *accountDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Balance and spend of the CloudRift account, or of the team if `team_id` is configured on the provider. " +
+			"Amounts are in USD.",
+		Attributes: map[string]schema.Attribute{
+			"team_id": schema.StringAttribute{
+				MarkdownDescription: "ID of the team the account belongs to, null for the personal account.",
+				Computed:            true,
+			},
+			"balance": schema.Float64Attribute{
+				MarkdownDescription: "Current balance in USD.",
+				Computed:            true,
+			},
+			"credit_limit": schema.Float64Attribute{
+				MarkdownDescription: "Credit limit in USD, null if the credit is unlimited. Only reported for teams.",
+				Computed:            true,
+			},
+			"unlimited_credit": schema.BoolAttribute{
+				MarkdownDescription: "Whether the credit of the account is unlimited. Only reported for teams.",
+				Computed:            true,
+			},
+			"total_spent": schema.Float64Attribute{
+				MarkdownDescription: "Total amount spent in USD. Only reported for teams, use `cloudrift_transactions` for the personal account.",
+				Computed:            true,
+			},
+			"instance_count": schema.Int64Attribute{
+				MarkdownDescription: "Number of active instances of the account. Only reported for teams.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (d *accountDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*cloudriftapi.HttpClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected DataSource Configure Type",
+			fmt.Sprintf("Expected *cloudriftapi.HttpClient, got: %T. Please report this issue to the provider developers.",
+				req.ProviderData,
+			),
+		)
+		return
+	}
+
+	d.client = client.WithContext(ctx)
+}
+
+func (d *accountDataSource) Read(ctx context.Context, _ datasource.ReadRequest, resp *datasource.ReadResponse) {
+	model := accountDataModel{
+		TeamID:          types.StringNull(),
+		CreditLimit:     types.Float64Null(),
+		UnlimitedCredit: types.BoolNull(),
+		TotalSpent:      types.Float64Null(),
+		InstanceCount:   types.Int64Null(),
+	}
+
+	if d.client.TeamID == "" {
+		info, err := d.client.GetAccountInfo()
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error reading CloudRift Account",
+				"Could not read the CloudRift account: "+err.Error(),
+			)
+			return
+		}
+		model.Balance = types.Float64Value(info.Data.Balance)
+
+		resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+		return
+	}
+
+	info, err := d.client.GetTeamAccountInfo(d.client.TeamID)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error reading CloudRift Account",
+			"Could not read the account of team "+d.client.TeamID+": "+err.Error(),
+		)
+		return
+	}
+
+	// Team amounts are reported in cents.
+	model.TeamID = types.StringValue(d.client.TeamID)
+	model.Balance = types.Float64Value(centsToUSD(info.Balance))
+	model.UnlimitedCredit = types.BoolValue(info.IsUnlimited)
+	if !info.IsUnlimited {
+		model.CreditLimit = types.Float64Value(centsToUSD(info.CreditLimit))
+	}
+	model.TotalSpent = types.Float64Value(centsToUSD(info.TotalSpent))
+	model.InstanceCount = types.Int64Value(int64(info.InstanceCount))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+// centsToUSD converts an amount the API reports in cents.
+func centsToUSD(cents int64) float64 {
+	return float64(cents) / 100
+}
@@ -0,0 +1,38 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func Test_AccountDataSource(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t, cloudrifttest.WithBalance(42.5), cloudrifttest.WithTeams(testTeam("team-123", 12345)))
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig(server.URL, "1.0") + `data "cloudrift_account" "current" {}`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cloudrift_account.current", "balance", "42.5"),
+					resource.TestCheckNoResourceAttr("data.cloudrift_account.current", "team_id"),
+					resource.TestCheckNoResourceAttr("data.cloudrift_account.current", "total_spent"),
+				),
+			},
+			{
+				Config: providerConfigWithTeamID(server.URL, "1.0", "team-123") + `data "cloudrift_account" "current" {}`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cloudrift_account.current", "team_id", "team-123"),
+					resource.TestCheckResourceAttr("data.cloudrift_account.current", "balance", "123.45"),
+					resource.TestCheckResourceAttr("data.cloudrift_account.current", "credit_limit", "0"),
+					resource.TestCheckResourceAttr("data.cloudrift_account.current", "unlimited_credit", "false"),
+					resource.TestCheckResourceAttr("data.cloudrift_account.current", "total_spent", "0"),
+				),
+			},
+		},
+	})
+}
@@ -0,0 +1,244 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	// apiLogSubsystem is the tflog subsystem of the CloudRift API requests,
+	// its level can be set on its own with TF_LOG_PROVIDER_CLOUDRIFT_API.
+	apiLogSubsystem = "cloudrift_api"
+	apiLogLevelEnv  = "TF_LOG_PROVIDER_CLOUDRIFT_API"
+	// maxLoggedBodyBytes truncates the logged request and response bodies,
+	// a team-wide instance list easily runs into megabytes.
+	maxLoggedBodyBytes = 4096
+	redactedValue      = "***"
+)
+
+// redactedBodyKeys are the JSON fields whose values never make it to the
+// logs: instance passwords and connection instructions, registry
+// credentials, tokens and private keys. API key secrets are added by
+// redactedBodyKeysFor.
+var redactedBodyKeys = map[string]bool{
+	"password":       true,
+	"new_password":   true,
+	"instructions":   true,
+	"registry_auth":  true,
+	"identity_token": true,
+	"secret":         true,
+	"client_secret":  true,
+	"token":          true,
+	"auth_token":     true,
+	"partial_token":  true,
+	"private_key":    true,
+}
+
+// apiKeysPath is the prefix of the endpoints managing API keys, whose "key"
+// field holds the API key secret. Elsewhere "key" is e.g. a public SSH key.
+const apiKeysPath = "/api/v1/api-keys/"
+
+// redactedBodyKeysFor returns the redactedBodyKeys of the requests to path,
+// along with the API key secrets of the API key endpoints.
+func redactedBodyKeysFor(path string) map[string]bool {
+	if !strings.HasPrefix(path, apiKeysPath) {
+		return redactedBodyKeys
+	}
+	keys := maps.Clone(redactedBodyKeys)
+	keys["key"] = true
+	return keys
+}
+
+// apiLoggingTransport logs the CloudRift API requests and responses at the
+// debug level of the cloudrift_api subsystem, within the context of the
+// request, see cloudriftapi.HttpClient.WithContext.
+type apiLoggingTransport struct {
+	token string
+	// logBodies is set when TF_LOG_PROVIDER_CLOUDRIFT_API asks for debug
+	// logs, the bodies are not captured otherwise.
+	logBodies bool
+	next      http.RoundTripper
+}
+
+// newAPILoggingTransport wraps next, masking the API token wherever it shows
+// up in the logged fields.
+func newAPILoggingTransport(token string, next http.RoundTripper) *apiLoggingTransport {
+	var logBodies bool
+	switch strings.ToUpper(os.Getenv(apiLogLevelEnv)) {
+	case "TRACE", "DEBUG", "JSON":
+		logBodies = true
+	}
+	return &apiLoggingTransport{token: token, logBodies: logBodies, next: next}
+}
+
+func (t *apiLoggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := tflog.NewSubsystem(req.Context(), apiLogSubsystem, tflog.WithLevelFromEnv(apiLogLevelEnv))
+	if t.token != "" {
+		ctx = tflog.SubsystemMaskAllFieldValuesStrings(ctx, apiLogSubsystem, t.token)
+	}
+
+	fields := map[string]any{
+		"method":  req.Method,
+		"url":     req.URL.String(),
+		"attempt": cloudriftapi.RetryAttempt(req),
+	}
+	if id := req.Header.Get(cloudriftapi.RequestIDHeader); id != "" {
+		fields["request_id"] = id
+	}
+	if req.Header.Get("X-API-KEY") != "" {
+		fields["api_key"] = redactedValue
+	}
+	redacted := redactedBodyKeysFor(req.URL.Path)
+	if t.logBodies && req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			b, truncated, _ := readLoggedBody(body)
+			_ = body.Close()
+			fields["request_body"] = loggedBody(b, truncated, redacted)
+		}
+	}
+	tflog.SubsystemDebug(ctx, apiLogSubsystem, "Sending CloudRift API request", fields)
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	fields["latency_ms"] = time.Since(start).Milliseconds()
+	delete(fields, "request_body")
+	if err != nil {
+		fields["error"] = err.Error()
+		tflog.SubsystemDebug(ctx, apiLogSubsystem, "CloudRift API request failed", fields)
+		return nil, err
+	}
+
+	fields["status"] = resp.StatusCode
+	if t.logBodies && resp.Body != nil {
+		b, truncated, readErr := readLoggedBody(resp.Body)
+		// The caller reads the whole body, starting with the logged part.
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(b), resp.Body), resp.Body}
+		if readErr != nil {
+			_ = resp.Body.Close()
+			return nil, readErr
+		}
+		fields["response_body"] = loggedBody(b, truncated, redacted)
+	}
+	tflog.SubsystemDebug(ctx, apiLogSubsystem, "Received CloudRift API response", fields)
+
+	return resp, nil
+}
+
+// readLoggedBody reads at most maxLoggedBodyBytes of the body, truncated is
+// set if there is more to it.
+func readLoggedBody(body io.Reader) (b []byte, truncated bool, err error) {
+	b, err = io.ReadAll(io.LimitReader(body, maxLoggedBodyBytes+1))
+	if len(b) > maxLoggedBodyBytes {
+		return b[:maxLoggedBodyBytes], true, err
+	}
+	return b, false, err
+}
+
+// loggedBody returns the body with the values of the redacted keys masked. A
+// truncated JSON body is logged up to its last complete token.
+func loggedBody(b []byte, truncated bool, redacted map[string]bool) string {
+	logged := string(b)
+	if masked, ok := redactJSON(b, redacted); ok {
+		logged = masked
+	}
+	if truncated {
+		logged += "... (truncated)"
+	}
+	return logged
+}
+
+// redactJSON re-encodes the JSON tokens of b, which may be cut short, with the
+// values of the redacted keys masked. It returns false if b is not JSON.
+func redactJSON(b []byte, redacted map[string]bool) (string, bool) {
+	type container struct {
+		object bool
+		tokens int
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var out strings.Builder
+	var stack []container
+	// redactNext is set after a redacted key, skip counts the open
+	// containers of the redacted value.
+	redactNext := false
+	skip := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			// io.EOF, or the end of a truncated body.
+			return out.String(), out.Len() > 0 || errors.Is(err, io.EOF)
+		}
+
+		delim, isDelim := tok.(json.Delim)
+		if skip > 0 {
+			if isDelim && (delim == '{' || delim == '[') {
+				skip++
+			} else if isDelim {
+				skip--
+			}
+			continue
+		}
+		if isDelim && (delim == '}' || delim == ']') {
+			out.WriteRune(rune(delim))
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		isKey := false
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			switch {
+			case top.tokens == 0:
+			case top.object && top.tokens%2 == 1:
+				out.WriteByte(':')
+			default:
+				out.WriteByte(',')
+			}
+			isKey = top.object && top.tokens%2 == 0
+			top.tokens++
+		}
+
+		if redactNext {
+			redactNext = false
+			if tok != nil {
+				out.WriteString(strconv.Quote(redactedValue))
+				if isDelim {
+					skip = 1
+				}
+				continue
+			}
+		}
+
+		switch v := tok.(type) {
+		case json.Delim:
+			out.WriteRune(rune(v))
+			stack = append(stack, container{object: v == '{'})
+		case string:
+			quoted, _ := json.Marshal(v)
+			out.Write(quoted)
+			redactNext = isKey && redacted[v]
+		case json.Number:
+			out.WriteString(v.String())
+		case bool:
+			out.WriteString(strconv.FormatBool(v))
+		case nil:
+			out.WriteString("null")
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+)
+
+func Test_APILoggingTransport(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data":{"instances":[{"id":"1","login_info":{"UsernameAndPassword":{"username":"riftuser","password":"hunter2"}}}]}}`))
+	}))
+	defer server.Close()
+
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+	transport := newAPILoggingTransport("rift_secret_token", http.DefaultTransport)
+	transport.logBodies = true
+	client := &http.Client{Transport: transport}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/api/v1/instances/list",
+		strings.NewReader(`{"version":"~upcoming","data":{"config":{"registry_auth":{"username":"u","password":"registry-pass"}},"note":"rift_secret_token"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-API-KEY", "rift_secret_token")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if !strings.Contains(string(body), "hunter2") {
+		t.Errorf("the response body must reach the caller unredacted, got %s", body)
+	}
+
+	logged := output.String()
+	for _, secret := range []string{"rift_secret_token", "registry-pass", "hunter2"} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("secret %q leaked into the logs:\n%s", secret, logged)
+		}
+	}
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected a request and a response entry, got %d: %v", len(entries), entries)
+	}
+	response := entries[1]
+	if response["@module"] != "provider."+apiLogSubsystem {
+		t.Errorf("expected the %s subsystem, got %v", apiLogSubsystem, response["@module"])
+	}
+	if response["status"] != float64(http.StatusOK) || response["method"] != http.MethodPost || response["attempt"] != float64(0) {
+		t.Errorf("unexpected response entry: %v", response)
+	}
+	if _, ok := response["latency_ms"]; !ok {
+		t.Errorf("expected the latency to be logged: %v", response)
+	}
+	if _, ok := response["response_body"]; !ok {
+		t.Errorf("expected the response body to be logged: %v", response)
+	}
+
+	// Without debug logs of the subsystem the bodies are not captured.
+	output.Reset()
+	transport.logBodies = false
+	req, _ = http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/api/v1/instances/list", strings.NewReader(`{}`))
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if !strings.Contains(string(body), "hunter2") {
+		t.Errorf("expected the whole response body, got %s", body)
+	}
+	if strings.Contains(output.String(), "_body") {
+		t.Errorf("expected no body to be logged:\n%s", output.String())
+	}
+}
+
+// Test_APILoggingTransport_APIKeySecret verifies that the secret of a new API
+// key, returned in its "key" field, does not make it to the logs.
+func Test_APILoggingTransport_APIKeySecret(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data":{"id":"k-1","key":"rift_api_key_secret","active":true,"name":"ci"}}`))
+	}))
+	defer server.Close()
+
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+	transport := newAPILoggingTransport("rift_secret_token", http.DefaultTransport)
+	transport.logBodies = true
+	client := &http.Client{Transport: transport}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/api/v1/api-keys/add", strings.NewReader(`{"data":{"name":"ci"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if logged := output.String(); strings.Contains(logged, "rift_api_key_secret") || !strings.Contains(logged, "response_body") {
+		t.Errorf("expected the response body to be logged without the API key secret:\n%s", logged)
+	}
+}
+
+func Test_LoggedBody(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		`{"data":{"key":"ssh-ed25519 AAAA","name":"ci"}}`:             `{"data":{"key":"ssh-ed25519 AAAA","name":"ci"}}`,
+		`{"data":{"instructions":null,"keys":["a"]}}`:                 `{"data":{"instructions":null,"keys":["a"]}}`,
+		`{"list":[{"password":"p"},{"secret":"s"}], "n": 1.50}`:       `{"list":[{"password":"***"},{"secret":"***"}],"n":1.50}`,
+		`{"registry_auth":{"Password":{"password":"p"}},"image":"x"}`: `{"registry_auth":"***","image":"x"}`,
+		`{"Token":{"identity_token":"t"},"ok":true}`:                  `{"Token":{"identity_token":"***"},"ok":true}`,
+		`not json`: `not json`,
+	}
+	for body, want := range cases {
+		if got := loggedBody([]byte(body), false, redactedBodyKeys); got != want {
+			t.Errorf("loggedBody(%s) = %s, want %s", body, got, want)
+		}
+	}
+
+	// A body cut within a secret logs none of it.
+	if got := loggedBody([]byte(`{"a":[1,2],"password":"hunt`), true, redactedBodyKeys); got != `{"a":[1,2],"password"... (truncated)` {
+		t.Errorf("unexpected truncated body %s", got)
+	}
+
+	// The "key" of the API key endpoints is the API key secret, elsewhere it
+	// is logged.
+	apiKey := `{"data":{"id":"k-1","key":"rift_api_key_secret","active":true}}`
+	if got := loggedBody([]byte(apiKey), false, redactedBodyKeysFor("/api/v1/api-keys/list")); got != `{"data":{"id":"k-1","key":"***","active":true}}` {
+		t.Errorf("unexpected API key body %s", got)
+	}
+	if got := loggedBody([]byte(apiKey), false, redactedBodyKeysFor("/api/v1/ssh-keys/add")); got != apiKey {
+		t.Errorf("expected the key of other endpoints to be logged, got %s", got)
+	}
+
+	b, truncated, err := readLoggedBody(bytes.NewReader(bytes.Repeat([]byte("a"), maxLoggedBodyBytes+10)))
+	if err != nil || !truncated || len(b) != maxLoggedBodyBytes {
+		t.Errorf("expected the body to be read up to %d bytes, got %d, truncated %v, %v", maxLoggedBodyBytes, len(b), truncated, err)
+	}
+}
@@ -0,0 +1,341 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ resource.Resource                   = &autoTopUpResource{}
+	_ resource.ResourceWithConfigure      = &autoTopUpResource{}
+	_ resource.ResourceWithImportState    = &autoTopUpResource{}
+	_ resource.ResourceWithValidateConfig = &autoTopUpResource{}
+)
+
+// personalAccountID is the ID of the auto top-up of the personal account,
+// the auto top-up of a team has the ID of the team.
+const personalAccountID = "personal"
+
+type autoTopUpModel struct {
+	ID               types.String `tfsdk:"id"`
+	TeamID           types.String `tfsdk:"team_id"`
+	Enabled          types.Bool   `tfsdk:"enabled"`
+	ThresholdCents   types.Int64  `tfsdk:"threshold_cents"`
+	TopUpAmountCents types.Int64  `tfsdk:"top_up_amount_cents"`
+	MonthlyCapCents  types.Int64  `tfsdk:"monthly_cap_cents"`
+
+	Status              types.String `tfsdk:"status"`
+	SpentThisMonthCents types.Int64  `tfsdk:"spent_this_month_cents"`
+	LastFailureCode     types.String `tfsdk:"last_failure_code"`
+	LastFailureMessage  types.String `tfsdk:"last_failure_message"`
+	PausedUntil         types.String `tfsdk:"paused_until"`
+	GracePeriodEndsAt   types.String `tfsdk:"grace_period_ends_at"`
+}
+
+type autoTopUpResource struct {
+	client *cloudriftapi.HttpClient
+}
+
+func NewAutoTopUpResource() resource.Resource {
+	return &autoTopUpResource{}
+}
+
+func (r *autoTopUpResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_auto_top_up"
+}
+
+func (r *autoTopUpResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *resourceData, got: %T. Please report this issue to the provider developers.",
+				req.ProviderData,
+			),
+		)
+		return
+	}
+
+	r.client = data.client.WithContext(ctx)
+}
+
+func (r *autoTopUpResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Manage the auto top-up of the CloudRift account, or of the team if `team_id` is configured on the provider. " +
+			"There is a single auto top-up per account, declare at most one of this resource per provider configuration. " +
+			"Destroying the resource disables the auto top-up.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "ID of the team the auto top-up belongs to, `" + personalAccountID + "` for the personal account",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"team_id": schema.StringAttribute{
+				MarkdownDescription: "ID of the team the auto top-up belongs to, null for the personal account",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"enabled": schema.BoolAttribute{
+				MarkdownDescription: "Whether the account is topped up automatically",
+				Required:            true,
+			},
+			"threshold_cents": schema.Int64Attribute{
+				MarkdownDescription: "Balance in cents below which the account is topped up",
+				Required:            true,
+			},
+			"top_up_amount_cents": schema.Int64Attribute{
+				MarkdownDescription: "Amount in cents charged per top-up",
+				Required:            true,
+			},
+			"monthly_cap_cents": schema.Int64Attribute{
+				MarkdownDescription: "Maximum amount in cents charged by auto top-ups per calendar month, unlimited if not set",
+				Optional:            true,
+			},
+			"status": schema.StringAttribute{
+				MarkdownDescription: "Status of the auto top-up, one of `Off`, `Active`, `CapReached`, `ChargeFailed` or `DisabledAfterFailures`",
+				Computed:            true,
+			},
+			"spent_this_month_cents": schema.Int64Attribute{
+				MarkdownDescription: "Amount in cents charged by auto top-ups this calendar month",
+				Computed:            true,
+			},
+			"last_failure_code": schema.StringAttribute{
+				MarkdownDescription: "Code of the last failed charge",
+				Computed:            true,
+			},
+			"last_failure_message": schema.StringAttribute{
+				MarkdownDescription: "Message of the last failed charge",
+				Computed:            true,
+			},
+			"paused_until": schema.StringAttribute{
+				MarkdownDescription: "Time until which the auto top-up is paused after failed charges",
+				Computed:            true,
+			},
+			"grace_period_ends_at": schema.StringAttribute{
+				MarkdownDescription: "Time until which instances keep running after a failed charge",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *autoTopUpResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config autoTopUpModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.ThresholdCents.IsUnknown() && config.ThresholdCents.ValueInt64() < 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("threshold_cents"),
+			"Invalid Auto Top-Up Configuration",
+			fmt.Sprintf("Attribute \"threshold_cents\" must not be negative, got: %d", config.ThresholdCents.ValueInt64()),
+		)
+	}
+	if !config.TopUpAmountCents.IsUnknown() && config.TopUpAmountCents.ValueInt64() <= 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("top_up_amount_cents"),
+			"Invalid Auto Top-Up Configuration",
+			fmt.Sprintf("Attribute \"top_up_amount_cents\" must be positive, got: %d", config.TopUpAmountCents.ValueInt64()),
+		)
+	}
+	if !config.MonthlyCapCents.IsNull() && !config.MonthlyCapCents.IsUnknown() && config.MonthlyCapCents.ValueInt64() <= 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("monthly_cap_cents"),
+			"Invalid Auto Top-Up Configuration",
+			fmt.Sprintf("Attribute \"monthly_cap_cents\" must be positive, got: %d", config.MonthlyCapCents.ValueInt64()),
+		)
+	}
+}
+
+func (r *autoTopUpResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan autoTopUpModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state, err := r.client.UpdateAutoTopUp(plan.settings())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error creating Auto Top-Up",
+			"Could not configure the auto top-up of "+describeTeam(r.client.TeamID)+": "+err.Error(),
+		)
+		return
+	}
+
+	plan.setState(r.client.TeamID, state)
+	resp.Diagnostics.Append(autoTopUpWarnings(r.client.TeamID, state)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *autoTopUpResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var model autoTopUpModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state, err := r.client.GetAutoTopUp()
+	if err != nil {
+		if errors.Is(err, cloudriftapi.ErrNotFound) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError(
+			"Error reading CloudRift Auto Top-Up",
+			"Could not read the auto top-up of "+describeTeam(r.client.TeamID)+": "+err.Error(),
+		)
+		return
+	}
+
+	if state.Data.Settings == nil {
+		// never configured, or reset outside of terraform.
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	model.Enabled = types.BoolValue(state.Data.Settings.Enabled)
+	model.ThresholdCents = types.Int64Value(state.Data.Settings.ThresholdCents)
+	model.TopUpAmountCents = types.Int64Value(state.Data.Settings.TopUpAmountCents)
+	model.MonthlyCapCents = types.Int64PointerValue(state.Data.Settings.MonthlyCapCents)
+	model.setState(r.client.TeamID, state)
+
+	resp.Diagnostics.Append(autoTopUpWarnings(r.client.TeamID, state)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+func (r *autoTopUpResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan autoTopUpModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	state, err := r.client.UpdateAutoTopUp(plan.settings())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error updating Auto Top-Up",
+			"Could not update the auto top-up of "+describeTeam(r.client.TeamID)+": "+err.Error(),
+		)
+		return
+	}
+
+	plan.setState(r.client.TeamID, state)
+	resp.Diagnostics.Append(autoTopUpWarnings(r.client.TeamID, state)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *autoTopUpResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state autoTopUpModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The API has no way to delete the settings, they are disabled instead.
+	settings := state.settings()
+	settings.Enabled = false
+	if _, err := r.client.UpdateAutoTopUp(settings); err != nil {
+		resp.Diagnostics.AddError(
+			"Error Delete Auto Top-Up",
+			"Could not disable the auto top-up of "+describeTeam(r.client.TeamID)+": "+err.Error(),
+		)
+		return
+	}
+}
+
+func (r *autoTopUpResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	if want := autoTopUpID(r.client.TeamID); req.ID != want {
+		resp.Diagnostics.AddError(
+			"Error importing Auto Top-Up",
+			fmt.Sprintf("The provider manages the auto top-up of %s, import it with the ID %q, got: %q", describeTeam(r.client.TeamID), want, req.ID),
+		)
+		return
+	}
+
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// settings returns the auto top-up settings of the model.
+func (m *autoTopUpModel) settings() cloudriftapi.AutoTopUpSettings {
+	return cloudriftapi.AutoTopUpSettings{
+		Enabled:          m.Enabled.ValueBool(),
+		ThresholdCents:   m.ThresholdCents.ValueInt64(),
+		TopUpAmountCents: m.TopUpAmountCents.ValueInt64(),
+		MonthlyCapCents:  m.MonthlyCapCents.ValueInt64Pointer(),
+	}
+}
+
+// setState sets the computed attributes of the model from the auto top-up
+// state reported by the API.
+func (m *autoTopUpModel) setState(teamID string, state *cloudriftapi.AutoTopUpStateProto) {
+	m.ID = types.StringValue(autoTopUpID(teamID))
+	m.TeamID = types.StringNull()
+	if teamID != "" {
+		m.TeamID = types.StringValue(teamID)
+	}
+
+	m.Status = types.StringValue(string(state.Data.Status))
+	m.SpentThisMonthCents = types.Int64Value(state.Data.SpentThisMonthCents)
+	m.LastFailureCode = types.StringPointerValue(state.Data.LastFailureCode)
+	m.LastFailureMessage = types.StringPointerValue(state.Data.LastFailureMessage)
+	m.PausedUntil = types.StringPointerValue(state.Data.PausedUntil)
+	m.GracePeriodEndsAt = types.StringPointerValue(state.Data.GracePeriodEndsAt)
+}
+
+func autoTopUpID(teamID string) string {
+	if teamID == "" {
+		return personalAccountID
+	}
+	return teamID
+}
+
+// autoTopUpWarnings warns about auto top-ups whose charges failed, the
+// instances of the account are stopped once the balance runs out.
+func autoTopUpWarnings(teamID string, state *cloudriftapi.AutoTopUpStateProto) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	failure := "the charge failed"
+	if state.Data.LastFailureMessage != nil {
+		failure = *state.Data.LastFailureMessage
+	}
+	if state.Data.LastFailureCode != nil {
+		failure += " (" + *state.Data.LastFailureCode + ")"
+	}
+
+	switch state.Data.Status {
+	case cloudriftapi.AutoTopUpStatusChargeFailed:
+		detail := fmt.Sprintf("The last auto top-up of %s failed: %s.", describeTeam(teamID), failure)
+		if state.Data.GracePeriodEndsAt != nil {
+			detail += " Instances keep running until " + *state.Data.GracePeriodEndsAt + "."
+		}
+		diags.AddWarning("CloudRift Auto Top-Up Failed", detail+" Check the payment method of the account.")
+	case cloudriftapi.AutoTopUpStatusDisabledAfterFailures:
+		detail := fmt.Sprintf("The auto top-up of %s was disabled after repeated failures, the last one: %s.", describeTeam(teamID), failure)
+		if state.Data.PausedUntil != nil {
+			detail += " It is paused until " + *state.Data.PausedUntil + "."
+		}
+		diags.AddWarning("CloudRift Auto Top-Up Disabled", detail+" Check the payment method of the account and apply the settings again.")
+	}
+	return diags
+}
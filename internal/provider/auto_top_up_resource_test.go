@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func Test_AutoTopUpResource(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t, cloudrifttest.WithBalance(10))
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			if settings := server.AutoTopUp(); settings == nil || settings.Enabled {
+				return fmt.Errorf("expected the auto top-up to be disabled on destroy, got %+v", settings)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig(server.URL, "1.0") + `
+					resource "cloudrift_auto_top_up" "default" {
+					  enabled             = true
+					  threshold_cents     = 1000
+					  top_up_amount_cents = 5000
+					}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("cloudrift_auto_top_up.default", "id", "personal"),
+					resource.TestCheckNoResourceAttr("cloudrift_auto_top_up.default", "team_id"),
+					resource.TestCheckResourceAttr("cloudrift_auto_top_up.default", "status", "Active"),
+					resource.TestCheckResourceAttr("cloudrift_auto_top_up.default", "spent_this_month_cents", "0"),
+					resource.TestCheckNoResourceAttr("cloudrift_auto_top_up.default", "monthly_cap_cents"),
+				),
+			},
+			{
+				Config: providerConfig(server.URL, "1.0") + `
+					resource "cloudrift_auto_top_up" "default" {
+					  enabled             = true
+					  threshold_cents     = 2000
+					  top_up_amount_cents = 5000
+					  monthly_cap_cents   = 20000
+					}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("cloudrift_auto_top_up.default", "threshold_cents", "2000"),
+					resource.TestCheckResourceAttr("cloudrift_auto_top_up.default", "monthly_cap_cents", "20000"),
+				),
+			},
+			{
+				ResourceName:      "cloudrift_auto_top_up.default",
+				ImportState:       true,
+				ImportStateId:     "personal",
+				ImportStateVerify: true,
+			},
+			{
+				ResourceName:  "cloudrift_auto_top_up.default",
+				ImportState:   true,
+				ImportStateId: "team-123",
+				ExpectError:   regexp.MustCompile(`import it with the ID "personal"`),
+			},
+		},
+	})
+}
+
+func Test_AutoTopUpResource_InvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig("http://localhost", "1.0") + `
+					resource "cloudrift_auto_top_up" "default" {
+					  enabled             = true
+					  threshold_cents     = -1
+					  top_up_amount_cents = 0
+					}
+				`,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`(?s)"threshold_cents" must not be negative.*"top_up_amount_cents" must be positive`),
+			},
+		},
+	})
+}
+
+func Test_AutoTopUpWarnings(t *testing.T) {
+	t.Parallel()
+
+	ptr := func(s string) *string { return &s }
+
+	var active, failed, disabled cloudriftapi.AutoTopUpStateProto
+	active.Data.Status = cloudriftapi.AutoTopUpStatusActive
+
+	failed.Data.Status = cloudriftapi.AutoTopUpStatusChargeFailed
+	failed.Data.LastFailureCode = ptr("card_declined")
+	failed.Data.LastFailureMessage = ptr("Your card was declined")
+	failed.Data.GracePeriodEndsAt = ptr("2025-01-04T00:00:00Z")
+
+	disabled.Data.Status = cloudriftapi.AutoTopUpStatusDisabledAfterFailures
+	disabled.Data.PausedUntil = ptr("2025-02-01T00:00:00Z")
+
+	if diags := autoTopUpWarnings("", &active); len(diags) != 0 {
+		t.Errorf("expected no warnings for an active auto top-up, got %v", diags)
+	}
+
+	diags := autoTopUpWarnings("", &failed)
+	if len(diags) != 1 || diags.HasError() {
+		t.Fatalf("expected a single warning for a failed charge, got %v", diags)
+	}
+	for _, want := range []string{"the personal account", "Your card was declined (card_declined)", "until 2025-01-04T00:00:00Z"} {
+		if !strings.Contains(diags[0].Detail(), want) {
+			t.Errorf("warning %q does not contain %q", diags[0].Detail(), want)
+		}
+	}
+
+	diags = autoTopUpWarnings("team-123", &disabled)
+	if len(diags) != 1 || diags.HasError() {
+		t.Fatalf("expected a single warning for a disabled auto top-up, got %v", diags)
+	}
+	for _, want := range []string{`team "team-123"`, "the charge failed", "paused until 2025-02-01T00:00:00Z"} {
+		if !strings.Contains(diags[0].Detail(), want) {
+			t.Errorf("warning %q does not contain %q", diags[0].Detail(), want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// balancePreflight fails the plan of Virtual Machines the balance cannot run
+// for `min_balance_hours`. Terraform plans every resource of a run with the
+// same provider instance, the hourly cost of each instance the plan rents is
+// added up so that the check covers them all rather than each instance on its
+// own. Instances kept as they are already run on the balance, counting them
+// would fail the very plans scaling the fleet down.
+type balancePreflight struct {
+	client   *cloudriftapi.HttpClient
+	minHours float64
+
+	mu sync.Mutex
+	// balance is fetched once per run, nil until then.
+	balance *float64
+	// rentCost is the hourly cost of the instances planned to be rented so
+	// far.
+	rentCost float64
+}
+
+// newBalancePreflight returns nil if no minimum is configured, the nil
+// preflight accepts every plan.
+func newBalancePreflight(client *cloudriftapi.HttpClient, minHours float64) *balancePreflight {
+	if minHours <= 0 {
+		return nil
+	}
+	return &balancePreflight{client: client, minHours: minHours}
+}
+
+// check adds the hourly cost of instances the plan rents, created or
+// replaced, and returns an error on attr if the balance cannot cover all the
+// instances rented by the plan for the configured number of hours.
+func (p *balancePreflight) check(ctx context.Context, costPerHour float64, attr path.Path) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if p == nil {
+		return diags
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.balance == nil {
+		balance, err := p.client.WithContext(ctx).Balance()
+		if err != nil {
+			diags.AddWarning(
+				"Unable to check CloudRift Balance",
+				fmt.Sprintf("Could not read the balance of %s to check \"min_balance_hours\": %s", describeTeam(p.client.TeamID), err.Error()),
+			)
+			return diags
+		}
+		p.balance = &balance
+	}
+
+	p.rentCost += costPerHour
+	if required := p.rentCost * p.minHours; required > *p.balance {
+		diags.AddAttributeError(
+			attr,
+			"Insufficient CloudRift Balance",
+			fmt.Sprintf("The balance of %s is $%.2f, the instances created or replaced by the plan cost $%.2f per hour together and need $%.2f to run for %g hours as required by \"min_balance_hours\". "+
+				"Top up the account or lower \"min_balance_hours\" in the provider configuration.",
+				describeTeam(p.client.TeamID), *p.balance, p.rentCost, required, p.minHours),
+		)
+	}
+	return diags
+}
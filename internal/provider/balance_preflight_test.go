@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// testTeam is a team of the owner of the API token, whose balance is given
+// in cents.
+func testTeam(id string, balanceCents int64) cloudriftapi.TeamInfo {
+	return cloudriftapi.TeamInfo{
+		Id:          id,
+		Name:        "research",
+		CreatedAt:   "2025-01-01T00:00:00Z",
+		AccountInfo: &cloudriftapi.TeamAccountInfo{Balance: balanceCents},
+	}
+}
+
+func Test_BalancePreflight(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		teamID  string
+		costs   []float64
+		wantErr []bool
+	}{
+		{
+			name:    "personal account",
+			costs:   []float64{0.5, 0.4, 0.2},
+			wantErr: []bool{false, false, true},
+		},
+		{
+			name:    "team",
+			teamID:  "team-123",
+			costs:   []float64{0.25, 0.5},
+			wantErr: []bool{false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			// $10 on the personal account, $5 on the team, 10 hours required.
+			server := newTestAPI(t, cloudrifttest.WithBalance(10), cloudrifttest.WithTeams(testTeam("team-123", 500)))
+
+			client, err := cloudriftapi.NewCustom(server.URL, "test", "", tt.teamID)
+			if err != nil {
+				t.Fatalf("NewCustom: %v", err)
+			}
+
+			preflight := newBalancePreflight(client, 10)
+			for i, cost := range tt.costs {
+				diags := preflight.check(context.Background(), cost, path.Root("cost_per_hour"))
+				if diags.HasError() != tt.wantErr[i] {
+					t.Errorf("check(%g) #%d: got %v, want error=%v", cost, i, diags, tt.wantErr[i])
+				}
+			}
+		})
+	}
+
+	var disabled *balancePreflight
+	if diags := disabled.check(context.Background(), 1000, path.Root("cost_per_hour")); diags.HasError() {
+		t.Errorf("a preflight without minimum must accept every plan, got %v", diags)
+	}
+}
+
+// Test_VirtualMachineResource_CostPerHour verifies that cost_per_hour is known
+// at plan time and that min_balance_hours fails plans the balance cannot cover.
+func Test_VirtualMachineResource_CostPerHour(t *testing.T) {
+	t.Parallel()
+
+	keyName := "anotheruser-key"
+	publicKey := "ssh-rsa AAAA anotheruser"
+	// The Virtual Machine costs 0.85 $/h, on a balance of $1.
+	server := newTestAPI(t, cloudrifttest.WithBalance(1))
+
+	vmConfig := `
+		resource "cloudrift_virtual_machine" "machine0" {
+		  recipe        = "ubuntu"
+		  datacenter    = "us-east-nc-nr-1"
+		  instance_type = "rtx49-10c-kn.1"
+		  ssh_key_id    = "11111"
+		}
+	`
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig(server.URL, "1.0") + fmt.Sprintf(`
+					resource "cloudrift_ssh_key" "primary" {
+					  name       = "%s"
+					  public_key = "%s"
+					}
+				`, keyName, publicKey) + strings.Replace(vmConfig, `"11111"`, "cloudrift_ssh_key.primary.id", 1),
+				Check: resource.TestCheckResourceAttr("cloudrift_virtual_machine.machine0", "cost_per_hour", "0.85"),
+			},
+			{
+				// The running Virtual Machine is not rented again, the low
+				// balance does not fail its plan.
+				Config: fmt.Sprintf(`
+					provider "cloudrift" {
+					  base_url          = %q
+					  proto_version     = "1.0"
+					  token             = "test"
+					  min_balance_hours = 2
+					}
+					resource "cloudrift_ssh_key" "primary" {
+					  name       = "%s"
+					  public_key = "%s"
+					}
+				`, server.URL, keyName, publicKey) + strings.Replace(vmConfig, `"11111"`, "cloudrift_ssh_key.primary.id", 1),
+				PlanOnly: true,
+			},
+		},
+	})
+
+	// $1 does not cover 0.85 $/h for 2 hours.
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					provider "cloudrift" {
+					  base_url          = %q
+					  proto_version     = "1.0"
+					  token             = "test"
+					  min_balance_hours = 2
+					}
+				`, server.URL) + vmConfig,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`(?s)Insufficient CloudRift Balance.*\$1\.00.*\$0\.85 per hour`),
+			},
+		},
+	})
+}
@@ -0,0 +1,268 @@
+package provider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// newTLSTestServer serves the fake CloudRift API over TLS with a self-signed
+// certificate, and returns the PEM encoded certificate.
+func newTLSTestServer(t *testing.T) (*httptest.Server, []byte) {
+	t.Helper()
+
+	server := httptest.NewTLSServer(newTestAPI(t).Config.Handler)
+	t.Cleanup(server.Close)
+	return server, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+}
+
+// selfSignedClientCert returns a PEM encoded certificate and key for client
+// authentication.
+func selfSignedClientCert(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "terraform"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func Test_ClientTransport_CACert(t *testing.T) {
+	t.Parallel()
+
+	server, caPEM := newTLSTestServer(t)
+
+	if _, err := cloudriftapi.NewCustom(server.URL, "test", "", ""); err == nil {
+		t.Fatal("a server certificate of an unknown CA must be rejected")
+	}
+
+	if _, err := cloudriftapi.NewCustom(server.URL, "test", "", "", cloudriftapi.WithCACertPEM(caPEM)); err != nil {
+		t.Fatalf("NewCustom with the CA PEM: %v", err)
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cloudriftapi.NewCustom(server.URL, "test", "", "", cloudriftapi.WithCACertFile(caFile)); err != nil {
+		t.Fatalf("NewCustom with the CA file: %v", err)
+	}
+}
+
+func Test_ClientTransport_InsecureSkipVerify(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newTLSTestServer(t)
+
+	if _, err := cloudriftapi.NewCustom(server.URL, "test", "", "", cloudriftapi.WithInsecureSkipVerify(true)); err != nil {
+		t.Fatalf("NewCustom skipping the verification: %v", err)
+	}
+}
+
+func Test_ClientTransport_ClientCertificate(t *testing.T) {
+	t.Parallel()
+
+	certPEM, keyPEM := selfSignedClientCert(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(certPEM)
+
+	server := httptest.NewUnstartedServer(newTestAPI(t).Config.Handler)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	if _, err := cloudriftapi.NewCustom(server.URL, "test", "", "", cloudriftapi.WithCACertPEM(caPEM)); err == nil {
+		t.Fatal("a client without certificate must be rejected")
+	}
+
+	if _, err := cloudriftapi.NewCustom(server.URL, "test", "", "",
+		cloudriftapi.WithCACertPEM(caPEM),
+		cloudriftapi.WithClientCertificate(certPEM, keyPEM),
+	); err != nil {
+		t.Fatalf("NewCustom with the client certificate: %v", err)
+	}
+}
+
+func Test_ClientTransport_Proxy(t *testing.T) {
+	t.Parallel()
+
+	var proxied int32
+	handler := newTestAPI(t).Config.Handler
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&proxied, 1)
+		handler.ServeHTTP(w, r)
+	}))
+	defer proxy.Close()
+
+	// The API host does not resolve, only the proxy can reach it.
+	if _, err := cloudriftapi.NewCustom("http://api.cloudrift.invalid", "test", "", "", cloudriftapi.WithProxy(proxy.URL)); err != nil {
+		t.Fatalf("NewCustom through the proxy: %v", err)
+	}
+	if atomic.LoadInt32(&proxied) == 0 {
+		t.Error("expected the requests to go through the proxy")
+	}
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func Test_ClientTransport_ChainedWrappers(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	wrapper := func(name string) cloudriftapi.HttpClientOption {
+		return cloudriftapi.WithTransportWrapper(func(rt http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				order = append(order, name)
+				mu.Unlock()
+				return rt.RoundTrip(req)
+			})
+		})
+	}
+
+	if _, err := cloudriftapi.NewCustom(server.URL, "test", "", "", wrapper("outer"), wrapper("inner")); err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) < 2 || order[0] != "outer" || order[1] != "inner" {
+		t.Errorf("expected the wrapper of the last option to be the innermost, got %v", order)
+	}
+}
+
+func Test_ClientTransport_RequestContext(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+
+	type key struct{}
+	var (
+		mu     sync.Mutex
+		values []any
+	)
+	record := cloudriftapi.WithTransportWrapper(func(rt http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			values = append(values, req.Context().Value(key{}))
+			mu.Unlock()
+			return rt.RoundTrip(req)
+		})
+	})
+
+	setup := context.WithValue(context.Background(), key{}, "setup")
+	client, err := cloudriftapi.NewCustomWithContext(setup, server.URL, "test", "", "", record)
+	if err != nil {
+		t.Fatalf("NewCustomWithContext: %v", err)
+	}
+	if _, err := client.WithContext(context.WithValue(context.Background(), key{}, "read")).ListSSHKeys(); err != nil {
+		t.Fatalf("ListSSHKeys: %v", err)
+	}
+	if _, err := client.ListSSHKeys(); err != nil {
+		t.Fatalf("ListSSHKeys: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	n := len(values)
+	if n < 3 || values[0] != "setup" || values[n-2] != "read" || values[n-1] != nil {
+		t.Errorf("expected the setup, the bound and no context, got %v", values)
+	}
+}
+
+func Test_ClientTransport_InvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	certPEM, _ := selfSignedClientCert(t)
+	_, err := cloudriftapi.NewCustom("http://api.cloudrift.invalid", "test", "", "",
+		cloudriftapi.WithProxy("proxy.example.com"),
+		cloudriftapi.WithCACertPEM([]byte("not a certificate")),
+		cloudriftapi.WithClientCertificate(certPEM, nil),
+	)
+	if err == nil {
+		t.Fatal("expected the invalid options to be rejected")
+	}
+	for _, want := range []string{"invalid proxy URL", "no PEM encoded CA certificate", "loading client certificate"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in the error, got %v", want, err)
+		}
+	}
+}
+
+func Test_TransportOptions(t *testing.T) {
+	certPEM, _ := selfSignedClientCert(t)
+	t.Setenv("CLOUDRIFT_INSECURE_SKIP_VERIFY", "yes please")
+
+	var diags diag.Diagnostics
+	transportOptions(CloudRiftProviderModel{
+		ClientCert:         types.StringValue(string(certPEM)),
+		InsecureSkipVerify: types.BoolNull(),
+	}, &diags)
+
+	var details []string
+	for _, d := range diags.Errors() {
+		details = append(details, d.Detail())
+	}
+	got := strings.Join(details, "\n")
+	for _, want := range []string{"client_cert and client_key must be set together", "CLOUDRIFT_INSECURE_SKIP_VERIFY must be a boolean"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in the errors, got:\n%s", want, got)
+		}
+	}
+
+	diags = nil
+	transportOptions(CloudRiftProviderModel{InsecureSkipVerify: types.BoolValue(true)}, &diags)
+	if diags.HasError() || len(diags.Warnings()) != 1 {
+		t.Errorf("expected a single warning for insecure_skip_verify, got %v", diags)
+	}
+}
@@ -0,0 +1,686 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+var (
+	_ resource.Resource                   = &clusterResource{}
+	_ resource.ResourceWithConfigure      = &clusterResource{}
+	_ resource.ResourceWithValidateConfig = &clusterResource{}
+	_ resource.ResourceWithModifyPlan     = &clusterResource{}
+)
+
+type clusterModel struct {
+	ID            types.String `tfsdk:"id"`
+	Name          types.String `tfsdk:"name"`
+	InstanceCount types.Int64  `tfsdk:"instance_count"`
+	Recipe        types.String `tfsdk:"recipe"`
+	Datacenter    types.String `tfsdk:"datacenter"`
+	InstanceType  types.String `tfsdk:"instance_type"`
+	SSHKeyID      types.String `tfsdk:"ssh_key_id"`
+
+	InstanceIDs types.List `tfsdk:"instance_ids"`
+	Instances   types.List `tfsdk:"instances"`
+}
+
+var clusterInstanceAttrTypes = map[string]attr.Type{
+	"id":         types.StringType,
+	"status":     types.StringType,
+	"node_id":    types.StringType,
+	"public_ip":  types.StringType,
+	"private_ip": types.StringType,
+}
+
+type clusterResource struct {
+	client    *cloudriftapi.HttpClient
+	preflight *balancePreflight
+}
+
+func NewClusterResource() resource.Resource {
+	return &clusterResource{}
+}
+
+func (r *clusterResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_cluster"
+}
+
+func (r *clusterResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(*resourceData)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected *resourceData, got: %T. Please report this issue to the provider developers.",
+				req.ProviderData,
+			),
+		)
+		return
+	}
+
+	r.client = data.client.WithContext(ctx)
+	r.preflight = data.preflight
+}
+
+func (r *clusterResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Manage a fleet of identical CloudRift Virtual Machines grouped under a cluster name. " +
+			"The instances are polled with a single request for the whole cluster, `instance_count` is scaled in place " +
+			"and destroying the resource terminates the instances it rented. The instances are named after the cluster and their index, e.g. `training-1`.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				MarkdownDescription: "Name of the cluster",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Name of the cluster, every instance renting under this name belongs to the cluster",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"instance_count": schema.Int64Attribute{
+				MarkdownDescription: "Number of instances in the cluster. Scaling down terminates the most recently rented instances.",
+				Required:            true,
+			},
+			"recipe": schema.StringAttribute{
+				MarkdownDescription: "The Base Image used for the instances. Either a name from the CloudRift recipe catalog (e.g. `ubuntu`), or a direct `http://` / `https://` URL of a custom VM image.",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"datacenter": schema.StringAttribute{
+				MarkdownDescription: "Datacenter to rent the instances in",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"instance_type": schema.StringAttribute{
+				MarkdownDescription: "The instance type identifier",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"ssh_key_id": schema.StringAttribute{
+				MarkdownDescription: "ID of the SSH key installed on the instances",
+				Required:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"instance_ids": schema.ListAttribute{
+				MarkdownDescription: "IDs of the instances of the cluster, in the order they were rented",
+				Computed:            true,
+				ElementType:         types.StringType,
+			},
+			"instances": schema.ListNestedAttribute{
+				MarkdownDescription: "Instances of the cluster, in the order they were rented",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							MarkdownDescription: "ID of the instance",
+							Computed:            true,
+						},
+						"status": schema.StringAttribute{
+							MarkdownDescription: "Status of the instance",
+							Computed:            true,
+						},
+						"node_id": schema.StringAttribute{
+							MarkdownDescription: "ID of the node the instance is running on",
+							Computed:            true,
+						},
+						"public_ip": schema.StringAttribute{
+							MarkdownDescription: "Public IP address of the instance",
+							Computed:            true,
+						},
+						"private_ip": schema.StringAttribute{
+							MarkdownDescription: "Private IP address of the instance",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (r *clusterResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config clusterModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !config.Name.IsUnknown() && !config.Name.IsNull() && strings.TrimSpace(config.Name.ValueString()) == "" {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("name"),
+			"Invalid Cluster Configuration",
+			"The cluster name must not be empty.",
+		)
+	}
+	if !config.InstanceCount.IsUnknown() && !config.InstanceCount.IsNull() && config.InstanceCount.ValueInt64() < 1 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("instance_count"),
+			"Invalid Cluster Configuration",
+			fmt.Sprintf("The cluster must have at least one instance, got instance_count = %d.", config.InstanceCount.ValueInt64()),
+		)
+	}
+}
+
+// ModifyPlan checks that the balance covers the instances the plan rents when
+// `min_balance_hours` is set: every instance of a cluster created or replaced,
+// only the added ones when the cluster is scaled up.
+func (r *clusterResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.client == nil || r.preflight == nil {
+		return
+	}
+
+	var plan clusterModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if plan.InstanceCount.IsUnknown() || plan.InstanceType.IsUnknown() {
+		return
+	}
+
+	rented := plan.InstanceCount.ValueInt64()
+	if !req.State.Raw.IsNull() && len(resp.RequiresReplace) == 0 {
+		var state clusterModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+		rented -= state.InstanceCount.ValueInt64()
+	}
+	if rented <= 0 {
+		return
+	}
+
+	cost := instanceTypeCost(r.client, plan.InstanceType.ValueString())
+	if cost.IsNull() {
+		resp.Diagnostics.AddWarning(
+			"Unable to check CloudRift Balance",
+			"Could not look up the hourly cost of the instance type "+plan.InstanceType.ValueString()+" to check \"min_balance_hours\".",
+		)
+		return
+	}
+	resp.Diagnostics.Append(r.preflight.check(ctx, cost.ValueFloat64()*float64(rented), path.Root("instance_count"))...)
+}
+
+func (r *clusterResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan clusterModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	opts, diags := r.rentOptions(plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	plan.ID = plan.Name
+	ids, err := r.rentInstances(opts, 0, int(plan.InstanceCount.ValueInt64()))
+	if err != nil {
+		r.abandonInstances(ids, "a failed rent", &resp.Diagnostics)
+		resp.Diagnostics.AddError(
+			"Error creating Cluster",
+			"Could not create Cluster, unexpected error: "+err.Error(),
+		)
+		return
+	}
+
+	instances, err := r.waitForInstances(ctx, plan.Name.ValueString(), ids)
+	var failed *clusterProvisioningError
+	if errors.As(err, &failed) {
+		// Hard failure: release the instances and leave no state, the next
+		// apply rents a fresh cluster.
+		r.abandonInstances(ids, failed.reason, &resp.Diagnostics)
+		resp.Diagnostics.AddError("Cluster provisioning failed", failed.Error())
+		return
+	}
+
+	// On success, user cancel or a transient polling error persist the rented
+	// instances, so a retry can reconcile them.
+	resp.Diagnostics.Append(setClusterInstances(ctx, &plan, ids, instances)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error creating Cluster",
+			"Could not create Cluster, failed waiting on the rented instances: "+err.Error(),
+		)
+	}
+}
+
+func (r *clusterResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state clusterModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	instances, err := r.client.ListClusterInstances(state.Name.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error Reading CloudRift Cluster",
+			"Could not read CloudRift Cluster "+state.Name.ValueString()+": "+err.Error(),
+		)
+		return
+	}
+
+	var known []string
+	resp.Diagnostics.Append(state.InstanceIDs.ElementsAs(ctx, &known, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Only the instances rented by Terraform belong to the resource, in their
+	// rent order. Instances rented into the cluster from outside of Terraform
+	// are neither adopted nor terminated.
+	live := liveClusterInstances(instances)
+	var ids []string
+	for _, id := range known {
+		if _, ok := live[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		// Every instance was terminated outside of Terraform.
+		resp.State.RemoveResource(ctx)
+		return
+	}
+
+	state.InstanceCount = types.Int64Value(int64(len(ids)))
+	resp.Diagnostics.Append(setClusterInstances(ctx, &state, ids, live)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *clusterResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state clusterModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var ids []string
+	resp.Diagnostics.Append(state.InstanceIDs.ElementsAs(ctx, &ids, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	name := state.Name.ValueString()
+	want := int(plan.InstanceCount.ValueInt64())
+
+	// saveState persists the instances of the cluster known so far.
+	saveState := func(ids []string, instances map[string]cloudriftapi.InstanceAndUsageInfo) {
+		state.InstanceCount = types.Int64Value(int64(len(ids)))
+		resp.Diagnostics.Append(setClusterInstances(ctx, &state, ids, instances)...)
+		resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+	}
+
+	switch {
+	case want > len(ids):
+		opts, diags := r.rentOptions(state)
+		resp.Diagnostics.Append(diags...)
+		if resp.Diagnostics.HasError() {
+			saveState(ids, nil)
+			return
+		}
+
+		added, err := r.rentInstances(opts, len(ids), want-len(ids))
+		if err != nil {
+			r.abandonInstances(added, "a failed rent", &resp.Diagnostics)
+			saveState(ids, nil)
+			resp.Diagnostics.AddError(
+				"Error scaling Cluster",
+				"Could not rent the additional instances of Cluster "+name+": "+err.Error(),
+			)
+			return
+		}
+
+		instances, err := r.waitForInstances(ctx, name, added)
+		var failed *clusterProvisioningError
+		if errors.As(err, &failed) {
+			r.abandonInstances(added, failed.reason, &resp.Diagnostics)
+			saveState(ids, instances)
+			resp.Diagnostics.AddError("Cluster provisioning failed", failed.Error())
+			return
+		}
+		ids = append(ids, added...)
+		if err != nil {
+			saveState(ids, instances)
+			resp.Diagnostics.AddError(
+				"Error scaling Cluster",
+				"Could not scale Cluster "+name+", failed waiting on the rented instances: "+err.Error(),
+			)
+			return
+		}
+
+	case want < len(ids):
+		// Scale down by releasing the most recently rented instances.
+		removed := ids[want:]
+		if err := r.client.TerminateInstances(removed); err != nil && !errors.Is(err, cloudriftapi.ErrNotFound) {
+			saveState(ids, nil)
+			resp.Diagnostics.AddError(
+				"Error scaling Cluster",
+				"Could not terminate instances "+strings.Join(removed, ", ")+" of Cluster "+name+": "+err.Error(),
+			)
+			return
+		}
+		ids = ids[:want]
+		if err := r.waitForTermination(ctx, name, removed); err != nil {
+			saveState(ids, nil)
+			resp.Diagnostics.AddError(
+				"Error scaling Cluster",
+				"Could not scale Cluster "+name+", failed waiting on the terminated instances: "+err.Error(),
+			)
+			return
+		}
+	}
+
+	instances, err := r.client.ListClusterInstances(name)
+	if err != nil {
+		saveState(ids, nil)
+		resp.Diagnostics.AddError(
+			"Error Reading CloudRift Cluster",
+			"Could not read CloudRift Cluster "+name+": "+err.Error(),
+		)
+		return
+	}
+	saveState(ids, liveClusterInstances(instances))
+}
+
+func (r *clusterResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state clusterModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var ids []string
+	resp.Diagnostics.Append(state.InstanceIDs.ElementsAs(ctx, &ids, false)...)
+	if resp.Diagnostics.HasError() || len(ids) == 0 {
+		return
+	}
+
+	// Only the instances of the state are terminated, others may have been
+	// rented into the cluster from outside of Terraform.
+	name := state.Name.ValueString()
+	if err := r.client.TerminateInstances(ids); err != nil {
+		if errors.Is(err, cloudriftapi.ErrNotFound) {
+			// cluster already deleted from outside the terraform state.
+			return
+		}
+		resp.Diagnostics.AddError(
+			"Error Delete Cluster",
+			"Could not delete Cluster "+name+": "+err.Error(),
+		)
+		return
+	}
+
+	if err := r.waitForTermination(ctx, name, ids); err != nil {
+		resp.Diagnostics.AddError(
+			"Error Delete Cluster",
+			"Could not delete Cluster "+name+", failed waiting on the terminated instances: "+err.Error(),
+		)
+	}
+}
+
+// rentOptions builds the rent request shared by every instance of the cluster.
+func (r *clusterResource) rentOptions(m clusterModel) (cloudriftapi.RentVMOptions, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	keys, err := r.client.ListSSHKeys()
+	if err != nil {
+		diags.AddError(
+			"Error reading CloudRift SSH Keys",
+			"Could not list CloudRift SSH Keys needed for the Cluster: "+err.Error(),
+		)
+		return cloudriftapi.RentVMOptions{}, diags
+	}
+
+	idx := slices.IndexFunc(keys, func(k cloudriftapi.SshKey) bool { return k.Id == m.SSHKeyID.ValueString() })
+	if idx < 0 {
+		diags.AddError(
+			"Error fetching Cluster SSH Key",
+			"Could not fetch Cluster SSH Key with ID: "+m.SSHKeyID.ValueString()+" as it does not exist",
+		)
+		return cloudriftapi.RentVMOptions{}, diags
+	}
+
+	return cloudriftapi.RentVMOptions{
+		Recipe:       m.Recipe.ValueString(),
+		Datacenter:   m.Datacenter.ValueString(),
+		InstanceType: m.InstanceType.ValueString(),
+		PublicKeys:   []string{keys[idx].PublicKey},
+		ClusterName:  m.Name.ValueString(),
+	}, diags
+}
+
+// rentInstances rents count instances into the cluster, after the first ones
+// already rented. The rent endpoint has no instance count, so every instance
+// is a request of its own, named after the cluster and its index, e.g.
+// "training-3". On error the instances rented so far are returned along with
+// it.
+func (r *clusterResource) rentInstances(opts cloudriftapi.RentVMOptions, first, count int) ([]string, error) {
+	var ids []string
+	for i := range count {
+		opts.Name = fmt.Sprintf("%s-%d", opts.ClusterName, first+i+1)
+		rented, err := r.client.RentPublicInstanceVM(opts)
+		if err != nil {
+			return ids, err
+		}
+		if len(rented.Data.InstanceIds) < 1 {
+			return ids, errors.New("no valid IDs were returned from the CloudRift server")
+		}
+		ids = append(ids, rented.Data.InstanceIds...)
+	}
+	return ids, nil
+}
+
+// abandonInstances releases the instances on hard-failure paths. The terminate
+// call is best-effort, a failure is surfaced as a warning.
+func (r *clusterResource) abandonInstances(ids []string, reason string, diags *diag.Diagnostics) {
+	if len(ids) == 0 {
+		return
+	}
+	if err := r.client.TerminateInstances(ids); err != nil && !errors.Is(err, cloudriftapi.ErrNotFound) {
+		diags.AddWarning(
+			"Best-effort termination of failed Cluster instances did not succeed",
+			fmt.Sprintf("After %s, attempted to terminate instances %s to avoid leaking rented VMs, but the call failed: %s. The backend may still deactivate the instances on its own.", reason, strings.Join(ids, ", "), err.Error()),
+		)
+	}
+}
+
+// clusterProvisioningError is a failure after which the instances will never
+// become active, as opposed to a transient polling error or a user cancel.
+type clusterProvisioningError struct {
+	reason string
+	msg    string
+}
+
+func (e *clusterProvisioningError) Error() string { return e.msg }
+
+// waitForInstances polls the cluster with a single list request per tick
+// until every one of the instances is active and reachable. It returns the
+// last seen state of the instances along with any error.
+func (r *clusterResource) waitForInstances(ctx context.Context, name string, ids []string) (map[string]cloudriftapi.InstanceAndUsageInfo, error) {
+	seen := make(map[string]cloudriftapi.InstanceAndUsageInfo)
+	deadline := time.After(provisioningTimeout)
+	pollStart := time.Now()
+
+	for {
+		select {
+		case <-deadline:
+			return seen, &clusterProvisioningError{
+				reason: "provisioning timeout",
+				msg:    "Provisioning timeout reached before finished waiting on the instances of Cluster " + name,
+			}
+
+		case <-ctx.Done():
+			return seen, ctx.Err()
+
+		case <-time.After(InstancePollingInterval):
+			instances, err := r.client.ListClusterInstances(name)
+			if err != nil {
+				return seen, err
+			}
+
+			ready := 0
+			for _, inst := range instances {
+				if !slices.Contains(ids, inst.Id) {
+					continue
+				}
+				seen[inst.Id] = inst
+
+				// A freshly rented instance that is not Initializing or
+				// Active has failed to come up.
+				if inst.Status == cloudriftapi.InstanceStatusInactive ||
+					inst.Status == cloudriftapi.InstanceStatusDeactivating ||
+					inst.Status == cloudriftapi.InstanceStatusFailed {
+					return seen, &clusterProvisioningError{
+						reason: fmt.Sprintf("instance %s reached terminal status %q", inst.Id, inst.Status),
+						msg:    fmt.Sprintf("Instance %s of Cluster %s reached terminal status %q instead of becoming active", inst.Id, name, inst.Status),
+					}
+				}
+				switch inst.NodeStatus {
+				case cloudriftapi.Offline, cloudriftapi.NotResponding, cloudriftapi.Hibernated:
+					return seen, &clusterProvisioningError{
+						reason: fmt.Sprintf("node of instance %s is %q", inst.Id, inst.NodeStatus),
+						msg:    fmt.Sprintf("Instance %s of Cluster %s node is %q — VM cannot be provisioned on an unhealthy node", inst.Id, name, inst.NodeStatus),
+					}
+				}
+
+				vmReady := len(inst.VirtualMachines) > 0 && inst.VirtualMachines[0].Ready
+				if inst.Status == cloudriftapi.InstanceStatusActive && vmReady && inst.HostAddress != nil {
+					ready++
+				}
+			}
+
+			// Instances missing from the list are not listed yet right after
+			// the rent, they are released by the deadline if they never appear.
+			tflog.Debug(ctx, "polled CloudRift cluster", map[string]any{
+				"cluster":   name,
+				"elapsed_s": int(time.Since(pollStart).Seconds()),
+				"listed":    len(seen),
+				"ready":     ready,
+				"expected":  len(ids),
+			})
+
+			if ready == len(ids) {
+				return seen, nil
+			}
+		}
+	}
+}
+
+// waitForTermination polls the cluster until none of the instances is live
+// anymore. As for a single Virtual Machine, Deactivating is good enough.
+func (r *clusterResource) waitForTermination(ctx context.Context, name string, ids []string) error {
+	deadline := time.After(destructionTimeout)
+
+	for {
+		select {
+		case <-deadline:
+			return errors.New("destruction timeout reached before finished waiting on instance deletion")
+
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-time.After(InstancePollingInterval):
+			instances, err := r.client.ListClusterInstances(name)
+			if err != nil {
+				return err
+			}
+
+			remaining := 0
+			for id := range liveClusterInstances(instances) {
+				if slices.Contains(ids, id) {
+					remaining++
+				}
+			}
+			if remaining == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+// liveClusterInstances indexes the instances that are neither terminated nor
+// being terminated by their ID.
+func liveClusterInstances(instances []cloudriftapi.InstanceAndUsageInfo) map[string]cloudriftapi.InstanceAndUsageInfo {
+	live := make(map[string]cloudriftapi.InstanceAndUsageInfo)
+	for _, inst := range instances {
+		switch inst.Status {
+		case cloudriftapi.InstanceStatusInactive, cloudriftapi.InstanceStatusDeactivating, cloudriftapi.InstanceStatusFailed:
+			continue
+		}
+		live[inst.Id] = inst
+	}
+	return live
+}
+
+// setClusterInstances sets instance_ids and instances of the model in the
+// order of ids, instances not found in the map have only their ID set.
+func setClusterInstances(ctx context.Context, m *clusterModel, ids []string, instances map[string]cloudriftapi.InstanceAndUsageInfo) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	var d diag.Diagnostics
+	m.InstanceIDs, d = types.ListValueFrom(ctx, types.StringType, ids)
+	diags.Append(d...)
+
+	values := make([]attr.Value, 0, len(ids))
+	for _, id := range ids {
+		attrs := map[string]attr.Value{
+			"id":         types.StringValue(id),
+			"status":     types.StringNull(),
+			"node_id":    types.StringNull(),
+			"public_ip":  types.StringNull(),
+			"private_ip": types.StringNull(),
+		}
+		if inst, ok := instances[id]; ok {
+			attrs["status"] = types.StringValue(string(inst.Status))
+			attrs["node_id"] = types.StringValue(inst.NodeId)
+			attrs["public_ip"] = types.StringPointerValue(inst.HostAddress)
+			attrs["private_ip"] = types.StringPointerValue(inst.InternalHostAddress)
+		}
+
+		obj, d := types.ObjectValue(clusterInstanceAttrTypes, attrs)
+		diags.Append(d...)
+		values = append(values, obj)
+	}
+
+	m.Instances, d = types.ListValue(types.ObjectType{AttrTypes: clusterInstanceAttrTypes}, values)
+	diags.Append(d...)
+	return diags
+}
@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func Test_ClusterResource(t *testing.T) {
+	t.Parallel()
+
+	keyName := "anotheruser-key"
+	publicKey := "ssh-rsa AAAA anotheruser"
+	server := newTestAPI(t)
+
+	// rentedID checks that the i-th instance of the cluster is the i-th one
+	// rented in it.
+	rentedID := func(i int) resource.TestCheckFunc {
+		return func(s *terraform.State) error {
+			rented := server.ClusterInstances("workers")
+			if i >= len(rented) {
+				return fmt.Errorf("expected at least %d instances rented in the cluster, got %d", i+1, len(rented))
+			}
+			return resource.TestCheckResourceAttr("cloudrift_cluster.workers", fmt.Sprintf("instance_ids.%d", i), rented[i].Id)(s)
+		}
+	}
+	live := func() int {
+		return len(liveClusterInstances(server.ClusterInstances("workers")))
+	}
+	// foreign is the instance rented into the cluster outside of Terraform.
+	var foreign string
+
+	clusterConfig := func(count int) string {
+		return providerConfig(server.URL, "1.0") + fmt.Sprintf(`
+			resource "cloudrift_ssh_key" "primary" {
+			  name       = "%s"
+			  public_key = "%s"
+			}
+
+			resource "cloudrift_cluster" "workers" {
+			  name           = "workers"
+			  instance_count = %d
+			  recipe         = "ubuntu"
+			  datacenter     = "us-east-nc-nr-1"
+			  instance_type  = "rtx49-10c-kn.1"
+			  ssh_key_id     = cloudrift_ssh_key.primary.id
+			}
+		`, keyName, publicKey, count)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: clusterConfig(2),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("cloudrift_cluster.workers", "id", "workers"),
+					resource.TestCheckResourceAttr("cloudrift_cluster.workers", "instance_ids.#", "2"),
+					rentedID(0),
+					rentedID(1),
+					resource.TestCheckResourceAttr("cloudrift_cluster.workers", "instances.1.status", "Active"),
+					func(s *terraform.State) error {
+						inst := server.ClusterInstances("workers")[1]
+						return resource.ComposeAggregateTestCheckFunc(
+							resource.TestCheckResourceAttr("cloudrift_cluster.workers", "instances.1.id", inst.Id),
+							resource.TestCheckResourceAttr("cloudrift_cluster.workers", "instances.1.node_id", inst.NodeId),
+							resource.TestCheckResourceAttr("cloudrift_cluster.workers", "instances.1.public_ip", *inst.HostAddress),
+						)(s)
+					},
+					func(*terraform.State) error {
+						for i, inst := range server.ClusterInstances("workers") {
+							if want := fmt.Sprintf("workers-%d", i+1); inst.InstanceName == nil || *inst.InstanceName != want {
+								return fmt.Errorf("expected instance %s to be named %q, got %v", inst.Id, want, inst.InstanceName)
+							}
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// Scale up in place, the existing instances are kept.
+				Config: clusterConfig(3),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("cloudrift_cluster.workers", "instance_ids.#", "3"),
+					rentedID(0),
+					rentedID(2),
+					func(*terraform.State) error {
+						if name := server.ClusterInstances("workers")[2].InstanceName; name == nil || *name != "workers-3" {
+							return fmt.Errorf("expected the added instance to be named workers-3, got %v", name)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// Scale down terminates the most recently rented instances.
+				Config: clusterConfig(1),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("cloudrift_cluster.workers", "instance_ids.#", "1"),
+					rentedID(0),
+					func(*terraform.State) error {
+						if n := live(); n != 1 {
+							return fmt.Errorf("expected 1 live instance in the cluster, got %d", n)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				// An instance rented into the cluster from outside of
+				// Terraform is not adopted, the plan is empty.
+				PreConfig: func() {
+					client, err := cloudriftapi.NewCustom(server.URL, "test", "1.0", "")
+					if err != nil {
+						t.Fatalf("NewCustom: %v", err)
+					}
+					rented, err := client.RentPublicInstanceVM(cloudriftapi.RentVMOptions{
+						Recipe:       "ubuntu",
+						Datacenter:   "us-east-nc-nr-1",
+						InstanceType: "rtx49-10c-kn.1",
+						PublicKeys:   []string{publicKey},
+						ClusterName:  "workers",
+					})
+					if err != nil {
+						t.Fatalf("RentPublicInstanceVM: %v", err)
+					}
+					foreign = rented.Data.InstanceIds[0]
+				},
+				Config:   clusterConfig(1),
+				PlanOnly: true,
+			},
+		},
+		CheckDestroy: func(*terraform.State) error {
+			// Only the instance rented outside of Terraform is left.
+			remaining := liveClusterInstances(server.ClusterInstances("workers"))
+			if _, ok := remaining[foreign]; len(remaining) != 1 || !ok {
+				return fmt.Errorf("expected only the foreign instance %s to be left live, got %v", foreign, remaining)
+			}
+			return nil
+		},
+	})
+}
+
+func Test_ClusterResource_InvalidInstanceCount(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig(server.URL, "1.0") + `
+					resource "cloudrift_cluster" "workers" {
+					  name           = "workers"
+					  instance_count = 0
+					  recipe         = "ubuntu"
+					  datacenter     = "us-east-nc-nr-1"
+					  instance_type  = "rtx49-10c-kn.1"
+					  ssh_key_id     = "11111"
+					}
+				`,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`The cluster must have at least one instance`),
+			},
+		},
+	})
+}
+
+func Test_LiveClusterInstances(t *testing.T) {
+	t.Parallel()
+
+	live := liveClusterInstances([]cloudriftapi.InstanceAndUsageInfo{
+		{Id: "a", Status: cloudriftapi.InstanceStatusActive},
+		{Id: "b", Status: cloudriftapi.InstanceStatusInitializing},
+		{Id: "c", Status: cloudriftapi.InstanceStatusDeactivating},
+		{Id: "d", Status: cloudriftapi.InstanceStatusInactive},
+		{Id: "e", Status: cloudriftapi.InstanceStatusFailed},
+	})
+
+	var ids []string
+	for id := range live {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"a", "b"}) {
+		t.Errorf("expected instances a and b to be live, got %v", ids)
+	}
+}
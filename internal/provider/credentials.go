@@ -0,0 +1,147 @@
+package provider
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultProfile is the profile of the credentials file used unless
+// another one is selected with the profile attribute or CLOUDRIFT_PROFILE.
+const defaultProfile = "default"
+
+// credentialsProfile is a named section of the credentials file:
+//
+//	[default]
+//	token = rift_...
+//
+//	[team]
+//	token   = rift_...
+//	team_id = 00000000-0000-0000-0000-000000000000
+type credentialsProfile struct {
+	Token        string
+	BaseURL      string
+	TeamID       string
+	ProtoVersion string
+}
+
+// defaultCredentialsFile returns ~/.cloudrift/credentials, empty if the home
+// directory is unknown.
+func defaultCredentialsFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cloudrift", "credentials")
+}
+
+// loadCredentialsProfile reads the profile from the credentials file at path,
+// along with the keys of the profile the provider ignores. A missing file or
+// profile is only an error if the profile was selected explicitly, otherwise
+// an empty profile is returned.
+func loadCredentialsProfile(path, name string, explicit bool) (credentialsProfile, []string, error) {
+	if path == "" {
+		if explicit {
+			return credentialsProfile{}, nil, fmt.Errorf("profile %q selected, but the credentials file location is unknown", name)
+		}
+		return credentialsProfile{}, nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && !explicit {
+			return credentialsProfile{}, nil, nil
+		}
+		return credentialsProfile{}, nil, fmt.Errorf("reading credentials file: %w", err)
+	}
+	defer f.Close()
+
+	profiles, unknown, err := parseCredentials(f)
+	if err != nil {
+		return credentialsProfile{}, nil, fmt.Errorf("parsing credentials file %s: %w", path, err)
+	}
+
+	profile, ok := profiles[name]
+	if !ok && explicit {
+		return credentialsProfile{}, nil, fmt.Errorf("profile %q not found in credentials file %s", name, path)
+	}
+	return profile, unknown[name], nil
+}
+
+// parseCredentials parses the INI style credentials file, lines starting with
+// # or ; are comments. The file may be shared with other tools, the keys the
+// provider does not know are returned by profile rather than rejected.
+func parseCredentials(r io.Reader) (profiles map[string]credentialsProfile, unknown map[string][]string, err error) {
+	profiles = make(map[string]credentialsProfile)
+	unknown = make(map[string][]string)
+
+	var (
+		current string
+		inside  bool
+	)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.TrimSpace(line[1:len(line)-1]) == "" {
+				return nil, nil, fmt.Errorf("line %d: invalid profile header %q", n, line)
+			}
+			current, inside = strings.TrimSpace(line[1:len(line)-1]), true
+			if _, ok := profiles[current]; !ok {
+				profiles[current] = credentialsProfile{}
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		if !inside {
+			return nil, nil, fmt.Errorf("line %d: %q outside of a [profile] section", n, strings.TrimSpace(key))
+		}
+
+		profile := profiles[current]
+		value = strings.TrimSpace(value)
+		switch key = strings.TrimSpace(key); key {
+		case "token":
+			profile.Token = value
+		case "base_url":
+			profile.BaseURL = value
+		case "team_id":
+			profile.TeamID = value
+		case "proto_version":
+			profile.ProtoVersion = value
+		default:
+			unknown[current] = append(unknown[current], key)
+		}
+		profiles[current] = profile
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return profiles, unknown, nil
+}
+
+// readTokenFile reads the API token from the file at path, e.g. a secret
+// mounted in CI, ignoring surrounding whitespace.
+func readTokenFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading token file: %w", err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
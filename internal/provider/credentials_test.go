@@ -0,0 +1,151 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func Test_ParseCredentials(t *testing.T) {
+	t.Parallel()
+
+	profiles, unknown, err := parseCredentials(strings.NewReader(`
+# personal key
+[default]
+token = rift_personal
+region = eu
+
+; team key on the staging API
+[team]
+token         = rift_team
+team_id       = team-1
+base_url      = https://staging.cloudrift.ai
+proto_version = 2025-06-10
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]credentialsProfile{
+		"default": {Token: "rift_personal"},
+		"team":    {Token: "rift_team", TeamID: "team-1", BaseURL: "https://staging.cloudrift.ai", ProtoVersion: "2025-06-10"},
+	}
+	if len(profiles) != len(want) {
+		t.Fatalf("expected %d profiles, got %v", len(want), profiles)
+	}
+	for name, p := range want {
+		if profiles[name] != p {
+			t.Errorf("profile %q = %+v, want %+v", name, profiles[name], p)
+		}
+	}
+	if len(unknown) != 1 || len(unknown["default"]) != 1 || unknown["default"][0] != "region" {
+		t.Errorf("expected the unknown key region of the default profile, got %v", unknown)
+	}
+
+	for input, wantErr := range map[string]string{
+		"token = x":        "outside of a [profile] section",
+		"[default]\ntoken": "expected key = value",
+		"[default":         "invalid profile header",
+	} {
+		if _, _, err := parseCredentials(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("parseCredentials(%q) error = %v, want %q", input, err, wantErr)
+		}
+	}
+}
+
+func Test_LoadCredentialsProfile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "credentials")
+	if err := os.WriteFile(file, []byte("[default]\ntoken = rift_personal\nregion = eu\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if p, unknown, err := loadCredentialsProfile(file, "default", false); err != nil || p.Token != "rift_personal" || len(unknown) != 1 {
+		t.Errorf("expected the default profile and its unknown key, got %+v, %v, %v", p, unknown, err)
+	}
+	if p, _, err := loadCredentialsProfile(filepath.Join(dir, "missing"), "default", false); err != nil || p != (credentialsProfile{}) {
+		t.Errorf("a missing default file must be ignored, got %+v, %v", p, err)
+	}
+	if _, _, err := loadCredentialsProfile(filepath.Join(dir, "missing"), "team", true); err == nil {
+		t.Error("a missing file of an explicit profile must be an error")
+	}
+	if _, _, err := loadCredentialsProfile(file, "team", true); err == nil || !strings.Contains(err.Error(), `profile "team" not found`) {
+		t.Errorf("expected a missing profile error, got %v", err)
+	}
+}
+
+// The token, team and API of the provider come from the selected profile,
+// unless set on the provider or from a token file.
+func Test_Provider_CredentialsProfile(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+	authenticatedWith := func(token string) resource.TestCheckFunc {
+		return func(*terraform.State) error {
+			if got := server.LastRequestHeader("/api/v1/auth/me").Get("X-API-KEY"); got != token {
+				return fmt.Errorf("expected the token %q, got %q", token, got)
+			}
+			return nil
+		}
+	}
+
+	dir := t.TempDir()
+	credentials := filepath.Join(dir, "credentials")
+	if err := os.WriteFile(credentials, fmt.Appendf(nil, "[default]\ntoken = rift_personal\n\n[team]\ntoken = rift_team\nbase_url = %s\n", server.URL), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("rift_mounted\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+					provider "cloudrift" {
+					  credentials_file = %q
+					  profile          = "team"
+					}
+
+					data "cloudrift_recipes" "default" {}
+				`, credentials),
+				// The token of the team profile.
+				Check: authenticatedWith("rift_team"),
+			},
+			{
+				Config: fmt.Sprintf(`
+					provider "cloudrift" {
+					  credentials_file = %q
+					  profile          = "team"
+					  token_file       = %q
+					}
+
+					data "cloudrift_recipes" "default" {}
+				`, credentials, tokenFile),
+				// The token of the token file.
+				Check: authenticatedWith("rift_mounted"),
+			},
+			{
+				Config: fmt.Sprintf(`
+					provider "cloudrift" {
+					  credentials_file = %q
+					  profile          = "staging"
+					}
+
+					data "cloudrift_recipes" "default" {}
+				`, credentials),
+				ExpectError: regexp.MustCompile(`profile "staging" not found`),
+			},
+		},
+	})
+}
@@ -0,0 +1,196 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ ephemeral.EphemeralResource              = &instanceCredentialsEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithConfigure = &instanceCredentialsEphemeralResource{}
+)
+
+type instanceCredentialsModel struct {
+	InstanceID   types.String `tfsdk:"instance_id"`
+	Host         types.String `tfsdk:"host"`
+	Username     types.String `tfsdk:"username"`
+	Password     types.String `tfsdk:"password"`
+	Instructions types.String `tfsdk:"instructions"`
+}
+
+type instanceCredentialsEphemeralResource struct {
+	client *cloudriftapi.HttpClient
+}
+
+func NewInstanceCredentialsEphemeralResource() ephemeral.EphemeralResource {
+	return &instanceCredentialsEphemeralResource{}
+}
+
+func (r *instanceCredentialsEphemeralResource) Metadata(_ context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_instance_credentials"
+}
+
+func (r *instanceCredentialsEphemeralResource) Configure(ctx context.Context, req ephemeral.ConfigureRequest, resp *ephemeral.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*cloudriftapi.HttpClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Ephemeral Resource Configure Type",
+			fmt.Sprintf("Expected *cloudriftapi.HttpClient, got: %T. Please report this issue to the provider developers.",
+				req.ProviderData,
+			),
+		)
+		return
+	}
+
+	r.client = client.WithContext(ctx)
+}
+
+func (r *instanceCredentialsEphemeralResource) Schema(_ context.Context, _ ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Login credentials of a CloudRift instance. They are never stored in the plan or state, " +
+			"and require the `ViewInstanceCredentials` permission on the API key.",
+		Attributes: map[string]schema.Attribute{
+			"instance_id": schema.StringAttribute{
+				MarkdownDescription: "ID of the instance, e.g. the `id` of a `cloudrift_virtual_machine`.",
+				Required:            true,
+			},
+			"host": schema.StringAttribute{
+				MarkdownDescription: "Public address to connect to the instance.",
+				Computed:            true,
+			},
+			"username": schema.StringAttribute{
+				MarkdownDescription: "Username to log into the instance.",
+				Computed:            true,
+			},
+			"password": schema.StringAttribute{
+				MarkdownDescription: "Login password, null for instances only accepting SSH key authentication.",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			"instructions": schema.StringAttribute{
+				MarkdownDescription: "Connection instructions of the instance, with the credentials filled in.",
+				Computed:            true,
+				Sensitive:           true,
+			},
+		},
+	}
+}
+
+func (r *instanceCredentialsEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	var data instanceCredentialsModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	id := data.InstanceID.ValueString()
+	instance, err := r.client.GetInstanceCredentials(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, cloudriftapi.ErrCredentialsForbidden):
+			resp.Diagnostics.AddAttributeError(
+				path.Root("instance_id"),
+				"Missing ViewInstanceCredentials permission",
+				"The API key configured on the provider is not allowed to view the credentials of instance "+id+". "+
+					"Grant the ViewInstanceCredentials permission to the key, or rely on SSH key authentication: "+err.Error(),
+			)
+		case errors.Is(err, cloudriftapi.ErrNotFound):
+			resp.Diagnostics.AddAttributeError(
+				path.Root("instance_id"),
+				"Error reading CloudRift Instance Credentials",
+				"No active instance with ID "+id,
+			)
+		default:
+			resp.Diagnostics.AddError(
+				"Error reading CloudRift Instance Credentials",
+				"Could not fetch the credentials of CloudRift instance "+id+": "+err.Error(),
+			)
+		}
+		return
+	}
+
+	data.Host = types.StringPointerValue(instance.HostAddress)
+	data.Username = types.StringNull()
+	data.Password = types.StringNull()
+	if username, password := instanceLogin(instance); username != "" {
+		data.Username = types.StringValue(username)
+		if password != "" {
+			data.Password = types.StringValue(password)
+		}
+	}
+
+	data.Instructions = types.StringNull()
+	if instance.Instructions != nil {
+		instructions, err := renderInstructions(instance.Instructions)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error reading CloudRift Instance Credentials",
+				"Could not render the connection instructions of CloudRift instance "+id+": "+err.Error(),
+			)
+			return
+		}
+		data.Instructions = types.StringValue(instructions)
+	}
+
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+}
+
+// instanceLogin returns the username and password of the first Virtual
+// Machine of the instance, or of its bare metal node.
+func instanceLogin(instance *cloudriftapi.InstanceAndUsageInfo) (string, string) {
+	var login *cloudriftapi.InstanceLoginInfo
+	switch {
+	case len(instance.VirtualMachines) > 0:
+		login = instance.VirtualMachines[0].LoginInfo
+	case instance.BareMetal != nil:
+		login = instance.BareMetal.LoginInfo
+	}
+	if login == nil {
+		return "", ""
+	}
+
+	// The union decodes into any variant, the non empty username tells
+	// which one the server returned.
+	if v, err := login.AsInstanceLoginInfo0(); err == nil && v.UsernameAndPassword.Username != "" {
+		return v.UsernameAndPassword.Username, v.UsernameAndPassword.Password
+	}
+	if v, err := login.AsInstanceLoginInfo1(); err == nil && v.Username.Username != "" {
+		return v.Username.Username, ""
+	}
+	if v, err := login.AsInstanceLoginInfo2(); err == nil && v.HiddenPassword.Username != "" {
+		return v.HiddenPassword.Username, ""
+	}
+	return "", ""
+}
+
+// renderInstructions decodes the base64 encoded instructions template and
+// replaces each placeholder with its value.
+func renderInstructions(instructions *cloudriftapi.InstanceUserInstructions) (string, error) {
+	template, err := base64.StdEncoding.DecodeString(instructions.InstructionsTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode base64 encoded instructions: %w", err)
+	}
+
+	pairs := make([]string, 0, 2*len(instructions.PlaceholderValues))
+	for _, pv := range instructions.PlaceholderValues {
+		if len(pv) != 2 {
+			return "", fmt.Errorf("invalid placeholder value %v, expected a placeholder and its value", pv)
+		}
+		pairs = append(pairs, fmt.Sprint(pv[0]), fmt.Sprint(pv[1]))
+	}
+
+	return strings.NewReplacer(pairs...).Replace(string(template)), nil
+}
@@ -0,0 +1,159 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/echoprovider"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+// testInstanceCredentials returns the credentials the fake API lists for
+// the instance.
+func testInstanceCredentials(t *testing.T, server *cloudrifttest.Server, id string) (host, password string) {
+	t.Helper()
+
+	for _, i := range server.Instances() {
+		if i.Id != id {
+			continue
+		}
+		login, err := i.VirtualMachines[0].LoginInfo.AsInstanceLoginInfo0()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *i.HostAddress, login.UsernameAndPassword.Password
+	}
+	t.Fatalf("instance %s not found", id)
+	return "", ""
+}
+
+func openInstanceCredentials(t *testing.T, client *cloudriftapi.HttpClient, instanceID string) (instanceCredentialsModel, ephemeral.OpenResponse) {
+	t.Helper()
+	ctx := context.Background()
+
+	r := &instanceCredentialsEphemeralResource{client: client}
+	var schemaResp ephemeral.SchemaResponse
+	r.Schema(ctx, ephemeral.SchemaRequest{}, &schemaResp)
+	objectType := schemaResp.Schema.Type().TerraformType(ctx)
+
+	config := tftypes.NewValue(objectType, map[string]tftypes.Value{
+		"instance_id":  tftypes.NewValue(tftypes.String, instanceID),
+		"host":         tftypes.NewValue(tftypes.String, nil),
+		"username":     tftypes.NewValue(tftypes.String, nil),
+		"password":     tftypes.NewValue(tftypes.String, nil),
+		"instructions": tftypes.NewValue(tftypes.String, nil),
+	})
+	req := ephemeral.OpenRequest{Config: tfsdk.Config{Schema: schemaResp.Schema, Raw: config}}
+	resp := ephemeral.OpenResponse{Result: tfsdk.EphemeralResultData{Schema: schemaResp.Schema, Raw: config.Copy()}}
+	r.Open(ctx, req, &resp)
+
+	var data instanceCredentialsModel
+	if !resp.Diagnostics.HasError() {
+		resp.Diagnostics.Append(resp.Result.Get(ctx, &data)...)
+	}
+	return data, resp
+}
+
+func Test_InstanceCredentialsEphemeralResource_Open(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+	client, err := cloudriftapi.NewCustom(server.URL, "test", "", "")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	id := rentTestInstances(t, client, 1)[0]
+	host, password := testInstanceCredentials(t, server, id)
+
+	data, resp := openInstanceCredentials(t, client, id)
+	if resp.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", resp.Diagnostics)
+	}
+	var listed struct {
+		Mask cloudriftapi.InstanceInfoFlags `json:"mask"`
+	}
+	if err := server.LastRequest("/api/v1/instances/list", &listed); err != nil {
+		t.Fatal(err)
+	}
+	if m := listed.Mask; m.WithCredentials == nil || !*m.WithCredentials || m.WithConnectionInfo == nil || !*m.WithConnectionInfo {
+		t.Errorf("expected credentials and connection info to be requested, got mask %+v", m)
+	}
+	if data.Username.ValueString() != cloudrifttest.LoginUsername || data.Password.ValueString() != password || data.Host.ValueString() != host {
+		t.Errorf("unexpected credentials: %+v", data)
+	}
+	if want := fmt.Sprintf("ssh %s@%s, password: %s", cloudrifttest.LoginUsername, host, password); data.Instructions.ValueString() != want {
+		t.Errorf("instructions %q, want %q", data.Instructions.ValueString(), want)
+	}
+}
+
+func Test_InstanceCredentialsEphemeralResource_Forbidden(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+	client, err := cloudriftapi.NewCustom(server.URL, "test", "", "")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	id := rentTestInstances(t, client, 1)[0]
+	// The API key lacks the ViewInstanceCredentials permission.
+	server.InjectFault(cloudrifttest.Fault{Path: "/api/v1/instances/list", Status: http.StatusForbidden, Match: `"with_credentials":true`})
+
+	_, resp := openInstanceCredentials(t, client, id)
+	if !resp.Diagnostics.HasError() || resp.Diagnostics[0].Summary() != "Missing ViewInstanceCredentials permission" {
+		t.Errorf("expected a missing permission error, got %v", resp.Diagnostics)
+	}
+}
+
+func Test_InstanceCredentialsEphemeralResource(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+	client, err := cloudriftapi.NewCustom(server.URL, "test", "", "")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	id := rentTestInstances(t, client, 1)[0]
+	_, password := testInstanceCredentials(t, server, id)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+			"cloudrift": providerserver.NewProtocol6WithError(New("test")()),
+			"echo":      echoprovider.NewProviderServer(),
+		},
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_10_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig(server.URL, "1.0") + `
+					ephemeral "cloudrift_instance_credentials" "vm" {
+					  instance_id = "` + id + `"
+					}
+
+					provider "echo" {
+					  data = ephemeral.cloudrift_instance_credentials.vm
+					}
+
+					resource "echo" "credentials" {}
+				`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("echo.credentials", tfjsonpath.New("data").AtMapKey("username"), knownvalue.StringExact(cloudrifttest.LoginUsername)),
+					statecheck.ExpectKnownValue("echo.credentials", tfjsonpath.New("data").AtMapKey("password"), knownvalue.StringExact(password)),
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,246 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ datasource.DataSource                   = &instanceMetricsDataSource{}
+	_ datasource.DataSourceWithConfigure      = &instanceMetricsDataSource{}
+	_ datasource.DataSourceWithValidateConfig = &instanceMetricsDataSource{}
+)
+
+type gpuMetricsModel struct {
+	GpuIndex              types.String  `tfsdk:"gpu_index"`
+	GpuUUID               types.String  `tfsdk:"gpu_uuid"`
+	PciBdf                types.String  `tfsdk:"pci_bdf"`
+	GpuUtilizationPercent types.Float64 `tfsdk:"gpu_utilization_percent"`
+	FbUsedMib             types.Float64 `tfsdk:"fb_used_mib"`
+	FbFreeMib             types.Float64 `tfsdk:"fb_free_mib"`
+	PowerUsageWatts       types.Float64 `tfsdk:"power_usage_watts"`
+	TemperatureCelsius    types.Float64 `tfsdk:"temperature_celsius"`
+	GrActivity            types.Float64 `tfsdk:"gr_activity"`
+	TensorActivity        types.Float64 `tfsdk:"tensor_activity"`
+}
+
+type instanceMetricsModel struct {
+	InstanceID types.String      `tfsdk:"instance_id"`
+	NodeID     types.String      `tfsdk:"node_id"`
+	GpuCount   types.Int64       `tfsdk:"gpu_count"`
+	Gpus       []gpuMetricsModel `tfsdk:"gpus"`
+}
+
+type instanceMetricsDataModel struct {
+	InstanceIDs types.List             `tfsdk:"instance_ids"`
+	Instances   []instanceMetricsModel `tfsdk:"instances"`
+}
+
+type instanceMetricsDataSource struct {
+	client *cloudriftapi.HttpClient
+}
+
+func NewInstanceMetricsDataSource() datasource.DataSource {
+	return new(instanceMetricsDataSource)
+}
+
+func (d *instanceMetricsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_instance_metrics"
+}
+
+func (d *instanceMetricsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Read the metrics of the GPUs allocated to instances, e.g. to check that the GPUs of a Virtual Machine are visible and idle.",
+		Attributes: map[string]schema.Attribute{
+			"instance_ids": schema.ListAttribute{
+				MarkdownDescription: "IDs of the instances to read the metrics of",
+				Required:            true,
+				ElementType:         types.StringType,
+			},
+			"instances": schema.ListNestedAttribute{
+				MarkdownDescription: "Metrics of the instances, in the order of `instance_ids`. " +
+					"Instances without metrics, e.g. because they are not running yet, have a null `node_id` and no GPUs.",
+				Computed: true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"instance_id": schema.StringAttribute{
+							MarkdownDescription: "ID of the instance",
+							Computed:            true,
+						},
+						"node_id": schema.StringAttribute{
+							MarkdownDescription: "ID of the node the instance is running on",
+							Computed:            true,
+						},
+						"gpu_count": schema.Int64Attribute{
+							MarkdownDescription: "Number of GPUs reporting metrics",
+							Computed:            true,
+						},
+						"gpus": schema.ListNestedAttribute{
+							MarkdownDescription: "Metrics of the GPUs allocated to the instance. Metrics the GPU does not report are null.",
+							Computed:            true,
+							NestedObject: schema.NestedAttributeObject{
+								Attributes: map[string]schema.Attribute{
+									"gpu_index": schema.StringAttribute{
+										MarkdownDescription: "Index of the GPU on the node",
+										Computed:            true,
+									},
+									"gpu_uuid": schema.StringAttribute{
+										MarkdownDescription: "UUID of the GPU",
+										Computed:            true,
+									},
+									"pci_bdf": schema.StringAttribute{
+										MarkdownDescription: "PCI address of the GPU, e.g. `0000:41:00.0`",
+										Computed:            true,
+									},
+									"gpu_utilization_percent": schema.Float64Attribute{
+										MarkdownDescription: "Utilization of the GPU in percent",
+										Computed:            true,
+									},
+									"fb_used_mib": schema.Float64Attribute{
+										MarkdownDescription: "Used framebuffer memory in MiB",
+										Computed:            true,
+									},
+									"fb_free_mib": schema.Float64Attribute{
+										MarkdownDescription: "Free framebuffer memory in MiB",
+										Computed:            true,
+									},
+									"power_usage_watts": schema.Float64Attribute{
+										MarkdownDescription: "Power usage in watts",
+										Computed:            true,
+									},
+									"temperature_celsius": schema.Float64Attribute{
+										MarkdownDescription: "Temperature in degrees Celsius",
+										Computed:            true,
+									},
+									"gr_activity": schema.Float64Attribute{
+										MarkdownDescription: "Ratio of time the graphics/compute engine was active, from 0 to 1",
+										Computed:            true,
+									},
+									"tensor_activity": schema.Float64Attribute{
+										MarkdownDescription: "Ratio of time the tensor cores were active, from 0 to 1",
+										Computed:            true,
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *instanceMetricsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*cloudriftapi.HttpClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected DataSource Configure Type",
+			fmt.Sprintf("Expected *cloudriftapi.HttpClient, got: %T. Please report this issue to the provider developers.",
+				req.ProviderData,
+			),
+		)
+		return
+	}
+
+	d.client = client.WithContext(ctx)
+}
+
+func (d *instanceMetricsDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var config instanceMetricsDataModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() || config.InstanceIDs.IsNull() || config.InstanceIDs.IsUnknown() {
+		return
+	}
+
+	var ids []types.String
+	resp.Diagnostics.Append(config.InstanceIDs.ElementsAs(ctx, &ids, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if len(ids) == 0 {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("instance_ids"),
+			"Invalid Instance Metrics Configuration",
+			"Attribute \"instance_ids\" must contain at least one instance ID.",
+		)
+	}
+	for i, id := range ids {
+		if !id.IsUnknown() && id.ValueString() == "" {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("instance_ids").AtListIndex(i),
+				"Invalid Instance Metrics Configuration",
+				"Instance IDs must not be empty.",
+			)
+		}
+	}
+}
+
+func (d *instanceMetricsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var model instanceMetricsDataModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var ids []string
+	resp.Diagnostics.Append(model.InstanceIDs.ElementsAs(ctx, &ids, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	metrics, err := d.client.GetInstanceMetrics(ids)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error reading CloudRift Instance Metrics",
+			fmt.Sprintf("Could not read the metrics of instances %v: %s", ids, err.Error()),
+		)
+		return
+	}
+
+	byID := make(map[string]cloudriftapi.InstanceMetrics, len(metrics))
+	for _, m := range metrics {
+		byID[m.InstanceId] = m
+	}
+
+	model.Instances = make([]instanceMetricsModel, 0, len(ids))
+	for _, id := range ids {
+		instance := instanceMetricsModel{
+			InstanceID: types.StringValue(id),
+			NodeID:     types.StringNull(),
+			GpuCount:   types.Int64Value(0),
+			Gpus:       []gpuMetricsModel{},
+		}
+		if m, ok := byID[id]; ok {
+			instance.NodeID = types.StringValue(m.NodeId)
+			instance.GpuCount = types.Int64Value(int64(len(m.Gpus)))
+			for _, g := range m.Gpus {
+				instance.Gpus = append(instance.Gpus, gpuMetricsModel{
+					GpuIndex:              types.StringValue(g.GpuIndex),
+					GpuUUID:               types.StringPointerValue(g.GpuUuid),
+					PciBdf:                types.StringPointerValue(g.PciBdf),
+					GpuUtilizationPercent: types.Float64PointerValue(g.GpuUtilizationPercent),
+					FbUsedMib:             types.Float64PointerValue(g.FbUsedMib),
+					FbFreeMib:             types.Float64PointerValue(g.FbFreeMib),
+					PowerUsageWatts:       types.Float64PointerValue(g.PowerUsageWatts),
+					TemperatureCelsius:    types.Float64PointerValue(g.TemperatureCelsius),
+					GrActivity:            types.Float64PointerValue(g.GrActivity),
+					TensorActivity:        types.Float64PointerValue(g.TensorActivity),
+				})
+			}
+		}
+		model.Instances = append(model.Instances, instance)
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func Test_InstanceMetricsDataSource(t *testing.T) {
+	t.Parallel()
+
+	var metrics []cloudriftapi.InstanceMetrics
+	if err := json.Unmarshal([]byte(`
+		[
+			{
+				"instance_id": "vm-1",
+				"node_id": "node-1",
+				"gpus": [
+					{
+						"gpu_index": "0",
+						"gpu_uuid": "GPU-0000",
+						"pci_bdf": "0000:41:00.0",
+						"gpu_utilization_percent": 0,
+						"fb_used_mib": 1.5,
+						"fb_free_mib": 24562.5,
+						"power_usage_watts": 21.3,
+						"temperature_celsius": 34,
+						"gr_activity": null,
+						"tensor_activity": 0
+					}
+				]
+			}
+		]
+	`), &metrics); err != nil {
+		t.Fatal(err)
+	}
+	server := newTestAPI(t, cloudrifttest.WithInstanceMetrics(metrics...))
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig(server.URL, "1.0") + `
+					data "cloudrift_instance_metrics" "vms" {
+					  instance_ids = ["vm-1", "vm-2"]
+					}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cloudrift_instance_metrics.vms", "instances.#", "2"),
+					resource.TestCheckResourceAttr("data.cloudrift_instance_metrics.vms", "instances.0.instance_id", "vm-1"),
+					resource.TestCheckResourceAttr("data.cloudrift_instance_metrics.vms", "instances.0.node_id", "node-1"),
+					resource.TestCheckResourceAttr("data.cloudrift_instance_metrics.vms", "instances.0.gpu_count", "1"),
+					resource.TestCheckResourceAttr("data.cloudrift_instance_metrics.vms", "instances.0.gpus.0.gpu_uuid", "GPU-0000"),
+					resource.TestCheckResourceAttr("data.cloudrift_instance_metrics.vms", "instances.0.gpus.0.gpu_utilization_percent", "0"),
+					resource.TestCheckResourceAttr("data.cloudrift_instance_metrics.vms", "instances.0.gpus.0.fb_free_mib", "24562.5"),
+					resource.TestCheckNoResourceAttr("data.cloudrift_instance_metrics.vms", "instances.0.gpus.0.gr_activity"),
+					resource.TestCheckResourceAttr("data.cloudrift_instance_metrics.vms", "instances.1.instance_id", "vm-2"),
+					resource.TestCheckNoResourceAttr("data.cloudrift_instance_metrics.vms", "instances.1.node_id"),
+					resource.TestCheckResourceAttr("data.cloudrift_instance_metrics.vms", "instances.1.gpu_count", "0"),
+					func(*terraform.State) error {
+						var requested struct {
+							Selector struct {
+								ByID []string `json:"ById"`
+							} `json:"selector"`
+						}
+						if err := server.LastRequest("/api/v1/instances/metrics", &requested); err != nil {
+							return err
+						}
+						if len(requested.Selector.ByID) != 2 {
+							return fmt.Errorf("expected both instances to be selected, got %v", requested.Selector.ByID)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config: providerConfig(server.URL, "1.0") + `
+					data "cloudrift_instance_metrics" "none" {
+					  instance_ids = []
+					}
+				`,
+				ExpectError: regexp.MustCompile(`must contain at least one instance ID`),
+			},
+		},
+	})
+}
@@ -0,0 +1,161 @@
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+)
+
+// rentTestInstances rents count Virtual Machines on the fake API.
+func rentTestInstances(t *testing.T, client *cloudriftapi.HttpClient, count int) []string {
+	t.Helper()
+
+	var ids []string
+	for range count {
+		rented, err := client.RentPublicInstanceVM(cloudriftapi.RentVMOptions{
+			Recipe:       "ubuntu",
+			Datacenter:   cloudrifttest.DefaultDatacenter,
+			InstanceType: "rtx49-10c-kn.1",
+			PublicKeys:   []string{"ssh-ed25519 AAAA test"},
+		})
+		if err != nil {
+			t.Fatalf("RentPublicInstanceVM: %v", err)
+		}
+		ids = append(ids, rented.Data.InstanceIds...)
+	}
+	return ids
+}
+
+// listedByID decodes the instance IDs the last list request selected.
+func listedByID(t *testing.T, server *cloudrifttest.Server) []string {
+	t.Helper()
+
+	var listed struct {
+		Selector struct {
+			ById []string `json:"ById"`
+		} `json:"selector"`
+	}
+	if err := server.LastRequest("/api/v1/instances/list", &listed); err != nil {
+		t.Fatal(err)
+	}
+	return listed.Selector.ById
+}
+
+// Concurrent lookups are coalesced into a single ById list request per poll
+// interval, and every waiter gets the result of its own instance.
+func Test_PollInstance_BatchesConcurrentLookups(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+	client, err := cloudriftapi.NewCustom(server.URL, "test", cloudriftapi.ProtoUpcoming, "", cloudriftapi.WithPollInterval(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	rented := rentTestInstances(t, client, 3)
+
+	ids := []string{rented[2], rented[0], rented[1], rented[1], "missing"}
+	var results []<-chan cloudriftapi.InstancePollResult
+	for _, id := range ids {
+		results = append(results, client.PollInstance(id))
+	}
+
+	for i, ch := range results {
+		select {
+		case res := <-ch:
+			if ids[i] == "missing" {
+				if !errors.Is(res.Err, cloudriftapi.ErrNotFound) {
+					t.Errorf("expected ErrNotFound for an unlisted instance, got %v", res.Err)
+				}
+				continue
+			}
+			if res.Err != nil {
+				t.Fatalf("PollInstance(%q): %v", ids[i], res.Err)
+			}
+			if res.Instance.Id != ids[i] {
+				t.Errorf("PollInstance(%q) returned instance %q", ids[i], res.Instance.Id)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("PollInstance(%q) did not return", ids[i])
+		}
+	}
+
+	if got := server.Requests("/api/v1/instances/list"); got != 1 {
+		t.Fatalf("expected a single list request, got %d", got)
+	}
+	if want := append(slices.Sorted(slices.Values(rented)), "missing"); !slices.Equal(listedByID(t, server), want) {
+		t.Errorf("expected the batch %v, got %v", want, listedByID(t, server))
+	}
+}
+
+// A list request failing for every instance is reported to every waiter,
+// never as ErrNotFound.
+func Test_PollInstance_FansOutErrors(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+	client, err := cloudriftapi.NewCustom(server.URL, "test", cloudriftapi.ProtoUpcoming, "", cloudriftapi.WithPollInterval(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	rented := rentTestInstances(t, client, 2)
+	server.InjectFault(cloudrifttest.Fault{Path: "/api/v1/instances/list", Status: http.StatusInternalServerError})
+
+	first, second := client.PollInstance(rented[0]), client.PollInstance(rented[1])
+	for _, ch := range []<-chan cloudriftapi.InstancePollResult{first, second} {
+		select {
+		case res := <-ch:
+			if res.Err == nil || errors.Is(res.Err, cloudriftapi.ErrNotFound) {
+				t.Errorf("expected the list error, got %v", res.Err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("PollInstance did not return")
+		}
+	}
+}
+
+// A failed list request is retried for each instance on its own, only the
+// instance the error concerns fails.
+func Test_PollInstance_FailsOnlyTheRejectedInstance(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+	client, err := cloudriftapi.NewCustom(server.URL, "test", cloudriftapi.ProtoUpcoming, "", cloudriftapi.WithPollInterval(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	id := rentTestInstances(t, client, 1)[0]
+	server.InjectFault(cloudrifttest.Fault{
+		Path:   "/api/v1/instances/list",
+		Status: http.StatusForbidden,
+		Body:   `{"error":"instance foreign belongs to another team"}`,
+		Match:  `"foreign"`,
+	})
+
+	ok, foreign := client.PollInstance(id), client.PollInstance("foreign")
+	select {
+	case res := <-ok:
+		if res.Err != nil || res.Instance.Id != id {
+			t.Errorf("expected the instance %s, got %+v", id, res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("PollInstance(%s) did not return", id)
+	}
+	select {
+	case res := <-foreign:
+		if res.Err == nil || errors.Is(res.Err, cloudriftapi.ErrNotFound) {
+			t.Errorf("expected the list error, got %v", res.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("PollInstance(foreign) did not return")
+	}
+
+	// The batch of both instances, then one request for each of them.
+	if got := server.Requests("/api/v1/instances/list"); got != 3 {
+		t.Errorf("expected 3 list requests, got %d", got)
+	}
+}
@@ -149,7 +149,7 @@ func (d *instanceTypesSource) Schema(_ context.Context, _ datasource.SchemaReque
 	}
 }
 
-func (d *instanceTypesSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+func (d *instanceTypesSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
 	if req.ProviderData == nil {
 		return
 	}
@@ -165,7 +165,7 @@ func (d *instanceTypesSource) Configure(_ context.Context, req datasource.Config
 		return
 	}
 
-	d.client = client
+	d.client = client.WithContext(ctx)
 }
 
 func (d *instanceTypesSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
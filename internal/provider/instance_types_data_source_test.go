@@ -3,13 +3,32 @@ package provider
 import (
 	"testing"
 
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 )
 
 func Test_InstanceTypesDataSource(t *testing.T) {
 	t.Parallel()
 
-	server := defaultHttpTestServer(nil)
+	gpus := int32(1)
+	server := newTestAPI(t, cloudrifttest.WithInstanceTypes(cloudriftapi.InstanceType{
+		Name: "test",
+		Variants: []cloudriftapi.InstanceVariantInfo{{
+			Name:                "test-variant",
+			CpuCount:            10,
+			LogicalCpuCount:     20,
+			GpuCount:            &gpus,
+			Disk:                1 << 40,
+			Dram:                64 << 30,
+			Vram:                24 << 30,
+			CostPerHour:         0.85,
+			AvailableNodes:      5,
+			AvailableNodesPerDc: map[string]int32{"dc-1": 3, "dc-2": 2},
+			NodesPerDc:          map[string]int32{"dc-1": 2, "dc-2": 1},
+			IpAvailabilityPerDc: map[string]cloudriftapi.IpAvailability{"dc-1": {PublicIps: true}, "dc-2": {PublicIps: false}},
+		}},
+	}))
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
@@ -0,0 +1,68 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+// hoursPerMonth is the average number of hours in a month, 365 * 24 / 12.
+const hoursPerMonth = 730
+
+var _ function.Function = &monthlyCostFunction{}
+
+type monthlyCostFunction struct{}
+
+func NewMonthlyCostFunction() function.Function {
+	return &monthlyCostFunction{}
+}
+
+func (f *monthlyCostFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "monthly_cost"
+}
+
+func (f *monthlyCostFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Monthly cost of an instance",
+		MarkdownDescription: "Returns the cost of an instance billed `cost_per_hour` running `hours` hours a month. " +
+			"Pass `null` as `hours` for an instance running the whole month (730 hours).",
+		Parameters: []function.Parameter{
+			function.Float64Parameter{
+				Name:                "cost_per_hour",
+				MarkdownDescription: "Cost of the instance per hour, e.g. the `cost_per_hour` of an instance type.",
+			},
+			function.Float64Parameter{
+				Name:                "hours",
+				MarkdownDescription: "Hours the instance runs a month, `null` for the whole month.",
+				AllowNullValue:      true,
+			},
+		},
+		Return: function.Float64Return{},
+	}
+}
+
+func (f *monthlyCostFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var costPerHour float64
+	var hours *float64
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &costPerHour, &hours))
+	if resp.Error != nil {
+		return
+	}
+
+	if costPerHour < 0 {
+		resp.Error = function.NewArgumentFuncError(0, "cost_per_hour must not be negative")
+		return
+	}
+
+	billed := float64(hoursPerMonth)
+	if hours != nil {
+		if *hours < 0 || *hours > 24*31 {
+			resp.Error = function.NewArgumentFuncError(1, "hours must be between 0 and 744, the hours of the longest month")
+			return
+		}
+		billed = *hours
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, costPerHour*billed))
+}
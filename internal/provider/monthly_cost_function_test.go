@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func Test_MonthlyCostFunction(t *testing.T) {
+	t.Parallel()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					output "part_time" {
+					  value = provider::cloudrift::monthly_cost(0.5, 100)
+					}
+
+					output "full_month" {
+					  value = provider::cloudrift::monthly_cost(0.5, null)
+					}
+				`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("part_time", knownvalue.Float64Exact(50)),
+					statecheck.ExpectKnownOutputValue("full_month", knownvalue.Float64Exact(365)),
+				},
+			},
+			{
+				Config: `
+					output "negative" {
+					  value = provider::cloudrift::monthly_cost(-1, 100)
+					}
+				`,
+				ExpectError: regexp.MustCompile(`cost_per_hour must not be negative`),
+			},
+			{
+				Config: `
+					output "too_long" {
+					  value = provider::cloudrift::monthly_cost(1, 1000)
+					}
+				`,
+				ExpectError: regexp.MustCompile(`hours must be between 0 and 744`),
+			},
+		},
+	})
+}
@@ -0,0 +1,122 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var _ function.Function = &parseInstanceTypeFunction{}
+
+var instanceTypeNameAttrTypes = map[string]attr.Type{
+	"gpu_family": types.StringType,
+	"cpu":        types.Int64Type,
+	"dram":       types.Int64Type,
+	"disk":       types.Int64Type,
+	"count":      types.Int64Type,
+}
+
+// instanceTypeName holds the parts of an instance type name such as
+// "rtx49-7-50-500-nr.1": the GPU family, then the CPU count, DRAM and disk
+// sizes, a location code and, after the dot, the number of GPUs. Shorter
+// names like "rtx49-10c-kn.1" leave the missing sizes null.
+type instanceTypeName struct {
+	GPUFamily types.String `tfsdk:"gpu_family"`
+	CPU       types.Int64  `tfsdk:"cpu"`
+	DRAM      types.Int64  `tfsdk:"dram"`
+	Disk      types.Int64  `tfsdk:"disk"`
+	Count     types.Int64  `tfsdk:"count"`
+}
+
+type parseInstanceTypeFunction struct{}
+
+func NewParseInstanceTypeFunction() function.Function {
+	return &parseInstanceTypeFunction{}
+}
+
+func (f *parseInstanceTypeFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "parse_instance_type"
+}
+
+func (f *parseInstanceTypeFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary: "Parse an instance type name",
+		MarkdownDescription: "Splits an instance type name such as `rtx49-7-50-500-nr.1` into an object with the GPU family (`gpu_family`), " +
+			"the CPU count (`cpu`), the DRAM (`dram`) and disk (`disk`) sizes as encoded in the name, and the GPU count (`count`). " +
+			"Sizes missing from the name, e.g. in `rtx49-10c-kn.1`, are `null`.",
+		Parameters: []function.Parameter{
+			function.StringParameter{
+				Name:                "name",
+				MarkdownDescription: "Name of the instance type.",
+			},
+		},
+		Return: function.ObjectReturn{
+			AttributeTypes: instanceTypeNameAttrTypes,
+		},
+	}
+}
+
+func (f *parseInstanceTypeFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var name string
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &name))
+	if resp.Error != nil {
+		return
+	}
+
+	parsed, err := parseInstanceTypeName(name)
+	if err != nil {
+		resp.Error = function.NewArgumentFuncError(0, err.Error())
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, parsed))
+}
+
+func parseInstanceTypeName(name string) (instanceTypeName, error) {
+	parsed := instanceTypeName{
+		CPU:  types.Int64Null(),
+		DRAM: types.Int64Null(),
+		Disk: types.Int64Null(),
+	}
+
+	base, count, ok := strings.Cut(name, ".")
+	if !ok {
+		return parsed, fmt.Errorf("instance type %q has no GPU count, expected a name like \"rtx49-7-50-500-nr.1\"", name)
+	}
+	n, err := strconv.ParseInt(count, 10, 64)
+	if err != nil || n < 1 {
+		return parsed, fmt.Errorf("instance type %q has an invalid GPU count %q", name, count)
+	}
+	parsed.Count = types.Int64Value(n)
+
+	parts := strings.Split(base, "-")
+	if parts[0] == "" {
+		return parsed, fmt.Errorf("instance type %q has no GPU family", name)
+	}
+	parsed.GPUFamily = types.StringValue(parts[0])
+
+	sizes := []*types.Int64{&parsed.CPU, &parsed.DRAM, &parsed.Disk}
+	for i, part := range parts[1:] {
+		// The CPU count may carry a "c" suffix, as in "rtx49-10c-kn.1".
+		v, err := strconv.ParseInt(strings.TrimSuffix(part, "c"), 10, 64)
+		if err != nil {
+			// Only the trailing location code is not a number.
+			if i != len(parts)-2 {
+				return parsed, fmt.Errorf("instance type %q has an invalid size %q", name, part)
+			}
+			break
+		}
+		if i >= len(sizes) {
+			return parsed, fmt.Errorf("instance type %q has more sizes than CPU, DRAM and disk", name)
+		}
+		*sizes[i] = types.Int64Value(v)
+	}
+
+	return parsed, nil
+}
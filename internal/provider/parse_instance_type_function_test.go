@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+)
+
+func Test_ParseInstanceTypeFunction(t *testing.T) {
+	t.Parallel()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_8_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: `
+					output "test" {
+					  value = provider::cloudrift::parse_instance_type("rtx49-7-50-500-nr.1")
+					}
+				`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownOutputValue("test", knownvalue.ObjectExact(map[string]knownvalue.Check{
+						"gpu_family": knownvalue.StringExact("rtx49"),
+						"cpu":        knownvalue.Int64Exact(7),
+						"dram":       knownvalue.Int64Exact(50),
+						"disk":       knownvalue.Int64Exact(500),
+						"count":      knownvalue.Int64Exact(1),
+					})),
+				},
+			},
+			{
+				Config: `
+					output "test" {
+					  value = provider::cloudrift::parse_instance_type("rtx49")
+					}
+				`,
+				ExpectError: regexp.MustCompile(`has no GPU count`),
+			},
+		},
+	})
+}
+
+func Test_ParseInstanceTypeName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		want    instanceTypeName
+		wantErr bool
+	}{
+		{
+			name: "rtx49-7-50-500-nr.1",
+			want: instanceTypeName{
+				GPUFamily: types.StringValue("rtx49"),
+				CPU:       types.Int64Value(7),
+				DRAM:      types.Int64Value(50),
+				Disk:      types.Int64Value(500),
+				Count:     types.Int64Value(1),
+			},
+		},
+		{
+			name: "rtx49-10c-kn.1",
+			want: instanceTypeName{
+				GPUFamily: types.StringValue("rtx49"),
+				CPU:       types.Int64Value(10),
+				DRAM:      types.Int64Null(),
+				Disk:      types.Int64Null(),
+				Count:     types.Int64Value(1),
+			},
+		},
+		{
+			name: "h100-16-128-1000.8",
+			want: instanceTypeName{
+				GPUFamily: types.StringValue("h100"),
+				CPU:       types.Int64Value(16),
+				DRAM:      types.Int64Value(128),
+				Disk:      types.Int64Value(1000),
+				Count:     types.Int64Value(8),
+			},
+		},
+		{name: "rtx49", wantErr: true},
+		{name: "rtx49-7.0", wantErr: true},
+		{name: "-7-50.1", wantErr: true},
+		{name: "rtx49-7-x-500-nr.1", wantErr: true},
+		{name: "rtx49-7-50-500-1-nr.1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseInstanceTypeName(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseInstanceTypeName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("parseInstanceTypeName(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
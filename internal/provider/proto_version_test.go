@@ -0,0 +1,178 @@
+package provider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+)
+
+// protoVersionServer serves the fake CloudRift API, listing latest as the
+// latest version of the server. It accepts the listed protocol versions on
+// /instances/list, rejects the others as the CloudRift API does, and records
+// the probed ones.
+func protoVersionServer(t *testing.T, latest string, accepted ...string) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	var (
+		mu     sync.Mutex
+		probed []string
+	)
+	fake := newTestAPI(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/api/v1/capabilities/list":
+			w.Header().Set("Content-Type", "json")
+			w.WriteHeader(http.StatusOK)
+			_, _ = fmt.Fprintf(w, `{"version": %q, "data": {"features": [{"nodes": {"read": true}}], "is_admin": false}}`, latest)
+			return
+		case "/api/v1/instances/list":
+			var body struct {
+				Version string `json:"version"`
+			}
+			b, _ := io.ReadAll(req.Body)
+			_ = json.Unmarshal(b, &body)
+			req.Body = io.NopCloser(bytes.NewReader(b))
+
+			mu.Lock()
+			probed = append(probed, body.Version)
+			mu.Unlock()
+
+			if !slices.Contains(accepted, body.Version) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = fmt.Fprintf(w, `{"error": "unsupported version %s"}`, body.Version)
+				return
+			}
+		}
+		fake.Config.Handler.ServeHTTP(w, req)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(probed)
+	}
+}
+
+func Test_ProtoVersion_NegotiatesLatest(t *testing.T) {
+	t.Parallel()
+
+	server, probed := protoVersionServer(t, cloudriftapi.Proto20250610, cloudriftapi.Proto20250610, cloudriftapi.ProtoUpcoming)
+
+	client, err := cloudriftapi.NewCustom(server.URL, "test", "", "")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	if client.ProtoVersion != cloudriftapi.Proto20250610 {
+		t.Errorf("expected the negotiated version %q, got %q", cloudriftapi.Proto20250610, client.ProtoVersion)
+	}
+	// The latest version of the server is tried first.
+	if want := []string{cloudriftapi.Proto20250610}; !slices.Equal(probed(), want) {
+		t.Errorf("expected the probes %v, got %v", want, probed())
+	}
+	if caps := client.Capabilities(); caps == nil || len(caps.Data.Features) != 1 {
+		t.Errorf("expected the listed capabilities, got %+v", caps)
+	}
+}
+
+func Test_ProtoVersion_NegotiatesNewestSupported(t *testing.T) {
+	t.Parallel()
+
+	server, probed := protoVersionServer(t, cloudriftapi.Proto20250610, cloudriftapi.Proto20250529, cloudriftapi.Proto20250321)
+
+	client, err := cloudriftapi.NewCustom(server.URL, "test", "", "")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	if client.ProtoVersion != cloudriftapi.Proto20250529 {
+		t.Errorf("expected the negotiated version %q, got %q", cloudriftapi.Proto20250529, client.ProtoVersion)
+	}
+	// Versions newer than the latest one of the server are not probed.
+	if want := []string{cloudriftapi.Proto20250610, cloudriftapi.ProtoUpcoming, cloudriftapi.Proto20250529}; !slices.Equal(probed(), want) {
+		t.Errorf("expected the probes %v, got %v", want, probed())
+	}
+}
+
+func Test_ProtoVersion_FallsBackToUpcoming(t *testing.T) {
+	t.Parallel()
+
+	server, probed := protoVersionServer(t, cloudriftapi.Proto20260510, cloudriftapi.ProtoUpcoming)
+
+	client, err := cloudriftapi.NewCustom(server.URL, "test", "", "")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	if client.ProtoVersion != cloudriftapi.ProtoUpcoming {
+		t.Errorf("expected the negotiated version %q, got %q", cloudriftapi.ProtoUpcoming, client.ProtoVersion)
+	}
+	if want := []string{cloudriftapi.Proto20260510, cloudriftapi.ProtoUpcoming}; !slices.Equal(probed(), want) {
+		t.Errorf("expected the probes %v, got %v", want, probed())
+	}
+}
+
+// Only an accepted probe counts as support, any other failure ends the
+// negotiation.
+func Test_ProtoVersion_ProbeError(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+	server.InjectFault(cloudrifttest.Fault{Path: "/api/v1/instances/list", Status: http.StatusForbidden, Body: `{"error": "forbidden"}`})
+
+	_, err := cloudriftapi.NewCustom(server.URL, "test", "", "")
+	if err == nil || errors.Is(err, cloudriftapi.ErrUnsupportedVersion) {
+		t.Fatalf("expected the probe error, got %v", err)
+	}
+	if want := "failed to probe the protocol version"; !strings.Contains(err.Error(), want) {
+		t.Errorf("expected %q in the error, got %v", want, err)
+	}
+}
+
+func Test_ProtoVersion_Pinned(t *testing.T) {
+	t.Parallel()
+
+	server, probed := protoVersionServer(t, cloudriftapi.Proto20260510, cloudriftapi.Proto20250321, cloudriftapi.Proto20240922)
+
+	client, err := cloudriftapi.NewCustom(server.URL, "test", cloudriftapi.Proto20240922, "")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	if client.ProtoVersion != cloudriftapi.Proto20240922 {
+		t.Errorf("expected the pinned version %q, got %q", cloudriftapi.Proto20240922, client.ProtoVersion)
+	}
+	if len(probed()) != 0 {
+		t.Errorf("expected the pinned version not to be probed, got %v", probed())
+	}
+
+	_, err = cloudriftapi.NewCustom(server.URL, "test", "2030-01-01", "")
+	if !errors.Is(err, cloudriftapi.ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+	if want := `the latest version of the CloudRift API is "2026-05-10"`; !strings.Contains(err.Error(), want) {
+		t.Errorf("expected %q in the error, got %v", want, err)
+	}
+}
+
+func Test_ProtoVersion_NoneSupported(t *testing.T) {
+	t.Parallel()
+
+	server, _ := protoVersionServer(t, "2030-01-01")
+
+	_, err := cloudriftapi.NewCustom(server.URL, "test", "", "")
+	if !errors.Is(err, cloudriftapi.ErrUnsupportedVersion) {
+		t.Fatalf("expected ErrUnsupportedVersion, got %v", err)
+	}
+	if want := `the latest version of the server is "2030-01-01"`; !strings.Contains(err.Error(), want) {
+		t.Errorf("expected %q in the error, got %v", want, err)
+	}
+}
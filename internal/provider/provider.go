@@ -2,37 +2,54 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
 	"github.com/hashicorp/terraform-plugin-framework/function"
+	"github.com/hashicorp/terraform-plugin-framework/list"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
 var (
 	_ provider.Provider                       = &CloudRiftProvider{}
 	_ provider.ProviderWithFunctions          = &CloudRiftProvider{}
 	_ provider.ProviderWithEphemeralResources = &CloudRiftProvider{}
+	_ provider.ProviderWithListResources      = &CloudRiftProvider{}
 )
 
 type CloudRiftProviderModel struct {
 	// X-API-Token used for interacting with the CloudRift platform API.
 	Token types.String `tfsdk:"token"`
 
+	// Optional file the token is read from, e.g. a secret mounted in CI.
+	TokenFile types.String `tfsdk:"token_file"`
+
+	// Optional profile of the credentials file providing the settings not
+	// configured otherwise, see credentialsProfile.
+	Profile         types.String `tfsdk:"profile"`
+	CredentialsFile types.String `tfsdk:"credentials_file"`
+
 	// Optional URL configurable at the provider level, if not set the default
 	// URL from the openapispec will be used.
 	BaseURL types.String `tfsdk:"base_url"`
 
 	// Optional Protocol Version configurable at the provider level, if not set the
-	// default protocol version will be used.
+	// newest version supported by the API is negotiated.
 	ProtoVersion types.String `tfsdk:"proto_version"`
 
 	// Optional Team ID for team-scoped operations (instance provisioning).
@@ -41,6 +58,28 @@ type CloudRiftProviderModel struct {
 	// Optional per-request HTTP timeout (Go duration string, e.g. "30s").
 	// If not set the provider uses cloudriftapi.DefaultRequestTimeout.
 	RequestTimeout types.String `tfsdk:"request_timeout"`
+
+	// Optional number of hours the balance must cover the planned Virtual
+	// Machines for, checked at plan time.
+	MinBalanceHours types.Float64 `tfsdk:"min_balance_hours"`
+
+	// Optional suffix of the User-Agent header sent to the CloudRift API.
+	UserAgentExtra types.String `tfsdk:"user_agent_extra"`
+
+	// Optional network settings of the API client, see transportOptions.
+	HTTPProxy          types.String `tfsdk:"http_proxy"`
+	CACertFile         types.String `tfsdk:"ca_cert_file"`
+	CACertPEM          types.String `tfsdk:"ca_cert_pem"`
+	ClientCert         types.String `tfsdk:"client_cert"`
+	ClientKey          types.String `tfsdk:"client_key"`
+	InsecureSkipVerify types.Bool   `tfsdk:"insecure_skip_verify"`
+}
+
+// resourceData is the provider data passed to resources, which also share
+// state across the resources of a run.
+type resourceData struct {
+	client    *cloudriftapi.HttpClient
+	preflight *balancePreflight
 }
 
 type CloudRiftProvider struct {
@@ -48,6 +87,9 @@ type CloudRiftProvider struct {
 	// provider is built and ran locally, and "test" when running acceptance
 	// testing.
 	version string
+	// clientOptions are applied after the options of the configuration,
+	// acceptance tests replaying cassettes swap the transport with them.
+	clientOptions []cloudriftapi.HttpClientOption
 }
 
 func (p *CloudRiftProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
@@ -66,6 +108,27 @@ func (p *CloudRiftProvider) Schema(ctx context.Context, req provider.SchemaReque
 				Sensitive:           true,
 				Optional:            true, // can be fetched from env.
 			},
+			"token_file": schema.StringAttribute{
+				Description: "Path of a file holding the token for CloudRift platform API, e.g. a secret mounted in CI. Ignored if token is set. " +
+					"May also be provided via CLOUDRIFT_TOKEN_FILE environment variable.",
+				MarkdownDescription: "Path of a file holding the token for CloudRift platform API, e.g. a secret mounted in CI. Ignored if `token` is set. " +
+					"May also be provided via CLOUDRIFT_TOKEN_FILE environment variable.",
+				Optional: true,
+			},
+			"profile": schema.StringAttribute{
+				Description: "Profile of the credentials file providing token, base_url, team_id and proto_version when they are not set otherwise. Defaults to default. " +
+					"May also be provided via CLOUDRIFT_PROFILE environment variable.",
+				MarkdownDescription: "Profile of the credentials file providing `token`, `base_url`, `team_id` and `proto_version` when they are not set otherwise. Defaults to `default`. " +
+					"May also be provided via CLOUDRIFT_PROFILE environment variable.",
+				Optional: true,
+			},
+			"credentials_file": schema.StringAttribute{
+				Description: "Path of the credentials file. Defaults to ~/.cloudrift/credentials. " +
+					"May also be provided via CLOUDRIFT_CREDENTIALS_FILE environment variable.",
+				MarkdownDescription: "Path of the credentials file. Defaults to `~/.cloudrift/credentials`. " +
+					"May also be provided via CLOUDRIFT_CREDENTIALS_FILE environment variable.",
+				Optional: true,
+			},
 			"base_url": schema.StringAttribute{
 				Description: "Base URL for the CloudRift platform API. If not specified the provider has a built in default Base URL that will be used." +
 					"May also be provided via CLOUDRIFT_BASE_URL environment variable.",
@@ -75,9 +138,9 @@ func (p *CloudRiftProvider) Schema(ctx context.Context, req provider.SchemaReque
 			},
 			"proto_version": schema.StringAttribute{
 				Description: "Protocol Version to be used for the CloudRift platform API." +
-					"If not specified the provider has a built in default version that will be used. May also be provided via CLOUDRIFT_PROTO_VERSION environment variable.",
+					"If not specified the provider negotiates a version supported by the API, starting with the latest one it lists. May also be provided via CLOUDRIFT_PROTO_VERSION environment variable.",
 				MarkdownDescription: "Protocol Version to be used for the CloudRift platform API." +
-					"If not specified the provider has a built in default version that will be used. May also be provided via CLOUDRIFT_PROTO_VERSION environment variable.",
+					"If not specified the provider negotiates a version supported by the API, starting with the latest one it lists. May also be provided via CLOUDRIFT_PROTO_VERSION environment variable.",
 				Optional: true, // can be fetched from env.
 			},
 			"team_id": schema.StringAttribute{
@@ -92,6 +155,63 @@ func (p *CloudRiftProvider) Schema(ctx context.Context, req provider.SchemaReque
 					"May also be provided via CLOUDRIFT_REQUEST_TIMEOUT environment variable.",
 				Optional: true,
 			},
+			"min_balance_hours": schema.Float64Attribute{
+				Description: "Fail the plan when the balance of the account, or of the team if team_id is set, cannot run the Virtual Machines and Cluster instances rented by the plan for this many hours. " +
+					"May also be provided via CLOUDRIFT_MIN_BALANCE_HOURS environment variable.",
+				MarkdownDescription: "Fail the plan when the balance of the account, or of the team if `team_id` is set, cannot run the Virtual Machines and Cluster instances rented by the plan for this many hours. " +
+					"May also be provided via CLOUDRIFT_MIN_BALANCE_HOURS environment variable.",
+				Optional: true,
+			},
+			"user_agent_extra": schema.StringAttribute{
+				Description: "Appended to the User-Agent header sent to the CloudRift API, e.g. to tell the requests of a CI pipeline apart. " +
+					"May also be provided via CLOUDRIFT_USER_AGENT_EXTRA environment variable.",
+				MarkdownDescription: "Appended to the User-Agent header sent to the CloudRift API, e.g. to tell the requests of a CI pipeline apart. " +
+					"May also be provided via CLOUDRIFT_USER_AGENT_EXTRA environment variable.",
+				Optional: true,
+			},
+			"http_proxy": schema.StringAttribute{
+				Description: "URL of the proxy to send the CloudRift API requests through, e.g. http://proxy.example.com:3128. Defaults to the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables. " +
+					"May also be provided via CLOUDRIFT_HTTP_PROXY environment variable.",
+				MarkdownDescription: "URL of the proxy to send the CloudRift API requests through, e.g. `http://proxy.example.com:3128`. Defaults to the `HTTPS_PROXY`, `HTTP_PROXY` and `NO_PROXY` environment variables. " +
+					"May also be provided via CLOUDRIFT_HTTP_PROXY environment variable.",
+				Optional: true,
+			},
+			"ca_cert_file": schema.StringAttribute{
+				Description: "Path of a file with PEM encoded CA certificates to trust in addition to the system ones, e.g. the CA of a TLS-intercepting proxy. " +
+					"May also be provided via CLOUDRIFT_CA_CERT_FILE environment variable.",
+				MarkdownDescription: "Path of a file with PEM encoded CA certificates to trust in addition to the system ones, e.g. the CA of a TLS-intercepting proxy. " +
+					"May also be provided via CLOUDRIFT_CA_CERT_FILE environment variable.",
+				Optional: true,
+			},
+			"ca_cert_pem": schema.StringAttribute{
+				Description: "PEM encoded CA certificates to trust in addition to the system ones, like ca_cert_file. " +
+					"May also be provided via CLOUDRIFT_CA_CERT_PEM environment variable.",
+				MarkdownDescription: "PEM encoded CA certificates to trust in addition to the system ones, like `ca_cert_file`. " +
+					"May also be provided via CLOUDRIFT_CA_CERT_PEM environment variable.",
+				Optional: true,
+			},
+			"client_cert": schema.StringAttribute{
+				Description: "PEM encoded client certificate for mutual TLS, requires client_key. " +
+					"May also be provided via CLOUDRIFT_CLIENT_CERT environment variable.",
+				MarkdownDescription: "PEM encoded client certificate for mutual TLS, requires `client_key`. " +
+					"May also be provided via CLOUDRIFT_CLIENT_CERT environment variable.",
+				Optional: true,
+			},
+			"client_key": schema.StringAttribute{
+				Description: "PEM encoded private key of client_cert. " +
+					"May also be provided via CLOUDRIFT_CLIENT_KEY environment variable.",
+				MarkdownDescription: "PEM encoded private key of `client_cert`. " +
+					"May also be provided via CLOUDRIFT_CLIENT_KEY environment variable.",
+				Sensitive: true,
+				Optional:  true,
+			},
+			"insecure_skip_verify": schema.BoolAttribute{
+				Description: "Skip the verification of the TLS certificate of the CloudRift API. Only meant for lab endpoints with self-signed certificates. " +
+					"May also be provided via CLOUDRIFT_INSECURE_SKIP_VERIFY environment variable.",
+				MarkdownDescription: "Skip the verification of the TLS certificate of the CloudRift API. Only meant for lab endpoints with self-signed certificates. " +
+					"May also be provided via CLOUDRIFT_INSECURE_SKIP_VERIFY environment variable.",
+				Optional: true,
+			},
 		},
 	}
 }
@@ -113,6 +233,33 @@ func (p *CloudRiftProvider) Configure(ctx context.Context, req provider.Configur
 		)
 	}
 
+	if config.TokenFile.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("token_file"),
+			"Unknown CloudRift API Token File",
+			"The provider cannot create the CloudRift API client as there is an unknown configuration for the CloudRift API token file."+
+				"Either target apply the source of the value first, set the value statically in the configuration, or use the CLOUDRIFT_TOKEN_FILE environment variable.",
+		)
+	}
+
+	if config.Profile.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("profile"),
+			"Unknown CloudRift Profile",
+			"The provider cannot create the CloudRift API client as there is an unknown configuration for the CloudRift profile."+
+				"Either target apply the source of the value first, set the value statically in the configuration, or use the CLOUDRIFT_PROFILE environment variable.",
+		)
+	}
+
+	if config.CredentialsFile.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("credentials_file"),
+			"Unknown CloudRift Credentials File",
+			"The provider cannot create the CloudRift API client as there is an unknown configuration for the CloudRift credentials file."+
+				"Either target apply the source of the value first, set the value statically in the configuration, or use the CLOUDRIFT_CREDENTIALS_FILE environment variable.",
+		)
+	}
+
 	if config.BaseURL.IsUnknown() {
 		resp.Diagnostics.AddAttributeError(
 			path.Root("base_url"),
@@ -149,6 +296,24 @@ func (p *CloudRiftProvider) Configure(ctx context.Context, req provider.Configur
 		)
 	}
 
+	if config.MinBalanceHours.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("min_balance_hours"),
+			"Unknown CloudRift Minimum Balance Hours",
+			"The provider cannot create the CloudRift API client as there is an unknown configuration for the CloudRift minimum balance hours."+
+				"Either target apply the source of the value first, set the value statically in the configuration, or use the CLOUDRIFT_MIN_BALANCE_HOURS environment variable.",
+		)
+	}
+
+	if config.UserAgentExtra.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("user_agent_extra"),
+			"Unknown CloudRift User-Agent Extra",
+			"The provider cannot create the CloudRift API client as there is an unknown configuration for the CloudRift User-Agent extra."+
+				"Either target apply the source of the value first, set the value statically in the configuration, or use the CLOUDRIFT_USER_AGENT_EXTRA environment variable.",
+		)
+	}
+
 	if resp.Diagnostics.HasError() {
 		return
 	}
@@ -158,9 +323,41 @@ func (p *CloudRiftProvider) Configure(ctx context.Context, req provider.Configur
 	protoVersion := os.Getenv("CLOUDRIFT_PROTO_VERSION")
 	teamID := os.Getenv("CLOUDRIFT_TEAM_ID")
 	requestTimeout := os.Getenv("CLOUDRIFT_REQUEST_TIMEOUT")
-
-	if !config.Token.IsNull() {
+	minBalanceHours := os.Getenv("CLOUDRIFT_MIN_BALANCE_HOURS")
+	userAgentExtra := os.Getenv("CLOUDRIFT_USER_AGENT_EXTRA")
+	tokenFile := os.Getenv("CLOUDRIFT_TOKEN_FILE")
+	profileName := os.Getenv("CLOUDRIFT_PROFILE")
+	credentialsFile := os.Getenv("CLOUDRIFT_CREDENTIALS_FILE")
+
+	// Every setting is taken from its attribute, else its environment
+	// variable, else the profile of the credentials file. The token files
+	// come right after the token of the same source.
+	switch {
+	case !config.Token.IsNull():
 		token = config.Token.ValueString()
+	case !config.TokenFile.IsNull():
+		token, tokenFile = "", config.TokenFile.ValueString()
+	}
+
+	if token == "" && tokenFile != "" {
+		t, err := readTokenFile(tokenFile)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("token_file"),
+				"Invalid CloudRift API Token File",
+				"The provider cannot read the CloudRift API token: "+err.Error(),
+			)
+			return
+		}
+		token = t
+	}
+
+	if !config.Profile.IsNull() {
+		profileName = config.Profile.ValueString()
+	}
+
+	if !config.CredentialsFile.IsNull() {
+		credentialsFile = config.CredentialsFile.ValueString()
 	}
 
 	if !config.BaseURL.IsNull() {
@@ -179,15 +376,65 @@ func (p *CloudRiftProvider) Configure(ctx context.Context, req provider.Configur
 		requestTimeout = config.RequestTimeout.ValueString()
 	}
 
+	if !config.MinBalanceHours.IsNull() {
+		minBalanceHours = strconv.FormatFloat(config.MinBalanceHours.ValueFloat64(), 'g', -1, 64)
+	}
+
+	if !config.UserAgentExtra.IsNull() {
+		userAgentExtra = config.UserAgentExtra.ValueString()
+	}
+
+	// A profile or credentials file configured on purpose must exist, the
+	// default profile of the default file is optional, and only read when
+	// the token is not set otherwise.
+	explicitProfile := profileName != "" || credentialsFile != ""
+	if profileName == "" {
+		profileName = defaultProfile
+	}
+	if credentialsFile == "" {
+		credentialsFile = defaultCredentialsFile()
+	}
+	var profile credentialsProfile
+	if explicitProfile || token == "" {
+		var unknown []string
+		var err error
+		profile, unknown, err = loadCredentialsProfile(credentialsFile, profileName, explicitProfile)
+		if err != nil {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("profile"),
+				"Invalid CloudRift Credentials Profile",
+				"The provider cannot load the CloudRift credentials profile: "+err.Error(),
+			)
+			return
+		}
+		if len(unknown) > 0 {
+			resp.Diagnostics.AddAttributeWarning(
+				path.Root("profile"),
+				"Unknown CloudRift Credentials Keys",
+				fmt.Sprintf("The keys %s of the profile %q of %s are ignored, the provider only reads token, base_url, team_id and proto_version.",
+					strings.Join(unknown, ", "), profileName, credentialsFile),
+			)
+		}
+	}
+
+	if token == "" {
+		token = profile.Token
+	}
+
 	if baseURL == "" {
-		baseURL = cloudriftapi.Endpoint
+		baseURL = profile.BaseURL
+	}
+
+	if teamID == "" {
+		teamID = profile.TeamID
 	}
 
-	// Default to ~upcoming because team-scoped selectors (scope field)
-	// are not yet available in any dated API version.
-	// TODO: switch to a fixed version once one supports team scope.
 	if protoVersion == "" {
-		protoVersion = cloudriftapi.ProtoUpcoming
+		protoVersion = profile.ProtoVersion
+	}
+
+	if baseURL == "" {
+		baseURL = cloudriftapi.Endpoint
 	}
 
 	if token == "" {
@@ -195,7 +442,7 @@ func (p *CloudRiftProvider) Configure(ctx context.Context, req provider.Configur
 			path.Root("token"),
 			"Missing CloudRift API Token",
 			"The provider cannot create the CloudRift API client as there is a missing or empty value for the CloudRift API token."+
-				"Set the token value in the configuration or use the CLOUDRIFT_TOKEN environment variable."+
+				"Set the token or token_file value in the configuration, use the CLOUDRIFT_TOKEN or CLOUDRIFT_TOKEN_FILE environment variable, or add the token to a profile of the credentials file."+
 				"If either is already set, ensure the value is not empty.",
 		)
 	}
@@ -214,16 +461,48 @@ func (p *CloudRiftProvider) Configure(ctx context.Context, req provider.Configur
 		timeout = d
 	}
 
+	var minHours float64
+	if minBalanceHours != "" {
+		h, err := strconv.ParseFloat(minBalanceHours, 64)
+		if err != nil || h <= 0 {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("min_balance_hours"),
+				"Invalid CloudRift Minimum Balance Hours",
+				"min_balance_hours must be a positive number of hours, got: "+minBalanceHours,
+			)
+			return
+		}
+		minHours = h
+	}
+
+	transportOpts := transportOptions(config, &resp.Diagnostics)
+
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
 	// retries kept low: each attempt now has a generous timeout, so 3 attempts
 	// max is a bounded worst case rather than many short hangs.
-	client, err := cloudriftapi.NewCustom(baseURL, token, protoVersion, teamID,
+	opts := append([]cloudriftapi.HttpClientOption{
 		cloudriftapi.WithRetryableHttpClient(2),
 		cloudriftapi.WithTimeout(timeout),
-	)
+		cloudriftapi.WithPollInterval(InstancePollingInterval),
+		cloudriftapi.WithUserAgent(userAgent(p.version, req.TerraformVersion, userAgentExtra)),
+		cloudriftapi.WithTransportWrapper(func(rt http.RoundTripper) http.RoundTripper {
+			return newAPILoggingTransport(token, rt)
+		}),
+	}, transportOpts...)
+	opts = append(opts, p.clientOptions...)
+	client, err := cloudriftapi.NewCustomWithContext(ctx, baseURL, token, protoVersion, teamID, opts...)
+	if errors.Is(err, cloudriftapi.ErrUnsupportedVersion) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("proto_version"),
+			"Unsupported CloudRift Protocol Version",
+			"The CloudRift API does not support the protocol version of the provider.\n\n"+
+				"CloudRift Client Error: "+err.Error(),
+		)
+		return
+	}
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Unable to create CloudRift API Client",
@@ -234,19 +513,41 @@ func (p *CloudRiftProvider) Configure(ctx context.Context, req provider.Configur
 		return
 	}
 
+	tflog.Info(ctx, "Negotiated CloudRift API protocol version", map[string]any{
+		"proto_version": client.ProtoVersion,
+		"pinned":        protoVersion != "",
+	})
+
 	resp.DataSourceData = client
-	resp.ResourceData = client
+	resp.ResourceData = &resourceData{
+		client:    client,
+		preflight: newBalancePreflight(client, minHours),
+	}
+	resp.ListResourceData = client
+	resp.EphemeralResourceData = client
 }
 
 func (p *CloudRiftProvider) Resources(ctx context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewSSHKeyResource,
 		NewInstanceResource,
+		NewAutoTopUpResource,
+		NewClusterResource,
 	}
 }
 
 func (p *CloudRiftProvider) EphemeralResources(ctx context.Context) []func() ephemeral.EphemeralResource {
-	return []func() ephemeral.EphemeralResource{}
+	return []func() ephemeral.EphemeralResource{
+		NewInstanceCredentialsEphemeralResource,
+		NewSSHKeyPairEphemeralResource,
+	}
+}
+
+func (p *CloudRiftProvider) ListResources(ctx context.Context) []func() list.ListResource {
+	return []func() list.ListResource{
+		NewSSHKeyListResource,
+		NewInstanceListResource,
+	}
 }
 
 func (p *CloudRiftProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
@@ -254,11 +555,105 @@ func (p *CloudRiftProvider) DataSources(ctx context.Context) []func() datasource
 		NewSSHKeyDataSource,
 		NewRecipesDataSource,
 		NewInstanceTypesDataSource,
+		NewAccountDataSource,
+		NewTransactionsDataSource,
+		NewInstanceMetricsDataSource,
+		NewSavedEnvironmentsDataSource,
 	}
 }
 
 func (p *CloudRiftProvider) Functions(ctx context.Context) []func() function.Function {
-	return []func() function.Function{}
+	return []func() function.Function{
+		NewMonthlyCostFunction,
+		NewParseInstanceTypeFunction,
+		NewVRAMGiBFunction,
+	}
+}
+
+// transportOptions returns the proxy and TLS options of the API client, the
+// attributes fall back to their CLOUDRIFT_* environment variables.
+func transportOptions(config CloudRiftProviderModel, diags *diag.Diagnostics) []cloudriftapi.HttpClientOption {
+	setting := func(attr string, v types.String, env string) string {
+		if v.IsUnknown() {
+			diags.AddAttributeError(
+				path.Root(attr),
+				"Unknown CloudRift Client Setting",
+				"The provider cannot create the CloudRift API client as there is an unknown configuration for "+attr+"."+
+					"Either target apply the source of the value first, set the value statically in the configuration, or use the "+env+" environment variable.",
+			)
+			return ""
+		}
+		if !v.IsNull() {
+			return v.ValueString()
+		}
+		return os.Getenv(env)
+	}
+
+	proxy := setting("http_proxy", config.HTTPProxy, "CLOUDRIFT_HTTP_PROXY")
+	caFile := setting("ca_cert_file", config.CACertFile, "CLOUDRIFT_CA_CERT_FILE")
+	caPEM := setting("ca_cert_pem", config.CACertPEM, "CLOUDRIFT_CA_CERT_PEM")
+	clientCert := setting("client_cert", config.ClientCert, "CLOUDRIFT_CLIENT_CERT")
+	clientKey := setting("client_key", config.ClientKey, "CLOUDRIFT_CLIENT_KEY")
+
+	var insecure bool
+	switch {
+	case config.InsecureSkipVerify.IsUnknown():
+		diags.AddAttributeError(
+			path.Root("insecure_skip_verify"),
+			"Unknown CloudRift Client Setting",
+			"The provider cannot create the CloudRift API client as there is an unknown configuration for insecure_skip_verify."+
+				"Either target apply the source of the value first, set the value statically in the configuration, or use the CLOUDRIFT_INSECURE_SKIP_VERIFY environment variable.",
+		)
+	case !config.InsecureSkipVerify.IsNull():
+		insecure = config.InsecureSkipVerify.ValueBool()
+	case os.Getenv("CLOUDRIFT_INSECURE_SKIP_VERIFY") != "":
+		v, err := strconv.ParseBool(os.Getenv("CLOUDRIFT_INSECURE_SKIP_VERIFY"))
+		if err != nil {
+			diags.AddAttributeError(
+				path.Root("insecure_skip_verify"),
+				"Invalid CloudRift Client Setting",
+				"CLOUDRIFT_INSECURE_SKIP_VERIFY must be a boolean, got: "+os.Getenv("CLOUDRIFT_INSECURE_SKIP_VERIFY"),
+			)
+		}
+		insecure = v
+	}
+
+	if (clientCert == "") != (clientKey == "") {
+		diags.AddAttributeError(
+			path.Root("client_cert"),
+			"Invalid CloudRift Client Setting",
+			"client_cert and client_key must be set together for mutual TLS.",
+		)
+	}
+
+	if insecure {
+		diags.AddAttributeWarning(
+			path.Root("insecure_skip_verify"),
+			"TLS Verification Disabled",
+			"The TLS certificate of the CloudRift API is not verified, do not use insecure_skip_verify outside of lab environments.",
+		)
+	}
+
+	return []cloudriftapi.HttpClientOption{
+		cloudriftapi.WithProxy(proxy),
+		cloudriftapi.WithCACertFile(caFile),
+		cloudriftapi.WithCACertPEM([]byte(caPEM)),
+		cloudriftapi.WithClientCertificate([]byte(clientCert), []byte(clientKey)),
+		cloudriftapi.WithInsecureSkipVerify(insecure),
+	}
+}
+
+// userAgent identifies the provider and Terraform versions to the CloudRift
+// API, so that CloudRift support can find the requests of a user.
+func userAgent(version, terraformVersion, extra string) string {
+	ua := cloudriftapi.DefaultUserAgent + "/" + version
+	if terraformVersion != "" {
+		ua += " terraform/" + terraformVersion
+	}
+	if extra = strings.TrimSpace(extra); extra != "" {
+		ua += " " + extra
+	}
+	return ua
 }
 
 func New(version string) func() provider.Provider {
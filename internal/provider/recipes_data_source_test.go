@@ -9,7 +9,7 @@ import (
 func Test_RecipesDataSource(t *testing.T) {
 	t.Parallel()
 
-	server := defaultHttpTestServer(nil)
+	server := newTestAPI(t)
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
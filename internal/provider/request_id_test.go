@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+)
+
+// Every request carries the User-Agent of the provider and its own request
+// ID, which the errors of the client quote.
+func Test_RequestHeaders_RequestIDInErrors(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+	ua := userAgent("1.2.3", "1.14.0", "ci/nightly")
+	client, err := cloudriftapi.NewCustom(server.URL, "test", cloudriftapi.ProtoUpcoming, "", cloudriftapi.WithUserAgent(ua))
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+
+	server.InjectFault(cloudrifttest.Fault{Path: "/api/v1/instances/list", Status: http.StatusInternalServerError, Body: `{"error":"boom"}`, Times: 1})
+	_, err = client.GetInstance("1")
+	if err == nil {
+		t.Fatal("GetInstance should fail on a 500")
+	}
+
+	listedID := server.LastRequestHeader("/api/v1/instances/list").Get(cloudriftapi.RequestIDHeader)
+	var reqErr *cloudriftapi.RequestError
+	if !errors.As(err, &reqErr) {
+		t.Fatalf("expected a RequestError, got %T: %v", err, err)
+	}
+	if reqErr.RequestID == "" || reqErr.RequestID != listedID {
+		t.Errorf("expected the request ID %q sent to the server, got %q", listedID, reqErr.RequestID)
+	}
+	if !strings.Contains(err.Error(), "X-Request-ID: "+listedID) {
+		t.Errorf("expected the request ID in the error message, got %q", err.Error())
+	}
+
+	// NotFound stays detectable through the annotation.
+	server.InjectFault(cloudrifttest.Fault{Path: "/api/v1/instances/list", Status: http.StatusNotFound, Times: 1})
+	if _, err := client.GetInstance("1"); !errors.Is(err, cloudriftapi.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	for _, path := range []string{"/api/v1/auth/me", "/api/v1/capabilities/list", "/api/v1/recipes/list", "/api/v1/instances/list"} {
+		header := server.LastRequestHeader(path)
+		if got := header.Get("User-Agent"); got != "terraform-provider-cloudrift/1.2.3 terraform/1.14.0 ci/nightly" {
+			t.Errorf("unexpected User-Agent header of %s: %q", path, got)
+		}
+		if header.Get(cloudriftapi.RequestIDHeader) == "" {
+			t.Errorf("the request to %s was sent without a request ID", path)
+		}
+	}
+}
+
+func Test_UserAgent(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		version, terraformVersion, extra string
+		want                             string
+	}{
+		{"1.2.3", "1.14.0", "", "terraform-provider-cloudrift/1.2.3 terraform/1.14.0"},
+		{"dev", "", "", "terraform-provider-cloudrift/dev"},
+		{"1.2.3", "1.14.0", "  acme-ci  ", "terraform-provider-cloudrift/1.2.3 terraform/1.14.0 acme-ci"},
+	}
+	for _, c := range cases {
+		if got := userAgent(c.version, c.terraformVersion, c.extra); got != c.want {
+			t.Errorf("userAgent(%q, %q, %q) = %q, want %q", c.version, c.terraformVersion, c.extra, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ datasource.DataSource              = &savedEnvironmentsDataSource{}
+	_ datasource.DataSourceWithConfigure = &savedEnvironmentsDataSource{}
+)
+
+type savedEnvironmentModel struct {
+	ID                  types.String `tfsdk:"id"`
+	Name                types.String `tfsdk:"name"`
+	NodeID              types.String `tfsdk:"node_id"`
+	NodeHostname        types.String `tfsdk:"node_hostname"`
+	InstanceTypeName    types.String `tfsdk:"instance_type_name"`
+	OriginalVariantName types.String `tfsdk:"original_variant_name"`
+	OSLabel             types.String `tfsdk:"os_label"`
+	DiskSizeBytes       types.Int64  `tfsdk:"disk_size_bytes"`
+	LastUsedAt          types.String `tfsdk:"last_used_at"`
+	ExpiresAt           types.String `tfsdk:"expires_at"`
+}
+
+type savedEnvironmentsDataModel struct {
+	Name              types.String            `tfsdk:"name"`
+	SavedEnvironments []savedEnvironmentModel `tfsdk:"saved_environments"`
+}
+
+type savedEnvironmentsDataSource struct {
+	client *cloudriftapi.HttpClient
+}
+
+func NewSavedEnvironmentsDataSource() datasource.DataSource {
+	return new(savedEnvironmentsDataSource)
+}
+
+func (d *savedEnvironmentsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_saved_environments"
+}
+
+func (d *savedEnvironmentsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Read the saved environments of the CloudRift account, or of the team if `team_id` is configured on the provider. " +
+			"A saved environment is the disk of a terminated Virtual Machine kept on its node until the node erases it, " +
+			"a new Virtual Machine boots from it with `reuse_environment_id`.",
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				MarkdownDescription: "Only return the environments of the Virtual Machines with this name",
+				Optional:            true,
+			},
+			"saved_environments": schema.ListNestedAttribute{
+				MarkdownDescription: "Saved environments",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"id": schema.StringAttribute{
+							MarkdownDescription: "ID of the environment, pass it as `reuse_environment_id` of a Virtual Machine",
+							Computed:            true,
+						},
+						"name": schema.StringAttribute{
+							MarkdownDescription: "Name of the terminated Virtual Machine",
+							Computed:            true,
+						},
+						"node_id": schema.StringAttribute{
+							MarkdownDescription: "ID of the node the disk lives on, a Virtual Machine reusing the environment runs on this node",
+							Computed:            true,
+						},
+						"node_hostname": schema.StringAttribute{
+							MarkdownDescription: "Hostname of the node, null if the node never reported one",
+							Computed:            true,
+						},
+						"instance_type_name": schema.StringAttribute{
+							MarkdownDescription: "Instance type the environment is bound to, e.g. `rtx49`, null for off-catalog nodes",
+							Computed:            true,
+						},
+						"original_variant_name": schema.StringAttribute{
+							MarkdownDescription: "Instance type variant the terminated Virtual Machine was rented as, null once it is removed from the catalog",
+							Computed:            true,
+						},
+						"os_label": schema.StringAttribute{
+							MarkdownDescription: "Operating system of the disk, e.g. `Ubuntu 24.04 Server`",
+							Computed:            true,
+						},
+						"disk_size_bytes": schema.Int64Attribute{
+							MarkdownDescription: "Virtual size of the disk in bytes",
+							Computed:            true,
+						},
+						"last_used_at": schema.StringAttribute{
+							MarkdownDescription: "Time the Virtual Machine was terminated",
+							Computed:            true,
+						},
+						"expires_at": schema.StringAttribute{
+							MarkdownDescription: "Time the node erases the disk, a Virtual Machine reusing the environment afterwards boots a fresh OS",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *savedEnvironmentsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*cloudriftapi.HttpClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected DataSource Configure Type",
+			fmt.Sprintf("Expected *cloudriftapi.HttpClient, got: %T. Please report this issue to the provider developers.",
+				req.ProviderData,
+			),
+		)
+		return
+	}
+
+	d.client = client.WithContext(ctx)
+}
+
+func (d *savedEnvironmentsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var model savedEnvironmentsDataModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	envs, err := d.client.ListSavedEnvironments()
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error reading CloudRift Saved Environments",
+			"Could not list the saved environments of "+describeTeam(d.client.TeamID)+": "+err.Error(),
+		)
+		return
+	}
+
+	model.SavedEnvironments = make([]savedEnvironmentModel, 0, len(envs))
+	for _, e := range envs {
+		if !model.Name.IsNull() && e.Name != model.Name.ValueString() {
+			continue
+		}
+		model.SavedEnvironments = append(model.SavedEnvironments, savedEnvironmentModel{
+			ID:                  types.StringValue(e.Id),
+			Name:                types.StringValue(e.Name),
+			NodeID:              types.StringValue(e.NodeId),
+			NodeHostname:        types.StringPointerValue(e.NodeHostname),
+			InstanceTypeName:    types.StringPointerValue(e.InstanceTypeName),
+			OriginalVariantName: types.StringPointerValue(e.OriginalVariantName),
+			OSLabel:             types.StringValue(e.OsLabel),
+			DiskSizeBytes:       types.Int64Value(e.DiskSizeBytes),
+			LastUsedAt:          types.StringValue(e.LastUsedAt),
+			ExpiresAt:           types.StringValue(e.ExpiresAt),
+		})
+	}
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
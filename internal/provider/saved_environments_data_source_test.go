@@ -0,0 +1,211 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func Test_SavedEnvironmentsDataSource(t *testing.T) {
+	t.Parallel()
+
+	typeName, variant := "rtx49", "rtx49-10c-kn.1"
+	server := newTestAPI(t, cloudrifttest.WithSavedEnvironments(cloudriftapi.SavedEnvironment{
+		Id:                  "env-1",
+		Name:                "bright-falcon",
+		NodeId:              "node-7",
+		InstanceTypeName:    &typeName,
+		OriginalVariantName: &variant,
+		OsLabel:             "Ubuntu 24.04 Server",
+		DiskSizeBytes:       100 << 30,
+		LastUsedAt:          "2025-01-01T00:00:00Z",
+		ExpiresAt:           "2025-01-08T00:00:00Z",
+	}))
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig(server.URL, "1.0") + `
+					data "cloudrift_saved_environments" "all" {}
+
+					data "cloudrift_saved_environments" "other" {
+					  name = "quiet-otter"
+					}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cloudrift_saved_environments.all", "saved_environments.#", "1"),
+					resource.TestCheckResourceAttr("data.cloudrift_saved_environments.all", "saved_environments.0.id", "env-1"),
+					resource.TestCheckResourceAttr("data.cloudrift_saved_environments.all", "saved_environments.0.node_id", "node-7"),
+					resource.TestCheckResourceAttr("data.cloudrift_saved_environments.all", "saved_environments.0.instance_type_name", "rtx49"),
+					resource.TestCheckResourceAttr("data.cloudrift_saved_environments.all", "saved_environments.0.disk_size_bytes", "107374182400"),
+					resource.TestCheckNoResourceAttr("data.cloudrift_saved_environments.all", "saved_environments.0.node_hostname"),
+					resource.TestCheckResourceAttr("data.cloudrift_saved_environments.other", "saved_environments.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+// Test_VirtualMachineResource_ReuseEnvironment verifies that a Virtual Machine
+// reusing a saved environment is rented on the node of the environment.
+func Test_VirtualMachineResource_ReuseEnvironment(t *testing.T) {
+	t.Parallel()
+
+	keyName := "anotheruser-key"
+	publicKey := "ssh-rsa AAAA anotheruser"
+	typeName, variant := "rtx49", "rtx49-10c-kn.1"
+	server := newTestAPI(t, cloudrifttest.WithSavedEnvironments(cloudriftapi.SavedEnvironment{
+		Id:                  "env-1",
+		Name:                "bright-falcon",
+		NodeId:              "node-7",
+		InstanceTypeName:    &typeName,
+		OriginalVariantName: &variant,
+		OsLabel:             "Ubuntu 24.04 Server",
+	}))
+
+	vmConfig := func(reuse, datacenter string) string {
+		return providerConfig(server.URL, "1.0") + fmt.Sprintf(`
+			resource "cloudrift_ssh_key" "primary" {
+			  name       = "%s"
+			  public_key = "%s"
+			}
+
+			resource "cloudrift_virtual_machine" "machine0" {
+			  recipe               = "ubuntu"
+			  datacenter           = %q
+			  instance_type        = "rtx49-10c-kn.1"
+			  ssh_key_id           = cloudrift_ssh_key.primary.id
+			  reuse_environment_id = %q
+			}
+		`, keyName, publicKey, datacenter, reuse)
+	}
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      vmConfig("env-2", "us-east-nc-nr-1"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`Saved environment "env-2" does not exist`),
+			},
+			{
+				Config:      vmConfig("env-1", "eu-west-1"),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile(`Saved environment "env-1" lives on node node-7 in datacenter`),
+			},
+			{
+				Config: vmConfig("env-1", "us-east-nc-nr-1"),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("cloudrift_virtual_machine.machine0", "reuse_environment_id", "env-1"),
+					func(*terraform.State) error {
+						var rented struct {
+							ReuseEnvironmentID *string                    `json:"reuse_environment_id"`
+							Selector           cloudriftapi.NodeSelector1 `json:"selector"`
+						}
+						if err := server.LastRequest("/api/v1/instances/rent", &rented); err != nil {
+							return err
+						}
+						if rented.ReuseEnvironmentID == nil || *rented.ReuseEnvironmentID != "env-1" {
+							return fmt.Errorf("expected reuse_environment_id env-1 in rent request, got %v", rented.ReuseEnvironmentID)
+						}
+						if node := rented.Selector.ByNodeId; node.NodeId != "node-7" || node.InstanceType != "rtx49-10c-kn.1" {
+							return fmt.Errorf("expected the rental to be pinned to node-7, got %+v", node)
+						}
+						return nil
+					},
+				),
+			},
+		},
+	})
+}
+
+func Test_ValidateSavedEnvironment(t *testing.T) {
+	t.Parallel()
+
+	var catalog cloudriftapi.ListInstanceTypesResponseProto
+	catalog.Data.InstanceTypes = []cloudriftapi.InstanceType{
+		{Name: "rtx49", Variants: []cloudriftapi.InstanceVariantInfo{
+			{Name: "rtx49-10c-kn.1", NodesPerDc: map[string]int32{"us-east-nc-nr-1": 2}},
+			{Name: "rtx49-20c-kn.2", NodesPerDc: map[string]int32{"us-east-nc-nr-1": 1}},
+		}},
+		{Name: "h100", Variants: []cloudriftapi.InstanceVariantInfo{{Name: "h100-80gb-1x", NodesPerDc: map[string]int32{"eu-west-1": 1, "eu-north-1": 1}}}},
+		{Name: "l40s", Variants: []cloudriftapi.InstanceVariantInfo{{Name: "l40s-1x"}}},
+	}
+	typeName, variant := "rtx49", "rtx49-10c-kn.1"
+	h100, l40s := "h100", "l40s"
+	envs := []cloudriftapi.SavedEnvironment{
+		{Id: "env-1", NodeId: "node-7", InstanceTypeName: &typeName, OriginalVariantName: &variant},
+		{Id: "env-2", NodeId: "node-8"},
+		{Id: "env-4", NodeId: "node-9", InstanceTypeName: &h100},
+		{Id: "env-5", NodeId: "node-10", InstanceTypeName: &l40s},
+	}
+
+	tests := []struct {
+		name         string
+		id           string
+		instanceType types.String
+		datacenter   types.String
+		wantError    string
+	}{
+		{name: "same variant", id: "env-1", instanceType: types.StringValue("rtx49-10c-kn.1"), datacenter: types.StringValue("us-east-nc-nr-1")},
+		{name: "other variant of the type", id: "env-1", instanceType: types.StringValue("rtx49-20c-kn.2"), datacenter: types.StringValue("us-east-nc-nr-1")},
+		{name: "unknown instance type", id: "env-1", instanceType: types.StringUnknown(), datacenter: types.StringValue("us-east-nc-nr-1")},
+		{name: "unknown datacenter", id: "env-1", instanceType: types.StringValue("rtx49-10c-kn.1"), datacenter: types.StringUnknown()},
+		{name: "off-catalog node", id: "env-2", instanceType: types.StringValue("h100-80gb-1x"), datacenter: types.StringValue("eu-west-1")},
+		{name: "one of the datacenters", id: "env-4", instanceType: types.StringValue("h100-80gb-1x"), datacenter: types.StringValue("eu-north-1")},
+		{name: "type without nodes", id: "env-5", instanceType: types.StringValue("l40s-1x"), datacenter: types.StringValue("eu-west-1")},
+		{
+			name:         "other datacenter",
+			id:           "env-1",
+			instanceType: types.StringValue("rtx49-10c-kn.1"),
+			datacenter:   types.StringValue("us-east-nc-nr-2"),
+			wantError:    `Saved environment "env-1" lives on node node-7 in datacenter "us-east-nc-nr-1", it cannot be reused in datacenter "us-east-nc-nr-2". Did you mean "us-east-nc-nr-1"?`,
+		},
+		{
+			name:         "none of the datacenters",
+			id:           "env-4",
+			instanceType: types.StringValue("h100-80gb-1x"),
+			datacenter:   types.StringValue("us-east-nc-nr-1"),
+			wantError:    `Saved environment "env-4" lives on node node-9 in one of the datacenters eu-north-1, eu-west-1, it cannot be reused in datacenter "us-east-nc-nr-1".`,
+		},
+		{
+			name:         "other instance type",
+			id:           "env-1",
+			instanceType: types.StringValue("h100-80gb-1x"),
+			datacenter:   types.StringValue("us-east-nc-nr-1"),
+			wantError:    `Saved environment "env-1" lives on node node-7 of instance type "rtx49", it cannot be reused by instance type "h100-80gb-1x". Did you mean "rtx49-10c-kn.1"?`,
+		},
+		{
+			name:         "missing",
+			id:           "env-3",
+			instanceType: types.StringValue("rtx49-10c-kn.1"),
+			datacenter:   types.StringValue("us-east-nc-nr-1"),
+			wantError: `Saved environment "env-3" does not exist, its disk may have been erased or reused already. ` +
+				`Use the cloudrift_saved_environments data source to list the available environments.`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			diags := validateSavedEnvironment(&catalog, envs, types.StringValue(tt.id), tt.instanceType, tt.datacenter)
+			if tt.wantError == "" {
+				if diags.HasError() {
+					t.Fatalf("unexpected error diagnostics: %v", diags)
+				}
+				return
+			}
+			if len(diags.Errors()) != 1 || diags.Errors()[0].Detail() != tt.wantError {
+				t.Errorf("got %v, want a single error %q", diags, tt.wantError)
+			}
+		})
+	}
+}
@@ -3,13 +3,15 @@ package provider
 import (
 	"testing"
 
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
 )
 
 func Test_SSHKeyDataSource(t *testing.T) {
 	t.Parallel()
 
-	server := defaultHttpTestServer(nil)
+	server := newTestAPI(t, cloudrifttest.WithSSHKeys(cloudriftapi.SshKey{Id: "1", Name: "test-key", PublicKey: "ssh-rsa AAAA testuser"}))
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
 		Steps: []resource.TestStep{
@@ -0,0 +1,93 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/list"
+	"github.com/hashicorp/terraform-plugin-framework/list/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ list.ListResource              = &sshKeyListResource{}
+	_ list.ListResourceWithConfigure = &sshKeyListResource{}
+)
+
+type sshKeyListResource struct {
+	client *cloudriftapi.HttpClient
+}
+
+func NewSSHKeyListResource() list.ListResource {
+	return &sshKeyListResource{}
+}
+
+func (r *sshKeyListResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_ssh_key"
+}
+
+func (r *sshKeyListResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*cloudriftapi.HttpClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected List Resource Configure Type",
+			fmt.Sprintf("Expected *cloudriftapi.HttpClient, got: %T. Please report this issue to the provider developers.",
+				req.ProviderData,
+			),
+		)
+		return
+	}
+
+	r.client = client.WithContext(ctx)
+}
+
+func (r *sshKeyListResource) ListResourceConfigSchema(_ context.Context, _ list.ListResourceSchemaRequest, resp *list.ListResourceSchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Lists the SSH Keys of the account.",
+	}
+}
+
+func (r *sshKeyListResource) List(ctx context.Context, req list.ListRequest, stream *list.ListResultsStream) {
+	keys, err := r.client.ListSSHKeys()
+	if err != nil {
+		var diags diag.Diagnostics
+		diags.AddError(
+			"Error listing CloudRift SSH Keys",
+			"Could not list CloudRift SSH Keys: "+err.Error(),
+		)
+		stream.Results = list.ListResultsStreamDiagnostics(diags)
+		return
+	}
+
+	stream.Results = func(push func(list.ListResult) bool) {
+		for i, k := range keys {
+			if req.Limit > 0 && int64(i) >= req.Limit {
+				return
+			}
+
+			result := req.NewListResult(ctx)
+			result.DisplayName = k.Name
+
+			result.Diagnostics.Append(result.Identity.Set(ctx, sshKeyIdentityModel{ID: types.StringValue(k.Id)})...)
+			if req.IncludeResource && !result.Diagnostics.HasError() {
+				result.Diagnostics.Append(result.Resource.Set(ctx, sshKeyModel{
+					ID:          types.StringValue(k.Id),
+					Name:        types.StringValue(k.Name),
+					PublicKey:   types.StringValue(k.PublicKey),
+					Fingerprint: fingerprintValue(k.PublicKey),
+				})...)
+			}
+
+			if !push(result) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+func Test_SSHKeyListResource(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t, cloudrifttest.WithSSHKeys(
+		cloudriftapi.SshKey{Id: "1", Name: "test-key", PublicKey: "ssh-rsa AAAA testuser"},
+		cloudriftapi.SshKey{Id: "11111", Name: "anotheruser-key", PublicKey: "ssh-rsa AAAA anotheruser"},
+	))
+
+	client, err := cloudriftapi.NewCustom(server.URL, "test", "", "")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+
+	results := runListResource(t, NewSSHKeyListResource(), &sshKeyResource{}, client, map[string]tftypes.Value{})
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	result := results[1]
+	if result.Diagnostics.HasError() {
+		t.Fatalf("unexpected diagnostics: %v", result.Diagnostics)
+	}
+	if result.DisplayName != "anotheruser-key" {
+		t.Errorf("display name %q, want %q", result.DisplayName, "anotheruser-key")
+	}
+
+	var identity sshKeyIdentityModel
+	if diags := result.Identity.Get(context.Background(), &identity); diags.HasError() {
+		t.Fatalf("identity: %v", diags)
+	}
+	var key sshKeyModel
+	if diags := result.Resource.Get(context.Background(), &key); diags.HasError() {
+		t.Fatalf("resource: %v", diags)
+	}
+	if identity.ID.ValueString() != "11111" || key.ID.ValueString() != "11111" {
+		t.Errorf("expected identity and resource id %q, got %q and %q", "11111", identity.ID.ValueString(), key.ID.ValueString())
+	}
+	if key.PublicKey.ValueString() != "ssh-rsa AAAA anotheruser" {
+		t.Errorf("public key %q, want %q", key.PublicKey.ValueString(), "ssh-rsa AAAA anotheruser")
+	}
+}
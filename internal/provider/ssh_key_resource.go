@@ -4,26 +4,44 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 
 	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/identityschema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 var (
-	_ resource.Resource                = &sshKeyResource{}
-	_ resource.ResourceWithConfigure   = &sshKeyResource{}
-	_ resource.ResourceWithImportState = &sshKeyResource{}
+	_ resource.Resource                   = &sshKeyResource{}
+	_ resource.ResourceWithConfigure      = &sshKeyResource{}
+	_ resource.ResourceWithImportState    = &sshKeyResource{}
+	_ resource.ResourceWithIdentity       = &sshKeyResource{}
+	_ resource.ResourceWithValidateConfig = &sshKeyResource{}
 )
 
 type sshKeyModel struct {
-	ID        types.String `tfsdk:"id"`
-	Name      types.String `tfsdk:"name"`
-	PublicKey types.String `tfsdk:"public_key"`
+	ID          types.String `tfsdk:"id"`
+	Name        types.String `tfsdk:"name"`
+	PublicKey   types.String `tfsdk:"public_key"`
+	Fingerprint types.String `tfsdk:"fingerprint"`
+
+	PublicKeyWO        types.String `tfsdk:"public_key_wo"`
+	PublicKeyWOVersion types.Int64  `tfsdk:"public_key_wo_version"`
+
+	Generate       types.Bool   `tfsdk:"generate"`
+	PrivateKeyFile types.String `tfsdk:"private_key_file"`
+}
+
+type sshKeyIdentityModel struct {
+	ID types.String `tfsdk:"id"`
 }
 
 type sshKeyResource struct {
@@ -38,23 +56,34 @@ func (r *sshKeyResource) Metadata(_ context.Context, req resource.MetadataReques
 	resp.TypeName = req.ProviderTypeName + "_ssh_key"
 }
 
-func (r *sshKeyResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+func (r *sshKeyResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
 	if req.ProviderData == nil {
 		return
 	}
 
-	client, ok := req.ProviderData.(*cloudriftapi.HttpClient)
+	data, ok := req.ProviderData.(*resourceData)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *cloudriftapi.HttpClient, got: %T. Please report this issue to the provider developers.",
+			fmt.Sprintf("Expected *resourceData, got: %T. Please report this issue to the provider developers.",
 				req.ProviderData,
 			),
 		)
 		return
 	}
 
-	r.client = client
+	r.client = data.client.WithContext(ctx)
+}
+
+func (r *sshKeyResource) IdentitySchema(_ context.Context, _ resource.IdentitySchemaRequest, resp *resource.IdentitySchemaResponse) {
+	resp.IdentitySchema = identityschema.Schema{
+		Attributes: map[string]identityschema.Attribute{
+			"id": identityschema.StringAttribute{
+				Description:       "ID of the SSH Key.",
+				RequiredForImport: true,
+			},
+		},
+	}
 }
 
 func (r *sshKeyResource) Schema(_ context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
@@ -73,13 +102,115 @@ func (r *sshKeyResource) Schema(_ context.Context, req resource.SchemaRequest, r
 				Required:            true,
 			},
 			"public_key": schema.StringAttribute{
-				MarkdownDescription: "The SSH public key",
-				Required:            true,
+				MarkdownDescription: "The SSH public key, required unless `public_key_wo` or `generate` is set",
+				Optional:            true,
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"public_key_wo": schema.StringAttribute{
+				MarkdownDescription: "The SSH public key, write-only so that it can be set from an ephemeral value, " +
+					"e.g. the public key of the `cloudrift_ssh_keypair` ephemeral resource. Requires Terraform 1.11 or later. " +
+					"The uploaded key is still stored in `public_key`. Changes are not detected, bump `public_key_wo_version` to upload a new key.",
+				Optional:  true,
+				WriteOnly: true,
+			},
+			"public_key_wo_version": schema.Int64Attribute{
+				MarkdownDescription: "Version of `public_key_wo`, changing it replaces the SSH Key with the current value of `public_key_wo`.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
+			},
+			"fingerprint": schema.StringAttribute{
+				MarkdownDescription: "SHA256 fingerprint of the public key, as printed by `ssh-keygen -l`",
+				Computed:            true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"generate": schema.BoolAttribute{
+				MarkdownDescription: "Generate an ed25519 key pair instead of uploading `public_key`. Only the public key is stored in the state, " +
+					"the private key is written to `private_key_file`, which is required. " +
+					"Use the `cloudrift_ssh_keypair` ephemeral resource to get hold of the private key without a file, uploading its public key with `public_key_wo`.",
+				Optional: true,
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"private_key_file": schema.StringAttribute{
+				MarkdownDescription: "Path the generated private key is written to, with permissions 0600. Required if and only if `generate` is set. " +
+					"The file must not exist yet, an existing key is never overwritten. The file is not removed when the SSH Key is destroyed.",
+				Optional: true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
 			},
 		},
 	}
 }
 
+func (r *sshKeyResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var config sshKeyModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() || config.Generate.IsUnknown() {
+		return
+	}
+
+	generate := config.Generate.ValueBool()
+	switch {
+	case generate && !config.PublicKey.IsNull():
+		resp.Diagnostics.AddAttributeError(
+			path.Root("public_key"),
+			"Invalid SSH Key Configuration",
+			"Attribute \"public_key\" cannot be set when \"generate\" is true.",
+		)
+	case generate && !config.PublicKeyWO.IsNull():
+		resp.Diagnostics.AddAttributeError(
+			path.Root("public_key_wo"),
+			"Invalid SSH Key Configuration",
+			"Attribute \"public_key_wo\" cannot be set when \"generate\" is true.",
+		)
+	case !config.PublicKey.IsNull() && !config.PublicKeyWO.IsNull():
+		resp.Diagnostics.AddAttributeError(
+			path.Root("public_key_wo"),
+			"Invalid SSH Key Configuration",
+			"Attributes \"public_key\" and \"public_key_wo\" cannot be set together.",
+		)
+	case !generate && config.PublicKey.IsNull() && config.PublicKeyWO.IsNull():
+		resp.Diagnostics.AddAttributeError(
+			path.Root("public_key"),
+			"Invalid SSH Key Configuration",
+			"Attribute \"public_key\" or \"public_key_wo\" is required unless \"generate\" is true.",
+		)
+	}
+
+	if !config.PublicKeyWOVersion.IsNull() && config.PublicKeyWO.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("public_key_wo_version"),
+			"Invalid SSH Key Configuration",
+			"Attribute \"public_key_wo_version\" requires \"public_key_wo\" to be set.",
+		)
+	}
+
+	switch {
+	case !generate && !config.PrivateKeyFile.IsNull():
+		resp.Diagnostics.AddAttributeError(
+			path.Root("private_key_file"),
+			"Invalid SSH Key Configuration",
+			"Attribute \"private_key_file\" requires \"generate\" to be true.",
+		)
+	case generate && config.PrivateKeyFile.IsNull():
+		// The private key would be lost, leaving a key nobody can log in with.
+		resp.Diagnostics.AddAttributeError(
+			path.Root("private_key_file"),
+			"Invalid SSH Key Configuration",
+			"Attribute \"private_key_file\" is required when \"generate\" is true, the generated private key is not kept otherwise.",
+		)
+	}
+}
+
 func (r *sshKeyResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var plan sshKeyModel
 	diags := req.Plan.Get(ctx, &plan)
@@ -96,6 +227,42 @@ func (r *sshKeyResource) Create(ctx context.Context, req resource.CreateRequest,
 		return
 	}
 
+	// The generated private key is written once the public key is uploaded.
+	var privateKey string
+	file := plan.PrivateKeyFile.ValueString()
+	if plan.Generate.ValueBool() {
+		// Never overwrite an existing key, e.g. ~/.ssh/id_ed25519.
+		if _, err := os.Lstat(file); !errors.Is(err, fs.ErrNotExist) {
+			detail := "Could not write the generated private key, " + file + " already exists. Remove it or choose another \"private_key_file\"."
+			if err != nil {
+				detail = "Could not check the private key file " + file + ": " + err.Error()
+			}
+			resp.Diagnostics.AddAttributeError(path.Root("private_key_file"), "Error creating SSH key", detail)
+			return
+		}
+
+		pair, err := generateSSHKeyPair(sshKeyAlgorithmED25519, 0)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error creating SSH key",
+				"Could not generate SSH key pair: "+err.Error(),
+			)
+			return
+		}
+		privateKey = pair.privateKey
+		plan.PublicKey = types.StringValue(pair.publicKey)
+	}
+
+	// Write-only values are only found in the configuration.
+	var publicKeyWO types.String
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("public_key_wo"), &publicKeyWO)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if !publicKeyWO.IsNull() {
+		plan.PublicKey = publicKeyWO
+	}
+
 	if plan.PublicKey.ValueString() == "" {
 		resp.Diagnostics.AddError(
 			"Error creating SSH key",
@@ -113,15 +280,31 @@ func (r *sshKeyResource) Create(ctx context.Context, req resource.CreateRequest,
 		return
 	}
 
+	if privateKey != "" {
+		if err := writePrivateKeyFile(file, privateKey); err != nil {
+			// Nobody could log in with the uploaded key, remove it.
+			detail := "Could not write the generated private key to " + file + ": " + err.Error()
+			if delErr := r.client.DeleteSSHKey(key.Data.PublicKey.Id); delErr != nil && !errors.Is(delErr, cloudriftapi.ErrNotFound) {
+				detail += fmt.Sprintf(" The uploaded SSH key %s could not be deleted either, delete it manually: %v", key.Data.PublicKey.Id, delErr)
+			}
+			resp.Diagnostics.AddError("Error creating SSH key", detail)
+			return
+		}
+	}
+
 	plan.ID = types.StringValue(key.Data.PublicKey.Id)
 	plan.Name = types.StringValue(key.Data.PublicKey.Name)
 	plan.PublicKey = types.StringValue(key.Data.PublicKey.PublicKey)
+	plan.Fingerprint = fingerprintValue(key.Data.PublicKey.PublicKey)
 
 	diags = resp.State.Set(ctx, &plan)
 	resp.Diagnostics.Append(diags...)
 	if resp.Diagnostics.HasError() {
 		return
 	}
+
+	diags = resp.Identity.Set(ctx, sshKeyIdentityModel{ID: plan.ID})
+	resp.Diagnostics.Append(diags...)
 }
 
 func (r *sshKeyResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
@@ -158,6 +341,7 @@ func (r *sshKeyResource) Read(ctx context.Context, req resource.ReadRequest, res
 	state.ID = types.StringValue(matched.Id)
 	state.Name = types.StringValue(matched.Name)
 	state.PublicKey = types.StringValue(matched.PublicKey)
+	state.Fingerprint = fingerprintValue(matched.PublicKey)
 
 	// update tf state.
 	diags = resp.State.Set(ctx, &state)
@@ -165,6 +349,9 @@ func (r *sshKeyResource) Read(ctx context.Context, req resource.ReadRequest, res
 	if resp.Diagnostics.HasError() {
 		return
 	}
+
+	diags = resp.Identity.Set(ctx, sshKeyIdentityModel{ID: state.ID})
+	resp.Diagnostics.Append(diags...)
 }
 
 func (r *sshKeyResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
@@ -196,5 +383,34 @@ func (r *sshKeyResource) Delete(ctx context.Context, req resource.DeleteRequest,
 }
 
 func (r *sshKeyResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	resource.ImportStatePassthroughWithIdentity(ctx, path.Root("id"), path.Root("id"), req, resp)
+}
+
+// fingerprintValue returns the fingerprint of the public key, null for keys
+// that cannot be parsed.
+func fingerprintValue(publicKey string) types.String {
+	fingerprint, ok := sshKeyFingerprint(publicKey)
+	if !ok {
+		return types.StringNull()
+	}
+	return types.StringValue(fingerprint)
+}
+
+// writePrivateKeyFile writes the private key to a new file readable only by
+// the user, it fails if the file exists.
+func writePrivateKeyFile(file, privateKey string) error {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(privateKey); err != nil {
+		_ = f.Close()
+		_ = os.Remove(file)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(file)
+		return err
+	}
+	return nil
 }
@@ -1,27 +1,40 @@
 package provider
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
 )
 
 func Test_SSHKeyResource_TeamApiKeyError(t *testing.T) {
 	t.Parallel()
 
-	server := defaultHttpTestServer(map[string]func(w http.ResponseWriter, req *http.Request){
-		"/api/v1/ssh-keys/add": func(w http.ResponseWriter, _ *http.Request) {
-			w.WriteHeader(http.StatusUnauthorized)
-			_, _ = w.Write([]byte("User cannot be authenticated from the request"))
-		},
-		"/api/v1/ssh-keys/list": func(w http.ResponseWriter, _ *http.Request) {
-			w.Header().Set("Content-Type", "json")
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write([]byte(`{"data":{"keys":[]}}`))
-		},
+	server := newTestAPI(t)
+	server.InjectFault(cloudrifttest.Fault{
+		Path:   "/api/v1/ssh-keys/add",
+		Status: http.StatusUnauthorized,
+		Body:   "User cannot be authenticated from the request",
 	})
 
 	resource.Test(t, resource.TestCase{
@@ -44,11 +57,7 @@ func Test_SSHKeyResource(t *testing.T) {
 	keyName := "anotheruser-key"
 	publicKey := "ssh-rsa AAAA anotheruser"
 
-	server := defaultHttpTestServer(map[string]func(w http.ResponseWriter, req *http.Request){
-		"/api/v1/ssh-keys/add":   sshKeyAddHandler(),
-		"/api/v1/ssh-keys/list":  sshKeyListHandlerWithKey(keyName, publicKey),
-		"/api/v1/ssh-keys/11111": sshKeyDeleteHandler(),
-	})
+	server := newTestAPI(t)
 
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
@@ -60,7 +69,13 @@ func Test_SSHKeyResource(t *testing.T) {
 					}`, keyName, publicKey),
 				Check: resource.ComposeAggregateTestCheckFunc(
 					resource.TestCheckResourceAttr("cloudrift_ssh_key.default", "name", keyName),
-					resource.TestCheckResourceAttr("cloudrift_ssh_key.default", "id", "11111"),
+					func(s *terraform.State) error {
+						keys := server.SSHKeys()
+						if len(keys) != 1 {
+							return fmt.Errorf("expected 1 SSH key, got %v", keys)
+						}
+						return resource.TestCheckResourceAttr("cloudrift_ssh_key.default", "id", keys[0].Id)(s)
+					},
 					resource.TestCheckResourceAttr("cloudrift_ssh_key.default", "public_key", publicKey),
 				),
 			},
@@ -72,3 +87,221 @@ func Test_SSHKeyResource(t *testing.T) {
 		},
 	})
 }
+
+func Test_SSHKeyResource_Identity(t *testing.T) {
+	t.Parallel()
+
+	keyName := "anotheruser-key"
+	publicKey := "ssh-rsa AAAA anotheruser"
+
+	server := newTestAPI(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_12_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig(server.URL, "1.0") + fmt.Sprintf(`resource "cloudrift_ssh_key" "default" {
+						name = "%s"
+						public_key = "%s"
+					}`, keyName, publicKey),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectIdentity("cloudrift_ssh_key.default", map[string]knownvalue.Check{
+						"id": knownvalue.NotNull(),
+					}),
+				},
+			},
+			{
+				ResourceName:    "cloudrift_ssh_key.default",
+				ImportState:     true,
+				ImportStateKind: resource.ImportBlockWithResourceIdentity,
+			},
+		},
+	})
+}
+
+func Test_SSHKeyResource_Generate(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+	privateKeyFile := filepath.Join(t.TempDir(), "id_ed25519")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig(server.URL, "1.0") + fmt.Sprintf(`resource "cloudrift_ssh_key" "generated" {
+						name             = "ci-key"
+						generate         = true
+						private_key_file = %q
+					}`, privateKeyFile),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestMatchResourceAttr("cloudrift_ssh_key.generated", "public_key", regexp.MustCompile(`^ssh-ed25519 `)),
+					resource.TestMatchResourceAttr("cloudrift_ssh_key.generated", "fingerprint", regexp.MustCompile(`^SHA256:`)),
+					func(s *terraform.State) error {
+						info, err := os.Stat(privateKeyFile)
+						if err != nil {
+							return fmt.Errorf("expected the private key to be written: %w", err)
+						}
+						if info.Mode().Perm() != 0o600 {
+							return fmt.Errorf("expected private key permissions 0600, got %v", info.Mode().Perm())
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config: providerConfig(server.URL, "1.0") + `resource "cloudrift_ssh_key" "generated" {
+						name       = "ci-key"
+						generate   = true
+						public_key = "ssh-ed25519 AAAA"
+					}`,
+				ExpectError: regexp.MustCompile(`cannot be set when "generate" is true`),
+			},
+			{
+				Config: providerConfig(server.URL, "1.0") + `resource "cloudrift_ssh_key" "generated" {
+						name     = "ci-key"
+						generate = true
+					}`,
+				ExpectError: regexp.MustCompile(`"private_key_file" is required when "generate" is true`),
+			},
+		},
+	})
+}
+
+func Test_SSHKeyResource_PublicKeyWriteOnly(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_11_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig(server.URL, "1.0") + `
+					ephemeral "cloudrift_ssh_keypair" "ci" {}
+
+					resource "cloudrift_ssh_key" "ci" {
+					  name                  = "ci-key"
+					  public_key_wo         = ephemeral.cloudrift_ssh_keypair.ci.public_key
+					  public_key_wo_version = 1
+					}
+				`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("cloudrift_ssh_key.ci", tfjsonpath.New("public_key"), knownvalue.StringRegexp(regexp.MustCompile(`^ssh-ed25519 `))),
+					statecheck.ExpectKnownValue("cloudrift_ssh_key.ci", tfjsonpath.New("public_key_wo"), knownvalue.Null()),
+				},
+			},
+			{
+				Config: providerConfig(server.URL, "1.0") + `resource "cloudrift_ssh_key" "ci" {
+						name          = "ci-key"
+						public_key    = "ssh-ed25519 AAAA"
+						public_key_wo = "ssh-ed25519 AAAA"
+					}`,
+				ExpectError: regexp.MustCompile(`"public_key" and "public_key_wo" cannot be set together`),
+			},
+		},
+	})
+}
+
+// Test_SSHKeyResource_GeneratePrivateKeyFile verifies that a generated private
+// key never overwrites an existing file, and is only written once its public
+// key is uploaded.
+func Test_SSHKeyResource_GeneratePrivateKeyFile(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	server := newTestAPI(t)
+	client, err := cloudriftapi.NewCustom(server.URL, "test", "1.0", "")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	r := &sshKeyResource{client: client}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	var identitySchemaResp fwresource.IdentitySchemaResponse
+	r.IdentitySchema(ctx, fwresource.IdentitySchemaRequest{}, &identitySchemaResp)
+	schemaType := schemaResp.Schema.Type().TerraformType(ctx)
+
+	create := func(t *testing.T, file string) diag.Diagnostics {
+		plan := tfsdk.Plan{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaType, nil)}
+		if diags := plan.Set(ctx, sshKeyModel{
+			ID:                 types.StringUnknown(),
+			Name:               types.StringValue("ci-key"),
+			PublicKey:          types.StringUnknown(),
+			Fingerprint:        types.StringUnknown(),
+			PublicKeyWO:        types.StringNull(),
+			PublicKeyWOVersion: types.Int64Null(),
+			Generate:           types.BoolValue(true),
+			PrivateKeyFile:     types.StringValue(file),
+		}); diags.HasError() {
+			t.Fatalf("plan: %v", diags)
+		}
+		resp := fwresource.CreateResponse{
+			State: tfsdk.State{Schema: schemaResp.Schema, Raw: tftypes.NewValue(schemaType, nil)},
+			Identity: &tfsdk.ResourceIdentity{
+				Schema: identitySchemaResp.IdentitySchema,
+				Raw:    tftypes.NewValue(identitySchemaResp.IdentitySchema.Type().TerraformType(ctx), nil),
+			},
+		}
+		r.Create(ctx, fwresource.CreateRequest{Plan: plan, Config: tfsdk.Config{Schema: plan.Schema, Raw: plan.Raw}}, &resp)
+		return resp.Diagnostics
+	}
+
+	t.Run("existing file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "id_ed25519")
+		if err := os.WriteFile(file, []byte("existing key"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if diags := create(t, file); !diags.HasError() || !strings.Contains(fmt.Sprint(diags), "already exists") {
+			t.Errorf("expected an existing file to fail the create, got %v", diags)
+		}
+		if b, _ := os.ReadFile(file); string(b) != "existing key" {
+			t.Errorf("the existing file was overwritten with %q", b)
+		}
+		if keys := server.SSHKeys(); len(keys) != 0 {
+			t.Errorf("expected no key to be uploaded, got %v", keys)
+		}
+	})
+
+	t.Run("failed upload", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "id_ed25519")
+		server.InjectFault(cloudrifttest.Fault{Path: "/api/v1/ssh-keys/add", Status: http.StatusBadRequest, Body: "invalid key", Times: 1})
+		if diags := create(t, file); !diags.HasError() {
+			t.Error("expected the failed upload to fail the create")
+		}
+		if _, err := os.Stat(file); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("expected no private key file after a failed upload, got %v", err)
+		}
+	})
+
+	t.Run("failed write", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "missing", "id_ed25519")
+		if diags := create(t, file); !diags.HasError() {
+			t.Error("expected the failed write to fail the create")
+		}
+		if keys := server.SSHKeys(); len(keys) != 0 {
+			t.Errorf("expected the uploaded key to be deleted, got %v", keys)
+		}
+	})
+
+	t.Run("written", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "id_ed25519")
+		if diags := create(t, file); diags.HasError() {
+			t.Fatalf("create: %v", diags)
+		}
+		info, err := os.Stat(file)
+		if err != nil || info.Mode().Perm() != 0o600 {
+			t.Fatalf("expected the private key to be written with permissions 0600, got %v, %v", info, err)
+		}
+		if keys := server.SSHKeys(); len(keys) != 1 || !strings.HasPrefix(keys[0].PublicKey, "ssh-ed25519 ") {
+			t.Errorf("expected the generated public key to be uploaded, got %v", keys)
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package provider
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	sshKeyAlgorithmED25519 = "ed25519"
+	sshKeyAlgorithmRSA     = "rsa"
+
+	defaultRSABits = 4096
+	minRSABits     = 2048
+	maxRSABits     = 8192
+)
+
+var (
+	_ ephemeral.EphemeralResource                   = &sshKeyPairEphemeralResource{}
+	_ ephemeral.EphemeralResourceWithValidateConfig = &sshKeyPairEphemeralResource{}
+)
+
+type sshKeyPairModel struct {
+	Algorithm         types.String `tfsdk:"algorithm"`
+	RSABits           types.Int64  `tfsdk:"rsa_bits"`
+	PublicKey         types.String `tfsdk:"public_key"`
+	PrivateKeyOpenSSH types.String `tfsdk:"private_key_openssh"`
+	Fingerprint       types.String `tfsdk:"fingerprint"`
+}
+
+type sshKeyPairEphemeralResource struct{}
+
+func NewSSHKeyPairEphemeralResource() ephemeral.EphemeralResource {
+	return &sshKeyPairEphemeralResource{}
+}
+
+func (r *sshKeyPairEphemeralResource) Metadata(_ context.Context, req ephemeral.MetadataRequest, resp *ephemeral.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_ssh_keypair"
+}
+
+func (r *sshKeyPairEphemeralResource) Schema(_ context.Context, _ ephemeral.SchemaRequest, resp *ephemeral.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Generates an SSH key pair in the provider. The private key is never stored in the plan or state. " +
+			"Ephemeral values only go to write-only attributes, such as `public_key_wo` of `cloudrift_ssh_key`, " +
+			"to provider configurations and to provisioner connections, e.g. to log into an instance created in the same run.",
+		Attributes: map[string]schema.Attribute{
+			"algorithm": schema.StringAttribute{
+				MarkdownDescription: "Key algorithm, `ed25519` (default) or `rsa`.",
+				Optional:            true,
+			},
+			"rsa_bits": schema.Int64Attribute{
+				MarkdownDescription: fmt.Sprintf("Size of an `rsa` key in bits, between %d and %d. Defaults to %d.", minRSABits, maxRSABits, defaultRSABits),
+				Optional:            true,
+			},
+			"public_key": schema.StringAttribute{
+				MarkdownDescription: "Public key in the OpenSSH authorized_keys format.",
+				Computed:            true,
+			},
+			"private_key_openssh": schema.StringAttribute{
+				MarkdownDescription: "Private key in the OpenSSH PEM format.",
+				Computed:            true,
+				Sensitive:           true,
+			},
+			"fingerprint": schema.StringAttribute{
+				MarkdownDescription: "SHA256 fingerprint of the public key, as printed by `ssh-keygen -l`.",
+				Computed:            true,
+			},
+		},
+	}
+}
+
+func (r *sshKeyPairEphemeralResource) ValidateConfig(ctx context.Context, req ephemeral.ValidateConfigRequest, resp *ephemeral.ValidateConfigResponse) {
+	var config sshKeyPairModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	algorithm := config.Algorithm.ValueString()
+	if !config.Algorithm.IsNull() && !config.Algorithm.IsUnknown() && algorithm != sshKeyAlgorithmED25519 && algorithm != sshKeyAlgorithmRSA {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("algorithm"),
+			"Invalid SSH Key Pair Configuration",
+			fmt.Sprintf("Unsupported algorithm %q, expected %q or %q.", algorithm, sshKeyAlgorithmED25519, sshKeyAlgorithmRSA),
+		)
+	}
+
+	if config.RSABits.IsNull() || config.RSABits.IsUnknown() {
+		return
+	}
+	// An unknown algorithm is checked in Open, once it is known.
+	if !config.Algorithm.IsUnknown() && algorithm != sshKeyAlgorithmRSA {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("rsa_bits"),
+			"Invalid SSH Key Pair Configuration",
+			"Attribute \"rsa_bits\" can only be set with algorithm \"rsa\".",
+		)
+	}
+	if bits := config.RSABits.ValueInt64(); bits < minRSABits || bits > maxRSABits {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("rsa_bits"),
+			"Invalid SSH Key Pair Configuration",
+			fmt.Sprintf("Attribute \"rsa_bits\" must be between %d and %d, got %d.", minRSABits, maxRSABits, bits),
+		)
+	}
+}
+
+func (r *sshKeyPairEphemeralResource) Open(ctx context.Context, req ephemeral.OpenRequest, resp *ephemeral.OpenResponse) {
+	var data sshKeyPairModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Algorithm.IsNull() {
+		data.Algorithm = types.StringValue(sshKeyAlgorithmED25519)
+	}
+	if data.Algorithm.ValueString() == sshKeyAlgorithmRSA && data.RSABits.IsNull() {
+		data.RSABits = types.Int64Value(defaultRSABits)
+	}
+	// ValidateConfig cannot check rsa_bits against an unknown algorithm.
+	if data.Algorithm.ValueString() != sshKeyAlgorithmRSA && !data.RSABits.IsNull() {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("rsa_bits"),
+			"Invalid SSH Key Pair Configuration",
+			"Attribute \"rsa_bits\" can only be set with algorithm \"rsa\".",
+		)
+		return
+	}
+
+	pair, err := generateSSHKeyPair(data.Algorithm.ValueString(), int(data.RSABits.ValueInt64()))
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error generating SSH key pair",
+			"Could not generate SSH key pair: "+err.Error(),
+		)
+		return
+	}
+
+	data.PublicKey = types.StringValue(pair.publicKey)
+	data.PrivateKeyOpenSSH = types.StringValue(pair.privateKey)
+	data.Fingerprint = types.StringValue(pair.fingerprint)
+
+	resp.Diagnostics.Append(resp.Result.Set(ctx, &data)...)
+}
+
+type sshKeyPair struct {
+	publicKey   string
+	privateKey  string
+	fingerprint string
+}
+
+// generateSSHKeyPair generates a key pair with the given algorithm, bits
+// only apply to RSA keys.
+func generateSSHKeyPair(algorithm string, bits int) (sshKeyPair, error) {
+	var private crypto.PrivateKey
+	switch algorithm {
+	case sshKeyAlgorithmED25519:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return sshKeyPair{}, err
+		}
+		private = key
+	case sshKeyAlgorithmRSA:
+		key, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return sshKeyPair{}, err
+		}
+		private = key
+	default:
+		return sshKeyPair{}, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+
+	block, err := ssh.MarshalPrivateKey(private, "")
+	if err != nil {
+		return sshKeyPair{}, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	signer, err := ssh.NewSignerFromKey(private)
+	if err != nil {
+		return sshKeyPair{}, fmt.Errorf("failed to derive public key: %w", err)
+	}
+
+	return sshKeyPair{
+		publicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))),
+		privateKey:  string(pem.EncodeToMemory(block)),
+		fingerprint: ssh.FingerprintSHA256(signer.PublicKey()),
+	}, nil
+}
+
+// sshKeyFingerprint returns the SHA256 fingerprint of a public key in the
+// authorized_keys format, false if it cannot be parsed.
+func sshKeyFingerprint(publicKey string) (string, bool) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return "", false
+	}
+	return ssh.FingerprintSHA256(key), true
+}
@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/ephemeral"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+	"github.com/hashicorp/terraform-plugin-testing/echoprovider"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
+	"github.com/hashicorp/terraform-plugin-testing/tfjsonpath"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
+	"golang.org/x/crypto/ssh"
+)
+
+func Test_SSHKeyPairEphemeralResource(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: map[string]func() (tfprotov6.ProviderServer, error){
+			"cloudrift": providerserver.NewProtocol6WithError(New("test")()),
+			"echo":      echoprovider.NewProviderServer(),
+		},
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_10_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig(server.URL, "1.0") + `
+					ephemeral "cloudrift_ssh_keypair" "ci" {
+					  algorithm = "rsa"
+					  rsa_bits  = 2048
+					}
+
+					provider "echo" {
+					  data = ephemeral.cloudrift_ssh_keypair.ci
+					}
+
+					resource "echo" "keypair" {}
+				`,
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectKnownValue("echo.keypair", tfjsonpath.New("data").AtMapKey("public_key"), knownvalue.StringRegexp(regexp.MustCompile(`^ssh-rsa `))),
+					statecheck.ExpectKnownValue("echo.keypair", tfjsonpath.New("data").AtMapKey("private_key_openssh"), knownvalue.StringRegexp(regexp.MustCompile(`BEGIN OPENSSH PRIVATE KEY`))),
+					statecheck.ExpectKnownValue("echo.keypair", tfjsonpath.New("data").AtMapKey("fingerprint"), knownvalue.StringRegexp(regexp.MustCompile(`^SHA256:`))),
+				},
+			},
+			{
+				Config: providerConfig(server.URL, "1.0") + `
+					ephemeral "cloudrift_ssh_keypair" "ci" {
+					  algorithm = "ed25519"
+					  rsa_bits  = 2048
+					}
+				`,
+				ExpectError: regexp.MustCompile(`can only be set with algorithm "rsa"`),
+			},
+		},
+	})
+}
+
+func Test_GenerateSSHKeyPair(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		algorithm string
+		bits      int
+		keyType   string
+	}{
+		{algorithm: sshKeyAlgorithmED25519, keyType: ssh.KeyAlgoED25519},
+		{algorithm: sshKeyAlgorithmRSA, bits: 2048, keyType: ssh.KeyAlgoRSA},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			t.Parallel()
+
+			pair, err := generateSSHKeyPair(tt.algorithm, tt.bits)
+			if err != nil {
+				t.Fatalf("generateSSHKeyPair: %v", err)
+			}
+
+			signer, err := ssh.ParsePrivateKey([]byte(pair.privateKey))
+			if err != nil {
+				t.Fatalf("private key is not a valid OpenSSH key: %v", err)
+			}
+			if got := signer.PublicKey().Type(); got != tt.keyType {
+				t.Errorf("key type %q, want %q", got, tt.keyType)
+			}
+
+			fingerprint, ok := sshKeyFingerprint(pair.publicKey)
+			if !ok {
+				t.Fatalf("public key %q cannot be parsed", pair.publicKey)
+			}
+			if fingerprint != pair.fingerprint || fingerprint != ssh.FingerprintSHA256(signer.PublicKey()) {
+				t.Errorf("fingerprint %q does not match the generated key pair", pair.fingerprint)
+			}
+		})
+	}
+
+	if _, err := generateSSHKeyPair("dsa", 0); err == nil {
+		t.Error("expected an error for an unsupported algorithm")
+	}
+}
+
+// Test_SSHKeyPairEphemeralResource_ValidateConfig verifies that rsa_bits is
+// only rejected for an algorithm known not to be rsa.
+func Test_SSHKeyPairEphemeralResource_ValidateConfig(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	r := &sshKeyPairEphemeralResource{}
+	var schemaResp ephemeral.SchemaResponse
+	r.Schema(ctx, ephemeral.SchemaRequest{}, &schemaResp)
+	schemaType := schemaResp.Schema.Type().TerraformType(ctx)
+
+	tests := []struct {
+		name      string
+		algorithm tftypes.Value
+		wantError bool
+	}{
+		{name: "rsa", algorithm: tftypes.NewValue(tftypes.String, sshKeyAlgorithmRSA)},
+		{name: "unknown", algorithm: tftypes.NewValue(tftypes.String, tftypes.UnknownValue)},
+		{name: "default", algorithm: tftypes.NewValue(tftypes.String, nil), wantError: true},
+		{name: "ed25519", algorithm: tftypes.NewValue(tftypes.String, sshKeyAlgorithmED25519), wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tfsdk.Config{
+				Schema: schemaResp.Schema,
+				Raw: tftypes.NewValue(schemaType, map[string]tftypes.Value{
+					"algorithm":           tt.algorithm,
+					"rsa_bits":            tftypes.NewValue(tftypes.Number, 3072),
+					"public_key":          tftypes.NewValue(tftypes.String, nil),
+					"private_key_openssh": tftypes.NewValue(tftypes.String, nil),
+					"fingerprint":         tftypes.NewValue(tftypes.String, nil),
+				}),
+			}
+			var resp ephemeral.ValidateConfigResponse
+			r.ValidateConfig(ctx, ephemeral.ValidateConfigRequest{Config: config}, &resp)
+			if resp.Diagnostics.HasError() != tt.wantError {
+				t.Errorf("expected an error %v, got %v", tt.wantError, resp.Diagnostics)
+			}
+		})
+	}
+}
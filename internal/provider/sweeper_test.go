@@ -0,0 +1,198 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// testAccNamePrefixes prefix the names of the resources created by the
+// acceptance tests, the sweepers only remove resources named this way.
+var testAccNamePrefixes = []string{"tf-acc-", "ci-test-", "provider-test-"}
+
+// TestMain runs the sweepers of the resources leaked by crashed acceptance
+// runs with
+//
+//	CLOUDRIFT_TOKEN=... CLOUDRIFT_TEAM_ID=... go test ./internal/provider -v -sweep=all
+//
+// CloudRift has no regions, the value of -sweep is ignored. Without -sweep
+// the tests run as usual.
+func TestMain(m *testing.M) {
+	resource.TestMain(m)
+}
+
+// The provider manages no volumes nor networks, instances and SSH keys are
+// the only resources billed or left behind.
+func init() {
+	resource.AddTestSweepers("cloudrift_virtual_machine", &resource.Sweeper{
+		Name: "cloudrift_virtual_machine",
+		F: func(string) error {
+			client, err := sweeperClient()
+			if err != nil {
+				return err
+			}
+			return sweepInstances(client)
+		},
+	})
+	// The keys go once the instances authorizing them are terminated.
+	resource.AddTestSweepers("cloudrift_ssh_key", &resource.Sweeper{
+		Name:         "cloudrift_ssh_key",
+		Dependencies: []string{"cloudrift_virtual_machine"},
+		F: func(string) error {
+			client, err := sweeperClient()
+			if err != nil {
+				return err
+			}
+			return sweepSSHKeys(client)
+		},
+	})
+}
+
+// sweeperClient returns a client configured as the acceptance tests are, by
+// the CLOUDRIFT_* environment variables.
+func sweeperClient() (*cloudriftapi.HttpClient, error) {
+	token := os.Getenv("CLOUDRIFT_TOKEN")
+	if token == "" {
+		return nil, errors.New("CLOUDRIFT_TOKEN must be set for sweeping")
+	}
+	return cloudriftapi.NewCustom(
+		os.Getenv("CLOUDRIFT_BASE_URL"),
+		token,
+		os.Getenv("CLOUDRIFT_PROTO_VERSION"),
+		os.Getenv("CLOUDRIFT_TEAM_ID"),
+	)
+}
+
+func isTestAccName(name string) bool {
+	return slices.ContainsFunc(testAccNamePrefixes, func(prefix string) bool {
+		return strings.HasPrefix(name, prefix)
+	})
+}
+
+// sweepInstances terminates the instances named by the acceptance tests,
+// within the scope of the team of the client. Clusters are swept along, as
+// their instances are.
+func sweepInstances(client *cloudriftapi.HttpClient) error {
+	listed, err := client.ListInstancesByStatus([]cloudriftapi.InstanceStatus{
+		cloudriftapi.InstanceStatusInitializing,
+		cloudriftapi.InstanceStatusActive,
+		cloudriftapi.InstanceStatusFailed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list instances: %w", err)
+	}
+
+	var ids []string
+	for _, i := range listed.Data.Instances {
+		if i.InstanceName != nil && isTestAccName(*i.InstanceName) {
+			log.Printf("[INFO] Terminating instance %s (%s)", i.Id, *i.InstanceName)
+			ids = append(ids, i.Id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := client.TerminateInstances(ids); err != nil {
+		return fmt.Errorf("failed to terminate instances %s: %w", strings.Join(ids, ", "), err)
+	}
+	return nil
+}
+
+// sweepSSHKeys deletes the SSH keys named by the acceptance tests.
+func sweepSSHKeys(client *cloudriftapi.HttpClient) error {
+	keys, err := client.ListSSHKeys()
+	if err != nil {
+		return fmt.Errorf("failed to list SSH keys: %w", err)
+	}
+
+	var errs []error
+	for _, k := range keys {
+		if !isTestAccName(k.Name) {
+			continue
+		}
+		log.Printf("[INFO] Deleting SSH key %s (%s)", k.Id, k.Name)
+		if err := client.DeleteSSHKey(k.Id); err != nil && !errors.Is(err, cloudriftapi.ErrNotFound) {
+			errs = append(errs, fmt.Errorf("failed to delete SSH key %s: %w", k.Id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func Test_Sweepers(t *testing.T) {
+	t.Parallel()
+
+	server := cloudrifttest.NewServer()
+	defer server.Close()
+
+	team, err := cloudriftapi.NewCustom(server.URL, "test", "", "team-1")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	personal, err := cloudriftapi.NewCustom(server.URL, "test", "", "")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+
+	rent := func(client *cloudriftapi.HttpClient, name string) string {
+		resp, err := client.RentPublicInstanceVM(cloudriftapi.RentVMOptions{
+			Recipe:       cloudrifttest.DefaultRecipes[0].Name,
+			Datacenter:   cloudrifttest.DefaultDatacenter,
+			InstanceType: "rtx49-10c-kn.1",
+			Name:         name,
+			PublicKeys:   []string{"ssh-ed25519 AAAA test"},
+		})
+		if err != nil {
+			t.Fatalf("RentPublicInstanceVM(%q): %v", name, err)
+		}
+		return resp.Data.InstanceIds[0]
+	}
+	leaked := rent(team, "provider-test-master-1")
+	rent(team, "production")
+	// Outside the scope of the team, left alone despite the name.
+	rent(personal, "tf-acc-personal")
+
+	for _, name := range []string{"ci-test-1", "tf-acc-2", "production"} {
+		if _, err := team.AddSSHKey(name, "ssh-ed25519 AAAA "+name); err != nil {
+			t.Fatalf("AddSSHKey(%q): %v", name, err)
+		}
+	}
+
+	if err := sweepInstances(team); err != nil {
+		t.Fatalf("sweepInstances: %v", err)
+	}
+	if err := sweepSSHKeys(team); err != nil {
+		t.Fatalf("sweepSSHKeys: %v", err)
+	}
+
+	instances := server.Instances()
+	if len(instances) != 3 {
+		t.Fatalf("expected 3 instances, got %+v", instances)
+	}
+	for _, inst := range instances {
+		wantTerminated := inst.Id == leaked
+		if terminated := inst.Status == cloudriftapi.InstanceStatusInactive; terminated != wantTerminated {
+			t.Errorf("expected instance %q terminated: %v, got status %s", inst.Id, wantTerminated, inst.Status)
+		}
+	}
+
+	keys := server.SSHKeys()
+	if len(keys) != 1 || keys[0].Name != "production" {
+		t.Errorf("expected only the production key to be kept, got %+v", keys)
+	}
+
+	// Sweeping again finds nothing left to remove.
+	if err := sweepInstances(team); err != nil {
+		t.Errorf("sweepInstances: %v", err)
+	}
+	if err := sweepSSHKeys(team); err != nil {
+		t.Errorf("sweepSSHKeys: %v", err)
+	}
+}
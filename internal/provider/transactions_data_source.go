@@ -0,0 +1,277 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ datasource.DataSource                   = &transactionsDataSource{}
+	_ datasource.DataSourceWithConfigure      = &transactionsDataSource{}
+	_ datasource.DataSourceWithValidateConfig = &transactionsDataSource{}
+)
+
+type transactionModel struct {
+	CreatedAt    types.String  `tfsdk:"created_at"`
+	Amount       types.Float64 `tfsdk:"amount"`
+	Kind         types.String  `tfsdk:"kind"`
+	ResourceID   types.String  `tfsdk:"resource_id"`
+	ResourceName types.String  `tfsdk:"resource_name"`
+	Description  types.String  `tfsdk:"description"`
+}
+
+type transactionsDataModel struct {
+	From         types.String       `tfsdk:"from"`
+	To           types.String       `tfsdk:"to"`
+	TotalSpent   types.Float64      `tfsdk:"total_spent"`
+	Transactions []transactionModel `tfsdk:"transactions"`
+}
+
+type transactionsDataSource struct {
+	client *cloudriftapi.HttpClient
+}
+
+func NewTransactionsDataSource() datasource.DataSource {
+	return new(transactionsDataSource)
+}
+
+func (d *transactionsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_transactions"
+}
+
+func (d *transactionsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Transactions of the CloudRift account, or of the team if `team_id` is configured on the provider, " +
+			"over a date range. Amounts are in USD.",
+		Attributes: map[string]schema.Attribute{
+			"from": schema.StringAttribute{
+				MarkdownDescription: "Start of the range as an RFC 3339 timestamp, inclusive. If omitted, the CloudRift API default applies.",
+				Optional:            true,
+			},
+			"to": schema.StringAttribute{
+				MarkdownDescription: "End of the range as an RFC 3339 timestamp, inclusive. If omitted, the CloudRift API default applies.",
+				Optional:            true,
+			},
+			"total_spent": schema.Float64Attribute{
+				MarkdownDescription: "Sum of the debits of the range in USD.",
+				Computed:            true,
+			},
+			"transactions": schema.ListNestedAttribute{
+				MarkdownDescription: "Transactions of the range.",
+				Computed:            true,
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"created_at": schema.StringAttribute{
+							MarkdownDescription: "Date and time of the transaction.",
+							Computed:            true,
+						},
+						"amount": schema.Float64Attribute{
+							MarkdownDescription: "Amount in USD, positive for a debit and negative for a credit.",
+							Computed:            true,
+						},
+						"kind": schema.StringAttribute{
+							MarkdownDescription: "Kind of the transaction, e.g. `Usage` for the usage of an instance, `Stripe` for a payment or `PromoCode`.",
+							Computed:            true,
+						},
+						"resource_id": schema.StringAttribute{
+							MarkdownDescription: "ID of the resource the transaction is for, e.g. the instance of a `Usage` transaction.",
+							Computed:            true,
+						},
+						"resource_name": schema.StringAttribute{
+							MarkdownDescription: "Name of the resource the transaction is for.",
+							Computed:            true,
+						},
+						"description": schema.StringAttribute{
+							MarkdownDescription: "Details of the transaction, e.g. the promo code, the external service or the kind of payment.",
+							Computed:            true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *transactionsDataSource) Configure(ctx context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*cloudriftapi.HttpClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected DataSource Configure Type",
+			fmt.Sprintf("Expected *cloudriftapi.HttpClient, got: %T. Please report this issue to the provider developers.",
+				req.ProviderData,
+			),
+		)
+		return
+	}
+
+	d.client = client.WithContext(ctx)
+}
+
+func (d *transactionsDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var config transactionsDataModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	from, fromOK := parseTimestamp(config.From)
+	to, toOK := parseTimestamp(config.To)
+	if !fromOK {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("from"),
+			"Invalid Transactions Configuration",
+			fmt.Sprintf("Attribute \"from\" must be an RFC 3339 timestamp, e.g. %q, got: %s", "2025-01-01T00:00:00Z", config.From.ValueString()),
+		)
+	}
+	if !toOK {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("to"),
+			"Invalid Transactions Configuration",
+			fmt.Sprintf("Attribute \"to\" must be an RFC 3339 timestamp, e.g. %q, got: %s", "2025-01-31T23:59:59Z", config.To.ValueString()),
+		)
+	}
+	if fromOK && toOK && !from.IsZero() && !to.IsZero() && to.Before(from) {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("to"),
+			"Invalid Transactions Configuration",
+			"Attribute \"to\" must not be before \"from\".",
+		)
+	}
+}
+
+func (d *transactionsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var model transactionsDataModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Both are validated already.
+	from, _ := parseTimestamp(model.From)
+	to, _ := parseTimestamp(model.To)
+
+	transactions, err := d.client.ListTransactions(from, to)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error reading CloudRift Transactions",
+			"Could not list the transactions of "+describeTeam(d.client.TeamID)+": "+err.Error(),
+		)
+		return
+	}
+
+	var spent int64
+	model.Transactions = make([]transactionModel, 0, len(transactions))
+	for _, t := range transactions {
+		if t.Amount > 0 {
+			spent += t.Amount
+		}
+
+		m := transactionModel{
+			CreatedAt: types.StringValue(t.CreatedAt),
+			// Transaction amounts are reported in cents.
+			Amount: types.Float64Value(centsToUSD(t.Amount)),
+		}
+		details, err := describeTransaction(t.Info)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error reading CloudRift Transactions",
+				"Could not decode the transaction of "+t.CreatedAt+": "+err.Error(),
+			)
+			return
+		}
+		m.Kind = types.StringValue(details.kind)
+		m.ResourceID = types.StringPointerValue(details.ResourceID)
+		m.ResourceName = types.StringPointerValue(details.ResourceName)
+		m.Description = types.StringPointerValue(details.description())
+
+		model.Transactions = append(model.Transactions, m)
+	}
+	model.TotalSpent = types.Float64Value(centsToUSD(spent))
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &model)...)
+}
+
+// parseTimestamp parses an optional RFC 3339 timestamp, the zero time for a
+// null or unknown value.
+func parseTimestamp(v types.String) (time.Time, bool) {
+	if v.IsNull() || v.IsUnknown() {
+		return time.Time{}, true
+	}
+	t, err := time.Parse(time.RFC3339, v.ValueString())
+	return t, err == nil
+}
+
+// transactionDetails are the fields shared by the variants of a transaction.
+type transactionDetails struct {
+	kind string
+	// stripeKind is the kind of a Stripe transaction, e.g. Payment.
+	stripeKind string
+
+	ResourceID   *string `json:"resource_id"`
+	ResourceName *string `json:"resource_name"`
+	Service      *string `json:"service"`
+	PromoCode    *string `json:"promo_code"`
+	Description  *string `json:"description"`
+}
+
+func (t transactionDetails) description() *string {
+	switch {
+	case t.Description != nil:
+		return t.Description
+	case t.Service != nil:
+		return t.Service
+	case t.PromoCode != nil:
+		return t.PromoCode
+	case t.stripeKind != "":
+		return &t.stripeKind
+	}
+	return nil
+}
+
+// describeTransaction returns the kind of the transaction, the single key of
+// its info union, and the details of the variant.
+func describeTransaction(info cloudriftapi.TransactionInfo) (transactionDetails, error) {
+	var details transactionDetails
+
+	raw, err := info.MarshalJSON()
+	if err != nil {
+		return details, err
+	}
+	var variants map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &variants); err != nil {
+		return details, err
+	}
+	if len(variants) != 1 {
+		return details, fmt.Errorf("expected a single kind of transaction, got %d", len(variants))
+	}
+
+	for kind, variant := range variants {
+		details.kind = kind
+		if kind == "Stripe" {
+			var stripe map[string]json.RawMessage
+			if err := json.Unmarshal(variant, &stripe); err != nil {
+				return details, err
+			}
+			for stripeKind := range stripe {
+				details.stripeKind = stripeKind
+			}
+			continue
+		}
+		if err := json.Unmarshal(variant, &details); err != nil {
+			return details, err
+		}
+	}
+	return details, nil
+}
@@ -0,0 +1,135 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+func Test_TransactionsDataSource(t *testing.T) {
+	t.Parallel()
+
+	var transactions []cloudriftapi.Transaction
+	if err := json.Unmarshal([]byte(`
+		[
+			{
+				"amount": 1250,
+				"created_at": "2025-01-02T10:00:00Z",
+				"info": {"Usage": {"carryover": [], "resource_id": "1", "resource_name": "bright-falcon-042", "resource_type": "Compute", "rounding": [], "usage_metadata": null}}
+			},
+			{
+				"amount": -5000,
+				"created_at": "2025-01-01T09:00:00Z",
+				"info": {"Stripe": {"Payment": {"payment_intent_id": "pi_1", "stripe_payment_intent_id": null}}}
+			},
+			{
+				"amount": 250,
+				"created_at": "2025-01-03T08:00:00Z",
+				"info": {"ServiceUsage": {"carryover": [], "rounding": [], "service": "storage", "usage_metadata": null}}
+			},
+			{
+				"amount": 700,
+				"created_at": "2025-02-01T08:00:00Z",
+				"info": {"ServiceUsage": {"carryover": [], "rounding": [], "service": "storage", "usage_metadata": null}}
+			}
+		]
+	`), &transactions); err != nil {
+		t.Fatal(err)
+	}
+	server := newTestAPI(t, cloudrifttest.WithTeams(testTeam("team-123", 0)), cloudrifttest.WithTransactions(transactions...))
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfigWithTeamID(server.URL, "1.0", "team-123") + `
+					data "cloudrift_transactions" "january" {
+					  from = "2025-01-01T00:00:00Z"
+					  to   = "2025-01-31T23:59:59Z"
+					}
+				`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.cloudrift_transactions.january", "total_spent", "15"),
+					resource.TestCheckResourceAttr("data.cloudrift_transactions.january", "transactions.#", "3"),
+					resource.TestCheckResourceAttr("data.cloudrift_transactions.january", "transactions.0.kind", "Usage"),
+					resource.TestCheckResourceAttr("data.cloudrift_transactions.january", "transactions.0.amount", "12.5"),
+					resource.TestCheckResourceAttr("data.cloudrift_transactions.january", "transactions.0.resource_id", "1"),
+					resource.TestCheckResourceAttr("data.cloudrift_transactions.january", "transactions.0.resource_name", "bright-falcon-042"),
+					resource.TestCheckResourceAttr("data.cloudrift_transactions.january", "transactions.1.kind", "Stripe"),
+					resource.TestCheckResourceAttr("data.cloudrift_transactions.january", "transactions.1.description", "Payment"),
+					resource.TestCheckResourceAttr("data.cloudrift_transactions.january", "transactions.2.description", "storage"),
+					func(*terraform.State) error {
+						var requested struct {
+							Selector any    `json:"selector"`
+							From     string `json:"from"`
+							To       string `json:"to"`
+						}
+						if err := server.LastRequest("/api/v1/account/transactions/list", &requested); err != nil {
+							return err
+						}
+						if requested.From != "2025-01-01T00:00:00Z" || requested.To != "2025-01-31T23:59:59Z" {
+							return fmt.Errorf("unexpected range in request: %+v", requested)
+						}
+						if selector, ok := requested.Selector.(map[string]any); !ok || selector["ByTeam"] != "team-123" {
+							return fmt.Errorf("expected the team selector in request, got %v", requested.Selector)
+						}
+						return nil
+					},
+				),
+			},
+			{
+				Config: providerConfig(server.URL, "1.0") + `
+					data "cloudrift_transactions" "invalid" {
+					  from = "2025-01-31"
+					}
+				`,
+				ExpectError: regexp.MustCompile(`must be an RFC 3339 timestamp`),
+			},
+		},
+	})
+}
+
+func Test_DescribeTransaction(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		info        string
+		kind        string
+		resourceID  string
+		description string
+	}{
+		{info: `{"Usage": {"resource_id": "1", "resource_name": "vm", "resource_type": "Compute"}}`, kind: "Usage", resourceID: "1"},
+		{info: `{"Stripe": {"DisputeOpen": {"stripe_dispute_amount": 1, "stripe_dispute_fee": 1, "stripe_dispute_id": "d"}}}`, kind: "Stripe", description: "DisputeOpen"},
+		{info: `{"PromoCode": {"promo_code": "WELCOME"}}`, kind: "PromoCode", description: "WELCOME"},
+		{info: `{"External": {"description": "wire transfer"}}`, kind: "External", description: "wire transfer"},
+	}
+
+	for _, tt := range tests {
+		var info cloudriftapi.TransactionInfo
+		if err := info.UnmarshalJSON([]byte(tt.info)); err != nil {
+			t.Fatalf("UnmarshalJSON(%s): %v", tt.info, err)
+		}
+		details, err := describeTransaction(info)
+		if err != nil {
+			t.Fatalf("describeTransaction(%s): %v", tt.info, err)
+		}
+
+		var resourceID, description string
+		if details.ResourceID != nil {
+			resourceID = *details.ResourceID
+		}
+		if d := details.description(); d != nil {
+			description = *d
+		}
+		if details.kind != tt.kind || resourceID != tt.resourceID || description != tt.description {
+			t.Errorf("describeTransaction(%s) = %q, %q, %q, want %q, %q, %q",
+				tt.info, details.kind, resourceID, description, tt.kind, tt.resourceID, tt.description)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package provider
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// maxSuggestions caps the number of "did you mean" candidates in diagnostics.
+const maxSuggestions = 3
+
+// validatePlacement checks that the instance type exists in the catalog and
+// is offered in the datacenter. Unknown or null values are not checked.
+func validatePlacement(catalog *cloudriftapi.ListInstanceTypesResponseProto, instanceType, datacenter types.String) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	variants := make(map[string]map[string]int32)
+	datacenters := make(map[string]struct{})
+	for _, t := range catalog.Data.InstanceTypes {
+		for _, v := range t.Variants {
+			variants[v.Name] = v.NodesPerDc
+			for dc := range v.NodesPerDc {
+				datacenters[dc] = struct{}{}
+			}
+		}
+	}
+
+	checkType := !instanceType.IsNull() && !instanceType.IsUnknown()
+	checkDatacenter := !datacenter.IsNull() && !datacenter.IsUnknown()
+
+	if checkType {
+		nodesPerDc, ok := variants[instanceType.ValueString()]
+		if !ok {
+			diags.AddAttributeError(
+				path.Root("instance_type"),
+				"Invalid Virtual Machine Configuration",
+				fmt.Sprintf("Instance type %q does not exist.", instanceType.ValueString())+
+					didYouMean(instanceType.ValueString(), slices.Collect(maps.Keys(variants))),
+			)
+			return diags
+		}
+
+		if checkDatacenter {
+			if _, ok := nodesPerDc[datacenter.ValueString()]; !ok {
+				offered := slices.Sorted(maps.Keys(nodesPerDc))
+				diags.AddAttributeError(
+					path.Root("datacenter"),
+					"Invalid Virtual Machine Configuration",
+					fmt.Sprintf("Instance type %q is not offered in datacenter %q, it is offered in: %s.",
+						instanceType.ValueString(), datacenter.ValueString(), strings.Join(offered, ", "))+
+						didYouMean(datacenter.ValueString(), offered),
+				)
+			}
+		}
+		return diags
+	}
+
+	if checkDatacenter {
+		if _, ok := datacenters[datacenter.ValueString()]; !ok {
+			diags.AddAttributeError(
+				path.Root("datacenter"),
+				"Invalid Virtual Machine Configuration",
+				fmt.Sprintf("Datacenter %q does not offer any instance type.", datacenter.ValueString())+
+					didYouMean(datacenter.ValueString(), slices.Collect(maps.Keys(datacenters))),
+			)
+		}
+	}
+
+	return diags
+}
+
+// didYouMean returns a sentence suggesting the candidates closest to value,
+// empty if none is close enough to be a likely typo.
+func didYouMean(value string, candidates []string) string {
+	type scored struct {
+		name     string
+		distance int
+	}
+
+	// Allow roughly one typo every three characters, and at least two.
+	threshold := max(2, len(value)/3)
+	lower := strings.ToLower(value)
+
+	var matches []scored
+	for _, c := range candidates {
+		d := levenshtein(lower, strings.ToLower(c))
+		if d <= threshold || (lower != "" && strings.Contains(strings.ToLower(c), lower)) {
+			matches = append(matches, scored{name: c, distance: d})
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+
+	slices.SortFunc(matches, func(a, b scored) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.name, b.name)
+	})
+
+	quoted := make([]string, 0, maxSuggestions)
+	for _, s := range matches[:min(len(matches), maxSuggestions)] {
+		quoted = append(quoted, fmt.Sprintf("%q", s.name))
+	}
+	if len(quoted) == 1 {
+		return " Did you mean " + quoted[0] + "?"
+	}
+	return " Did you mean one of " + strings.Join(quoted, ", ") + "?"
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// findInstanceVariant returns the instance type variant with the name, nil if
+// the catalog has none.
+func findInstanceVariant(catalog *cloudriftapi.ListInstanceTypesResponseProto, name string) *cloudriftapi.InstanceVariantInfo {
+	for i := range catalog.Data.InstanceTypes {
+		for j := range catalog.Data.InstanceTypes[i].Variants {
+			if v := &catalog.Data.InstanceTypes[i].Variants[j]; v.Name == name {
+				return v
+			}
+		}
+	}
+	return nil
+}
+
+// instanceTypeCost returns the hourly cost of the instance type, null if it
+// cannot be looked up.
+func instanceTypeCost(client *cloudriftapi.HttpClient, instanceType string) types.Float64 {
+	catalog, err := client.ListInstanceTypes()
+	if err != nil {
+		return types.Float64Null()
+	}
+	if v := findInstanceVariant(catalog, instanceType); v != nil {
+		return types.Float64Value(v.CostPerHour)
+	}
+	return types.Float64Null()
+}
+
+// validateSavedEnvironment checks that the saved environment exists and that
+// the instance type and the datacenter match the node the environment lives
+// on. A nil catalog or an unknown instance type or datacenter skips the
+// corresponding check.
+func validateSavedEnvironment(catalog *cloudriftapi.ListInstanceTypesResponseProto, envs []cloudriftapi.SavedEnvironment, id, instanceType, datacenter types.String) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	i := slices.IndexFunc(envs, func(e cloudriftapi.SavedEnvironment) bool { return e.Id == id.ValueString() })
+	if i < 0 {
+		diags.AddAttributeError(
+			path.Root("reuse_environment_id"),
+			"Invalid Virtual Machine Configuration",
+			fmt.Sprintf("Saved environment %q does not exist, its disk may have been erased or reused already. "+
+				"Use the cloudrift_saved_environments data source to list the available environments.", id.ValueString()),
+		)
+		return diags
+	}
+	env := envs[i]
+
+	if catalog == nil || env.InstanceTypeName == nil {
+		return diags
+	}
+
+	diags.Append(validateSavedEnvironmentDatacenter(catalog, env, datacenter)...)
+
+	if instanceType.IsNull() || instanceType.IsUnknown() {
+		return diags
+	}
+
+	for _, t := range catalog.Data.InstanceTypes {
+		if !slices.ContainsFunc(t.Variants, func(v cloudriftapi.InstanceVariantInfo) bool { return v.Name == instanceType.ValueString() }) {
+			continue
+		}
+		if t.Name != *env.InstanceTypeName {
+			detail := fmt.Sprintf("Saved environment %q lives on node %s of instance type %q, it cannot be reused by instance type %q.",
+				id.ValueString(), env.NodeId, *env.InstanceTypeName, instanceType.ValueString())
+			if env.OriginalVariantName != nil {
+				detail += fmt.Sprintf(" Did you mean %q?", *env.OriginalVariantName)
+			}
+			diags.AddAttributeError(path.Root("instance_type"), "Invalid Virtual Machine Configuration", detail)
+		}
+		break
+	}
+	return diags
+}
+
+// validateSavedEnvironmentDatacenter checks that the datacenter is one the
+// node of the saved environment may be in. The CloudRift API does not report
+// the datacenter of a node, it is resolved from the datacenters offering the
+// instance type of the environment. An unknown datacenter, or a node of an
+// instance type the catalog no longer offers, skips the check.
+func validateSavedEnvironmentDatacenter(catalog *cloudriftapi.ListInstanceTypesResponseProto, env cloudriftapi.SavedEnvironment, datacenter types.String) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if env.InstanceTypeName == nil || datacenter.IsNull() || datacenter.IsUnknown() {
+		return diags
+	}
+
+	datacenters := make(map[string]struct{})
+	for _, t := range catalog.Data.InstanceTypes {
+		if t.Name != *env.InstanceTypeName {
+			continue
+		}
+		for _, v := range t.Variants {
+			for dc := range v.NodesPerDc {
+				datacenters[dc] = struct{}{}
+			}
+		}
+	}
+	if len(datacenters) == 0 {
+		return diags
+	}
+	if _, ok := datacenters[datacenter.ValueString()]; ok {
+		return diags
+	}
+
+	offered := slices.Sorted(maps.Keys(datacenters))
+	where := fmt.Sprintf("datacenter %q", offered[0])
+	if len(offered) > 1 {
+		where = "one of the datacenters " + strings.Join(offered, ", ")
+	}
+	diags.AddAttributeError(
+		path.Root("datacenter"),
+		"Invalid Virtual Machine Configuration",
+		fmt.Sprintf("Saved environment %q lives on node %s in %s, it cannot be reused in datacenter %q.",
+			env.Id, env.NodeId, where, datacenter.ValueString())+
+			didYouMean(datacenter.ValueString(), offered),
+	)
+	return diags
+}
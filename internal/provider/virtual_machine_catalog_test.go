@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// Test_VirtualMachineResource_CatalogValidation verifies that typos in the
+// instance type, datacenter and recipe fail the plan with a suggestion.
+func Test_VirtualMachineResource_CatalogValidation(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+
+	testCases := []struct {
+		name         string
+		recipe       string
+		datacenter   string
+		instanceType string
+		errorRe      string
+	}{
+		{
+			name:         "unknown instance type",
+			recipe:       "ubuntu",
+			datacenter:   "us-east-nc-nr-1",
+			instanceType: "rtx94-10c-kn.1",
+			errorRe:      `(?s)Instance type "rtx94-10c-kn.1" does not exist.*Did you mean "rtx49-10c-kn.1"\?`,
+		},
+		{
+			name:         "instance type not offered in datacenter",
+			recipe:       "ubuntu",
+			datacenter:   "us-east-nc-nr-2",
+			instanceType: "rtx49-10c-kn.1",
+			errorRe:      `(?s)not offered in datacenter "us-east-nc-nr-2".*Did you mean "us-east-nc-nr-1"\?`,
+		},
+		{
+			name:         "unknown recipe",
+			recipe:       "ubunut",
+			datacenter:   "us-east-nc-nr-1",
+			instanceType: "rtx49-10c-kn.1",
+			errorRe:      `(?s)Recipe "ubunut" does not exist.*Did you mean "ubuntu"\?`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resource.Test(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []resource.TestStep{
+					{
+						Config: providerConfig(server.URL, "1.0") + fmt.Sprintf(`
+							resource "cloudrift_virtual_machine" "machine0" {
+							  recipe        = %q
+							  datacenter    = %q
+							  instance_type = %q
+							  ssh_key_id    = "11111"
+							}
+						`, tc.recipe, tc.datacenter, tc.instanceType),
+						PlanOnly:    true,
+						ExpectError: regexp.MustCompile(tc.errorRe),
+					},
+				},
+			})
+		})
+	}
+}
+
+func Test_ValidatePlacement(t *testing.T) {
+	t.Parallel()
+
+	var catalog cloudriftapi.ListInstanceTypesResponseProto
+	catalog.Data.InstanceTypes = []cloudriftapi.InstanceType{
+		{
+			Name: "rtx49",
+			Variants: []cloudriftapi.InstanceVariantInfo{
+				{Name: "rtx49-10c-kn.1", NodesPerDc: map[string]int32{"us-east-nc-nr-1": 2}},
+				{Name: "rtx49-20c-kn.2", NodesPerDc: map[string]int32{"us-east-nc-nr-1": 1, "eu-north-1": 1}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		instanceType types.String
+		datacenter   types.String
+		wantError    string
+	}{
+		{name: "offered", instanceType: types.StringValue("rtx49-20c-kn.2"), datacenter: types.StringValue("eu-north-1")},
+		{name: "unknown values", instanceType: types.StringUnknown(), datacenter: types.StringUnknown()},
+		{name: "datacenter only", instanceType: types.StringNull(), datacenter: types.StringValue("eu-north-1")},
+		{
+			name:         "unknown instance type",
+			instanceType: types.StringValue("rtx50-10c-kn.1"),
+			datacenter:   types.StringValue("us-east-nc-nr-1"),
+			wantError:    `Instance type "rtx50-10c-kn.1" does not exist. Did you mean one of "rtx49-10c-kn.1", "rtx49-20c-kn.2"?`,
+		},
+		{
+			name:         "not offered",
+			instanceType: types.StringValue("rtx49-10c-kn.1"),
+			datacenter:   types.StringValue("eu-north-1"),
+			wantError:    `Instance type "rtx49-10c-kn.1" is not offered in datacenter "eu-north-1", it is offered in: us-east-nc-nr-1.`,
+		},
+		{
+			name:         "unknown datacenter",
+			instanceType: types.StringUnknown(),
+			datacenter:   types.StringValue("eu-nort-1"),
+			wantError:    `Datacenter "eu-nort-1" does not offer any instance type. Did you mean "eu-north-1"?`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			diags := validatePlacement(&catalog, tt.instanceType, tt.datacenter)
+			if tt.wantError == "" {
+				if diags.HasError() {
+					t.Fatalf("unexpected error diagnostics: %v", diags)
+				}
+				return
+			}
+			if len(diags.Errors()) != 1 || diags.Errors()[0].Detail() != tt.wantError {
+				t.Errorf("got %v, want a single error %q", diags, tt.wantError)
+			}
+		})
+	}
+}
+
+func Test_DidYouMean(t *testing.T) {
+	t.Parallel()
+
+	candidates := []string{"ubuntu", "ubuntu-2", "ubuntu-cuda", "debian"}
+	tests := map[string]string{
+		"ubunut":  ` Did you mean "ubuntu"?`,
+		"Debain":  ` Did you mean "debian"?`,
+		"windows": ``,
+		"":        ``,
+	}
+
+	for value, want := range tests {
+		if got := didYouMean(value, candidates); got != want {
+			t.Errorf("didYouMean(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/hashicorp/terraform-plugin-framework/list"
+	"github.com/hashicorp/terraform-plugin-framework/list/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+var (
+	_ list.ListResource                   = &virtualMachineListResource{}
+	_ list.ListResourceWithConfigure      = &virtualMachineListResource{}
+	_ list.ListResourceWithValidateConfig = &virtualMachineListResource{}
+)
+
+// listableInstanceStatuses are the statuses the `statuses` filter accepts,
+// the ones of instances that can be imported. Terminated and failed instances
+// cannot be read back, they are never listed.
+var listableInstanceStatuses = []cloudriftapi.InstanceStatus{
+	cloudriftapi.InstanceStatusInitializing,
+	cloudriftapi.InstanceStatusActive,
+	cloudriftapi.InstanceStatusDeactivating,
+}
+
+type virtualMachineListModel struct {
+	Statuses types.List `tfsdk:"statuses"`
+}
+
+type virtualMachineListResource struct {
+	client *cloudriftapi.HttpClient
+}
+
+func NewInstanceListResource() list.ListResource {
+	return new(virtualMachineListResource)
+}
+
+func (r *virtualMachineListResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_virtual_machine"
+}
+
+func (r *virtualMachineListResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	if req.ProviderData == nil {
+		return
+	}
+
+	client, ok := req.ProviderData.(*cloudriftapi.HttpClient)
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected List Resource Configure Type",
+			fmt.Sprintf("Expected *cloudriftapi.HttpClient, got: %T. Please report this issue to the provider developers.",
+				req.ProviderData,
+			),
+		)
+		return
+	}
+
+	r.client = client.WithContext(ctx)
+}
+
+func (r *virtualMachineListResource) ListResourceConfigSchema(_ context.Context, _ list.ListResourceSchemaRequest, resp *list.ListResourceSchemaResponse) {
+	statuses := make([]string, 0, len(listableInstanceStatuses))
+	for _, s := range listableInstanceStatuses {
+		statuses = append(statuses, "`"+string(s)+"`")
+	}
+
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "Lists the Virtual Machines of the account, or of the team if `team_id` is configured on the provider.",
+		Attributes: map[string]schema.Attribute{
+			"statuses": schema.ListAttribute{
+				MarkdownDescription: "Only list instances in one of these statuses, one of " + strings.Join(statuses, ", ") +
+					". Defaults to all of them. Terminated (`Inactive`) and `Failed` instances cannot be imported and are never listed.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+		},
+	}
+}
+
+func (r *virtualMachineListResource) ValidateListResourceConfig(ctx context.Context, req list.ValidateConfigRequest, resp *list.ValidateConfigResponse) {
+	var config virtualMachineListModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &config)...)
+	if resp.Diagnostics.HasError() || config.Statuses.IsNull() || config.Statuses.IsUnknown() {
+		return
+	}
+
+	for i, s := range config.Statuses.Elements() {
+		status, ok := s.(types.String)
+		if !ok || status.IsNull() || status.IsUnknown() {
+			continue
+		}
+		if !slices.Contains(listableInstanceStatuses, cloudriftapi.InstanceStatus(status.ValueString())) {
+			resp.Diagnostics.AddAttributeError(
+				path.Root("statuses").AtListIndex(i),
+				"Invalid Virtual Machine List Configuration",
+				fmt.Sprintf("Instance status %q cannot be listed, expected one of: %v. Terminated and failed instances cannot be imported.",
+					status.ValueString(), listableInstanceStatuses),
+			)
+		}
+	}
+}
+
+func (r *virtualMachineListResource) List(ctx context.Context, req list.ListRequest, stream *list.ListResultsStream) {
+	var config virtualMachineListModel
+	diags := req.Config.Get(ctx, &config)
+	if diags.HasError() {
+		stream.Results = list.ListResultsStreamDiagnostics(diags)
+		return
+	}
+
+	statuses := cloudriftapi.DefaultListedInstanceStatuses
+	if !config.Statuses.IsNull() {
+		var selected []string
+		diags.Append(config.Statuses.ElementsAs(ctx, &selected, false)...)
+		if diags.HasError() {
+			stream.Results = list.ListResultsStreamDiagnostics(diags)
+			return
+		}
+		statuses = make([]cloudriftapi.InstanceStatus, 0, len(selected))
+		for _, s := range selected {
+			statuses = append(statuses, cloudriftapi.InstanceStatus(s))
+		}
+	}
+
+	instances, err := r.client.ListInstancesByStatus(statuses)
+	if err != nil {
+		diags.AddError(
+			"Error listing CloudRift Virtual Machines",
+			"Could not list CloudRift Virtual Machines: "+err.Error(),
+		)
+		stream.Results = list.ListResultsStreamDiagnostics(diags)
+		return
+	}
+
+	stream.Results = func(push func(list.ListResult) bool) {
+		for i := range instances.Data.Instances {
+			if req.Limit > 0 && int64(i) >= req.Limit {
+				return
+			}
+			vm := &instances.Data.Instances[i]
+
+			result := req.NewListResult(ctx)
+			result.DisplayName = vm.Id
+			if vm.InstanceName != nil && *vm.InstanceName != "" {
+				result.DisplayName = *vm.InstanceName
+			}
+
+			result.Diagnostics.Append(result.Identity.Set(ctx, newVirtualMachineIdentity(vm.Id, r.client.TeamID))...)
+			if req.IncludeResource && !result.Diagnostics.HasError() {
+				m := listedVirtualMachineModel(vm)
+				result.Diagnostics.Append(populateModelFromInstanceResponse(&m, vm)...)
+				result.Diagnostics.Append(result.Resource.Set(ctx, &m)...)
+			}
+
+			if !push(result) {
+				return
+			}
+		}
+	}
+}
+
+// listedVirtualMachineModel returns the model of a listed instance before it
+// is populated from the API, with the inputs the API does not return unset.
+func listedVirtualMachineModel(vm *cloudriftapi.InstanceAndUsageInfo) virtualMachineModel {
+	m := virtualMachineModel{
+		Name:         types.StringNull(),
+		Recipe:       types.StringNull(),
+		Datacenter:   types.StringNull(),
+		SSHKeyID:     types.StringNull(),
+		InstanceType: types.StringNull(),
+		ExposedPorts: types.ListNull(types.Int64Type),
+	}
+	if vm.InstanceName != nil && *vm.InstanceName != "" {
+		m.Name = types.StringValue(*vm.InstanceName)
+	}
+	return m
+}
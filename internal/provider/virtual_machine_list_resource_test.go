@@ -0,0 +1,163 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+	"github.com/hashicorp/terraform-plugin-framework/list"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
+)
+
+// runListResource configures the list resource with the client and collects
+// the results it streams for the given list configuration.
+func runListResource(t *testing.T, lr list.ListResource, r resource.ResourceWithIdentity, client *cloudriftapi.HttpClient, config map[string]tftypes.Value) []list.ListResult {
+	t.Helper()
+	ctx := context.Background()
+
+	var configureResp resource.ConfigureResponse
+	lr.(list.ListResourceWithConfigure).Configure(ctx, resource.ConfigureRequest{ProviderData: client}, &configureResp)
+	if configureResp.Diagnostics.HasError() {
+		t.Fatalf("Configure: %v", configureResp.Diagnostics)
+	}
+
+	var listSchema list.ListResourceSchemaResponse
+	lr.ListResourceConfigSchema(ctx, list.ListResourceSchemaRequest{}, &listSchema)
+	var resourceSchema resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &resourceSchema)
+	var identitySchema resource.IdentitySchemaResponse
+	r.IdentitySchema(ctx, resource.IdentitySchemaRequest{}, &identitySchema)
+
+	req := list.ListRequest{
+		Config: tfsdk.Config{
+			Schema: listSchema.Schema,
+			Raw:    tftypes.NewValue(listSchema.Schema.Type().TerraformType(ctx), config),
+		},
+		IncludeResource:        true,
+		ResourceSchema:         resourceSchema.Schema,
+		ResourceIdentitySchema: identitySchema.IdentitySchema,
+	}
+
+	var stream list.ListResultsStream
+	lr.List(ctx, req, &stream)
+	return slices.Collect(stream.Results)
+}
+
+func Test_VirtualMachineListResource(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t, cloudrifttest.WithTimeline(cloudrifttest.Timeline{Deactivating: -1}))
+	client, err := cloudriftapi.NewCustom(server.URL, "test", "", "team-123")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+
+	// An active Virtual Machine and an unnamed deactivating one.
+	var ids []string
+	for _, name := range []string{"vm-one", ""} {
+		rented, err := client.RentPublicInstanceVM(cloudriftapi.RentVMOptions{
+			Recipe:       "ubuntu",
+			Datacenter:   cloudrifttest.DefaultDatacenter,
+			InstanceType: "rtx49-10c-kn.1",
+			Name:         name,
+			PublicKeys:   []string{"ssh-ed25519 AAAA test"},
+		})
+		if err != nil {
+			t.Fatalf("RentPublicInstanceVM: %v", err)
+		}
+		ids = append(ids, rented.Data.InstanceIds[0])
+	}
+	if err := client.TerminateInstances(ids[1:]); err != nil {
+		t.Fatalf("TerminateInstances: %v", err)
+	}
+
+	results := runListResource(t, NewInstanceListResource(), &virtualMachineResource{}, client, map[string]tftypes.Value{
+		"statuses": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{
+			tftypes.NewValue(tftypes.String, "Active"),
+			tftypes.NewValue(tftypes.String, "Deactivating"),
+		}),
+	})
+
+	var listed struct {
+		Selector struct {
+			ByStatus struct {
+				Statuses []string `json:"statuses"`
+				Scope    struct {
+					Teams []string `json:"Teams"`
+				} `json:"scope"`
+			} `json:"ByStatus"`
+		} `json:"selector"`
+	}
+	if err := server.LastRequest("/api/v1/instances/list", &listed); err != nil {
+		t.Fatal(err)
+	}
+	if by := listed.Selector.ByStatus; !slices.Equal(by.Statuses, []string{"Active", "Deactivating"}) || !slices.Equal(by.Scope.Teams, []string{"team-123"}) {
+		t.Errorf("expected the listing to select the configured statuses within the team, got %+v", by)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	wantDisplayNames := []string{"vm-one", ids[1]}
+	for i, result := range results {
+		if result.Diagnostics.HasError() {
+			t.Fatalf("result %d: unexpected diagnostics: %v", i, result.Diagnostics)
+		}
+		if result.DisplayName != wantDisplayNames[i] {
+			t.Errorf("result %d: display name %q, want %q", i, result.DisplayName, wantDisplayNames[i])
+		}
+
+		var identity virtualMachineIdentityModel
+		if diags := result.Identity.Get(context.Background(), &identity); diags.HasError() {
+			t.Fatalf("result %d: identity: %v", i, diags)
+		}
+		if identity.TeamID.ValueString() != "team-123" {
+			t.Errorf("result %d: identity team_id %q, want %q", i, identity.TeamID.ValueString(), "team-123")
+		}
+	}
+
+	var name, status types.String
+	results[0].Resource.GetAttribute(context.Background(), path.Root("name"), &name)
+	results[0].Resource.GetAttribute(context.Background(), path.Root("status"), &status)
+	if name.ValueString() != "vm-one" || status.ValueString() != "Active" {
+		t.Errorf("unexpected listed resource: name %q, status %q", name.ValueString(), status.ValueString())
+	}
+}
+
+func Test_VirtualMachineListResource_InvalidStatus(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	lr := &virtualMachineListResource{}
+	var listSchema list.ListResourceSchemaResponse
+	lr.ListResourceConfigSchema(ctx, list.ListResourceSchemaRequest{}, &listSchema)
+
+	// Terminated and failed instances cannot be imported.
+	for _, status := range []string{"Running", "Inactive", "Failed"} {
+		req := list.ValidateConfigRequest{
+			Config: tfsdk.Config{
+				Schema: listSchema.Schema,
+				Raw: tftypes.NewValue(listSchema.Schema.Type().TerraformType(ctx), map[string]tftypes.Value{
+					"statuses": tftypes.NewValue(tftypes.List{ElementType: tftypes.String}, []tftypes.Value{
+						tftypes.NewValue(tftypes.String, "Active"),
+						tftypes.NewValue(tftypes.String, status),
+					}),
+				}),
+			},
+		}
+		var resp list.ValidateConfigResponse
+		lr.ValidateListResourceConfig(ctx, req, &resp)
+
+		if len(resp.Diagnostics) != 1 || !strings.Contains(resp.Diagnostics[0].Detail(), fmt.Sprintf("%q", status)) {
+			t.Errorf("expected a single error for the status %q, got %v", status, resp.Diagnostics)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,7 +14,10 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/identityschema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/float64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
@@ -37,6 +42,9 @@ var (
 	_ resource.ResourceWithConfigure      = &virtualMachineResource{}
 	_ resource.ResourceWithImportState    = &virtualMachineResource{}
 	_ resource.ResourceWithValidateConfig = &virtualMachineResource{}
+	_ resource.ResourceWithUpgradeState   = &virtualMachineResource{}
+	_ resource.ResourceWithIdentity       = &virtualMachineResource{}
+	_ resource.ResourceWithModifyPlan     = &virtualMachineResource{}
 )
 
 type virtualMachineMetadataModel struct {
@@ -60,22 +68,48 @@ type virtualMachineModel struct {
 	PublicIP  types.String `tfsdk:"public_ip"`
 	PrivateIP types.String `tfsdk:"private_ip"`
 
-	ProviderName types.String `tfsdk:"provider_name"`
-	InstanceType types.String `tfsdk:"instance_type"`
+	ProviderName types.String  `tfsdk:"provider_name"`
+	InstanceType types.String  `tfsdk:"instance_type"`
+	CostPerHour  types.Float64 `tfsdk:"cost_per_hour"`
 
 	VirtualMachines types.List `tfsdk:"virtual_machines"`
 	PortMappings    types.List `tfsdk:"port_mappings"`
+	PortEndpoints   types.Map  `tfsdk:"port_endpoints"`
 
 	// Write only attributes.
-	Name       types.String                 `tfsdk:"name"`
-	Metadata   *virtualMachineMetadataModel `tfsdk:"metadata"`
-	Recipe     types.String                 `tfsdk:"recipe"`
-	Datacenter types.String                 `tfsdk:"datacenter"`
-	SSHKeyID   types.String                 `tfsdk:"ssh_key_id"`
+	Name         types.String                 `tfsdk:"name"`
+	Metadata     *virtualMachineMetadataModel `tfsdk:"metadata"`
+	UserData     *virtualMachineUserDataModel `tfsdk:"user_data"`
+	Recipe       types.String                 `tfsdk:"recipe"`
+	Datacenter   types.String                 `tfsdk:"datacenter"`
+	SSHKeyID     types.String                 `tfsdk:"ssh_key_id"`
+	ExposedPorts types.List                   `tfsdk:"exposed_ports"`
+
+	ReuseEnvironmentID types.String `tfsdk:"reuse_environment_id"`
+	ClusterName        types.String `tfsdk:"cluster_name"`
+}
+
+// virtualMachineIdentityModel identifies a Virtual Machine across teams, the
+// same instance ID is only visible within the scope of its team.
+type virtualMachineIdentityModel struct {
+	ID     types.String `tfsdk:"id"`
+	TeamID types.String `tfsdk:"team_id"`
+}
+
+func newVirtualMachineIdentity(id, teamID string) virtualMachineIdentityModel {
+	identity := virtualMachineIdentityModel{
+		ID:     types.StringValue(id),
+		TeamID: types.StringNull(),
+	}
+	if teamID != "" {
+		identity.TeamID = types.StringValue(teamID)
+	}
+	return identity
 }
 
 type virtualMachineResource struct {
-	client *cloudriftapi.HttpClient
+	client    *cloudriftapi.HttpClient
+	preflight *balancePreflight
 }
 
 func NewInstanceResource() resource.Resource {
@@ -86,23 +120,24 @@ func (r *virtualMachineResource) Metadata(_ context.Context, req resource.Metada
 	resp.TypeName = req.ProviderTypeName + "_virtual_machine"
 }
 
-func (r *virtualMachineResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+func (r *virtualMachineResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
 	if req.ProviderData == nil {
 		return
 	}
 
-	client, ok := req.ProviderData.(*cloudriftapi.HttpClient)
+	data, ok := req.ProviderData.(*resourceData)
 	if !ok {
 		resp.Diagnostics.AddError(
 			"Unexpected Resource Configure Type",
-			fmt.Sprintf("Expected *cloudriftapi.HttpClient, got: %T. Please report this issue to the provider developers.",
+			fmt.Sprintf("Expected *resourceData, got: %T. Please report this issue to the provider developers.",
 				req.ProviderData,
 			),
 		)
 		return
 	}
 
-	r.client = client
+	r.client = data.client.WithContext(ctx)
+	r.preflight = data.preflight
 }
 
 func (r *virtualMachineResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
@@ -122,10 +157,209 @@ func (r *virtualMachineResource) ValidateConfig(ctx context.Context, req resourc
 			"Attribute \"recipe\" must not be empty.",
 		)
 	}
+
+	if !config.ExposedPorts.IsUnknown() && !config.ExposedPorts.IsNull() {
+		for i, e := range config.ExposedPorts.Elements() {
+			port, ok := e.(types.Int64)
+			if !ok || port.IsUnknown() {
+				continue
+			}
+			if port.IsNull() || port.ValueInt64() < 1 || port.ValueInt64() > 65535 {
+				resp.Diagnostics.AddAttributeError(
+					path.Root("exposed_ports").AtListIndex(i),
+					"Invalid Virtual Machine Configuration",
+					fmt.Sprintf("Attribute \"exposed_ports\" must only contain ports between 1 and 65535, got: %s", port.String()),
+				)
+			}
+		}
+	}
+
+	resp.Diagnostics.Append(validateStartupConfig(config)...)
+}
+
+// ModifyPlan checks the instance type, datacenter and recipe against the
+// CloudRift catalog, so that a typo fails the plan rather than the rent
+// request. Only values that change are checked, an instance type retired
+// from the catalog must not break the plan of an existing Virtual Machine.
+// It also plans the cost of the instance type and, for a Virtual Machine
+// created or replaced, checks that the balance covers it when
+// `min_balance_hours` is set, and checks that a reused saved environment
+// exists and is bound to the instance type.
+func (r *virtualMachineResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() || r.client == nil {
+		return
+	}
+
+	var planned, current catalogInputs
+	resp.Diagnostics.Append(planned.from(ctx, req.Plan.GetAttribute)...)
+	if !req.State.Raw.IsNull() {
+		resp.Diagnostics.Append(current.from(ctx, req.State.GetAttribute)...)
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var costPerHour types.Float64
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("cost_per_hour"), &costPerHour)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	instanceType := changedValue(planned.InstanceType, current.InstanceType)
+	datacenter := changedValue(planned.Datacenter, current.Datacenter)
+	if !instanceType.IsNull() {
+		// The prior cost belongs to the replaced instance type.
+		costPerHour = types.Float64Unknown()
+	}
+	lookupCost := costPerHour.IsUnknown() && !planned.InstanceType.IsUnknown()
+
+	if !instanceType.IsNull() || !datacenter.IsNull() || lookupCost {
+		catalog, err := r.client.ListInstanceTypes()
+		if err != nil {
+			resp.Diagnostics.AddWarning(
+				"Unable to validate Virtual Machine Configuration",
+				"Could not list the CloudRift instance types to validate \"instance_type\" and \"datacenter\": "+err.Error(),
+			)
+		} else {
+			if !instanceType.IsNull() || !datacenter.IsNull() {
+				// The datacenter is checked against the planned instance type
+				// even if only the datacenter changes.
+				if instanceType.IsNull() && !datacenter.IsNull() {
+					instanceType = planned.InstanceType
+				}
+				resp.Diagnostics.Append(validatePlacement(catalog, instanceType, datacenter)...)
+			}
+			if lookupCost {
+				if variant := findInstanceVariant(catalog, planned.InstanceType.ValueString()); variant != nil {
+					costPerHour = types.Float64Value(variant.CostPerHour)
+				}
+				resp.Diagnostics.Append(resp.Plan.SetAttribute(ctx, path.Root("cost_per_hour"), costPerHour)...)
+			}
+		}
+	}
+
+	// Only the instances the plan rents count against the balance, unchanged
+	// and updated in-place ones already run on it.
+	rents := req.State.Raw.IsNull() || len(resp.RequiresReplace) > 0
+	if rents && !costPerHour.IsUnknown() && !costPerHour.IsNull() {
+		resp.Diagnostics.Append(r.preflight.check(ctx, costPerHour.ValueFloat64(), path.Root("cost_per_hour"))...)
+	}
+
+	// An imported Virtual Machine adopts the saved environment of the
+	// configuration without renting again.
+	reuseChanged := !changedValue(planned.ReuseEnvironmentID, current.ReuseEnvironmentID).IsNull() &&
+		!(current.ReuseEnvironmentID.IsNull() && adoptingImportedInputs(ctx, req.Private))
+	placementChanged := !changedValue(planned.InstanceType, current.InstanceType).IsNull() ||
+		!changedValue(planned.Datacenter, current.Datacenter).IsNull()
+	if reuseChanged || (!planned.ReuseEnvironmentID.IsNull() && placementChanged) {
+		resp.Diagnostics.Append(r.validateReuseEnvironment(planned.ReuseEnvironmentID, planned.InstanceType, planned.Datacenter)...)
+	}
+
+	recipe := changedValue(planned.Recipe, current.Recipe)
+	if recipe.IsNull() || cloudriftapi.IsImageURL(strings.TrimSpace(recipe.ValueString())) {
+		return
+	}
+	found, err := r.client.HasVMRecipe(recipe.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddWarning(
+			"Unable to validate Virtual Machine Configuration",
+			"Could not list the CloudRift recipes to validate \"recipe\": "+err.Error(),
+		)
+		return
+	}
+	if !found {
+		resp.Diagnostics.AddAttributeError(
+			path.Root("recipe"),
+			"Invalid Virtual Machine Configuration",
+			fmt.Sprintf("Recipe %q does not exist in the CloudRift recipe catalog, use a recipe name or an http:// or https:// image URL.", recipe.ValueString())+
+				didYouMean(strings.ToLower(recipe.ValueString()), r.client.VMRecipeNames()),
+		)
+	}
+}
+
+// validateReuseEnvironment checks the saved environment against the planned
+// instance type and datacenter, failing to list the environments or the
+// catalog only warns.
+func (r *virtualMachineResource) validateReuseEnvironment(id, instanceType, datacenter types.String) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if id.IsUnknown() || id.IsNull() {
+		return diags
+	}
+
+	envs, err := r.client.ListSavedEnvironments()
+	if err != nil {
+		diags.AddWarning(
+			"Unable to validate Virtual Machine Configuration",
+			"Could not list the CloudRift saved environments to validate \"reuse_environment_id\": "+err.Error(),
+		)
+		return diags
+	}
+
+	catalog, err := r.client.ListInstanceTypes()
+	if err != nil {
+		diags.AddWarning(
+			"Unable to validate Virtual Machine Configuration",
+			"Could not list the CloudRift instance types to validate \"reuse_environment_id\": "+err.Error(),
+		)
+		catalog = nil
+	}
+
+	diags.Append(validateSavedEnvironment(catalog, envs, id, instanceType, datacenter)...)
+	return diags
+}
+
+// catalogInputs are the attributes of a Virtual Machine checked against the
+// CloudRift catalog.
+type catalogInputs struct {
+	InstanceType       types.String
+	Datacenter         types.String
+	Recipe             types.String
+	ReuseEnvironmentID types.String
+}
+
+func (c *catalogInputs) from(ctx context.Context, get func(context.Context, path.Path, any) diag.Diagnostics) diag.Diagnostics {
+	var diags diag.Diagnostics
+	diags.Append(get(ctx, path.Root("instance_type"), &c.InstanceType)...)
+	diags.Append(get(ctx, path.Root("datacenter"), &c.Datacenter)...)
+	diags.Append(get(ctx, path.Root("recipe"), &c.Recipe)...)
+	diags.Append(get(ctx, path.Root("reuse_environment_id"), &c.ReuseEnvironmentID)...)
+	return diags
+}
+
+// changedValue returns the planned value if it is known and differs from the
+// current one, null otherwise.
+func changedValue(planned, current types.String) types.String {
+	if planned.IsUnknown() || planned.IsNull() || planned.Equal(current) {
+		return types.StringNull()
+	}
+	return planned
+}
+
+func (r *virtualMachineResource) UpgradeState(_ context.Context) map[int64]resource.StateUpgrader {
+	return map[int64]resource.StateUpgrader{
+		// Version 0 only had the base64 encoded metadata.startup_commands.
+		0: {StateUpgrader: upgradeVirtualMachineStateV0},
+	}
+}
+
+func (r *virtualMachineResource) IdentitySchema(_ context.Context, _ resource.IdentitySchemaRequest, resp *resource.IdentitySchemaResponse) {
+	resp.IdentitySchema = identityschema.Schema{
+		Attributes: map[string]identityschema.Attribute{
+			"id": identityschema.StringAttribute{
+				Description:       "ID of the CloudRift instance.",
+				RequiredForImport: true,
+			},
+			"team_id": identityschema.StringAttribute{
+				Description:       "ID of the team owning the instance, unset for personal accounts.",
+				OptionalForImport: true,
+			},
+		},
+	}
 }
 
 func (r *virtualMachineResource) Schema(_ context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
 	resp.Schema = schema.Schema{
+		Version:             1,
 		MarkdownDescription: "Manage virtualMachines",
 		Attributes: map[string]schema.Attribute{
 			"id": schema.StringAttribute{
@@ -176,6 +410,13 @@ func (r *virtualMachineResource) Schema(_ context.Context, req resource.SchemaRe
 					stringplanmodifier.RequiresReplace(),
 				},
 			},
+			"cost_per_hour": schema.Float64Attribute{
+				MarkdownDescription: "Hourly cost in USD of the instance type, taken from the CloudRift catalog when the instance type is planned.",
+				Computed:            true,
+				PlanModifiers: []planmodifier.Float64{
+					float64planmodifier.UseStateForUnknown(),
+				},
+			},
 			"virtual_machines": schema.ListNestedAttribute{
 				MarkdownDescription: "Virtual Machines info.",
 				Computed:            true,
@@ -212,16 +453,58 @@ func (r *virtualMachineResource) Schema(_ context.Context, req resource.SchemaRe
 					},
 				},
 			},
+			"port_endpoints": schema.MapAttribute{
+				MarkdownDescription: "Public `host:port` endpoint for each reachable guest port, keyed by the guest port (e.g. `port_endpoints[\"8888\"]`). " +
+					"On shared-IP datacenters it is built from `port_mappings`; on dedicated-IP datacenters every port in `exposed_ports` is reachable on `public_ip` directly.",
+				Computed:    true,
+				ElementType: types.StringType,
+			},
+			"exposed_ports": schema.ListAttribute{
+				MarkdownDescription: "Guest ports to expose on the Virtual Machine, sent at rent time. On shared-IP datacenters the platform forwards each of them from a port on the shared IP, see `port_endpoints`. Changing it forces replacement.",
+				Optional:            true,
+				ElementType:         types.Int64Type,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplaceIf(
+						func(ctx context.Context, req planmodifier.ListRequest, resp *listplanmodifier.RequiresReplaceIfFuncResponse) {
+							resp.RequiresReplace = !(req.StateValue.IsNull() && adoptingImportedInputs(ctx, req.Private))
+						},
+						"Changing the exposed ports forces replacement.",
+						"Changing the exposed ports forces replacement.",
+					),
+				},
+			},
 			"metadata": schema.SingleNestedAttribute{
 				MarkdownDescription: "Option to provide metadata. Currently supported is `startup_commands`.",
+				DeprecationMessage:  "Use user_data.shell_script instead, which takes the script as plain text.",
 				Optional:            true,
+				PlanModifiers: []planmodifier.Object{
+					requiresReplaceIfStartupConfigChanged(),
+				},
 				Attributes: map[string]schema.Attribute{
 					"startup_commands": schema.StringAttribute{
+						MarkdownDescription: "A base64 encoded script that will be executed after the first instance boot.",
+						Optional:            true,
+					},
+				},
+			},
+			"user_data": schema.SingleNestedAttribute{
+				MarkdownDescription: "First boot configuration of the Virtual Machine. Changing the effective configuration forces replacement.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.Object{
+					requiresReplaceIfStartupConfigChanged(),
+				},
+				Attributes: map[string]schema.Attribute{
+					"shell_script": schema.StringAttribute{
 						MarkdownDescription: "A plain text script that will be executed after the first instance boot.",
 						Optional:            true,
-						PlanModifiers: []planmodifier.String{
-							stringplanmodifier.RequiresReplace(),
-						},
+					},
+					"cloud_config": schema.StringAttribute{
+						MarkdownDescription: "A cloud-config YAML document, starting with `#cloud-config`, replacing the recipe's cloud-init configuration. Validated at plan time. Conflicts with `cloudinit_url`.",
+						Optional:            true,
+					},
+					"cloudinit_url": schema.StringAttribute{
+						MarkdownDescription: "URL of a cloud-init configuration replacing the one of the recipe. Conflicts with `cloud_config`.",
+						Optional:            true,
 					},
 				},
 			},
@@ -229,28 +512,43 @@ func (r *virtualMachineResource) Schema(_ context.Context, req resource.SchemaRe
 				MarkdownDescription: "Optional name for the Virtual Machine, shown in the CloudRift dashboard. Changing it forces replacement.",
 				Optional:            true,
 				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
+					requiresReplaceUnlessAdopted(),
 				},
 			},
 			"recipe": schema.StringAttribute{
 				MarkdownDescription: "The Base Image used for the Virtual Machine. Either a name from the CloudRift recipe catalog (e.g. `ubuntu`), or a direct `http://` / `https://` URL of a custom VM image.",
 				Required:            true,
 				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
+					requiresReplaceUnlessAdopted(),
 				},
 			},
 			"datacenter": schema.StringAttribute{
 				MarkdownDescription: "The datacenter identifier",
 				Required:            true,
 				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
+					requiresReplaceUnlessAdopted(),
 				},
 			},
 			"ssh_key_id": schema.StringAttribute{
 				MarkdownDescription: "The SSH Key ID to be able to connect to the Virtual Machine.",
 				Required:            true,
 				PlanModifiers: []planmodifier.String{
-					stringplanmodifier.RequiresReplace(),
+					requiresReplaceUnlessAdopted(),
+				},
+			},
+			"cluster_name": schema.StringAttribute{
+				MarkdownDescription: "Name of the cluster to rent the Virtual Machine in, grouping it with the other instances of the cluster. Changing it forces replacement.",
+				Optional:            true,
+				PlanModifiers: []planmodifier.String{
+					requiresReplaceUnlessAdopted(),
+				},
+			},
+			"reuse_environment_id": schema.StringAttribute{
+				MarkdownDescription: "ID of a saved environment to boot the Virtual Machine from instead of a fresh OS, see the `cloudrift_saved_environments` data source. " +
+					"The Virtual Machine is rented on the node of the environment, which must be of the planned instance type and in the planned datacenter. Changing it forces replacement.",
+				Optional: true,
+				PlanModifiers: []planmodifier.String{
+					requiresReplaceUnlessAdopted(),
 				},
 			},
 		},
@@ -290,19 +588,60 @@ func (r *virtualMachineResource) Create(ctx context.Context, req resource.Create
 		return
 	}
 
-	startupCommands := ""
-	if plan.Metadata != nil {
-		startupCommands = plan.Metadata.StartupCommands.ValueString()
+	startup, _, err := newStartupConfig(plan.Metadata, plan.UserData)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error creating Virtual Machine",
+			"Could not create Virtual Machine, invalid startup configuration: "+err.Error(),
+		)
+		return
 	}
 
-	ids, err := r.client.RentPublicInstanceVM(
-		plan.Recipe.ValueString(),
-		plan.Datacenter.ValueString(),
-		plan.InstanceType.ValueString(),
-		startupCommands,
-		plan.Name.ValueString(),
-		[]string{matched.PublicKey},
-	)
+	var exposedPorts []int64
+	resp.Diagnostics.Append(plan.ExposedPorts.ElementsAs(ctx, &exposedPorts, false)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	var ports []string
+	for _, p := range exposedPorts {
+		ports = append(ports, strconv.FormatInt(p, 10))
+	}
+
+	opts := cloudriftapi.RentVMOptions{
+		Recipe:          plan.Recipe.ValueString(),
+		Datacenter:      plan.Datacenter.ValueString(),
+		InstanceType:    plan.InstanceType.ValueString(),
+		Name:            plan.Name.ValueString(),
+		PublicKeys:      []string{matched.PublicKey},
+		Ports:           ports,
+		StartupCommands: startup.ShellScript,
+		CloudInit:       startup.cloudInit(),
+		ClusterName:     plan.ClusterName.ValueString(),
+	}
+	if id := plan.ReuseEnvironmentID.ValueString(); id != "" {
+		env, err := r.client.GetSavedEnvironment(id)
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error creating Virtual Machine",
+				"Could not find saved environment "+id+" to reuse: "+err.Error(),
+			)
+			return
+		}
+		// The rent is pinned to the node of the environment, which ignores
+		// the datacenter: a datacenter the node is not in would be recorded.
+		// A catalog that cannot be listed was already warned about in the plan.
+		if catalog, err := r.client.ListInstanceTypes(); err == nil {
+			resp.Diagnostics.Append(validateSavedEnvironmentDatacenter(catalog, *env, plan.Datacenter)...)
+			if resp.Diagnostics.HasError() {
+				return
+			}
+		}
+		opts.ReuseEnvironmentID = env.Id
+		opts.NodeID = env.NodeId
+	}
+
+	ids, err := r.client.RentPublicInstanceVM(opts)
 	if err != nil {
 		resp.Diagnostics.AddError(
 			"Error creating Virtual Machine",
@@ -323,6 +662,10 @@ func (r *virtualMachineResource) Create(ctx context.Context, req resource.Create
 
 	id := ids.Data.InstanceIds[0]
 	plan.ID = types.StringValue(id)
+	if plan.CostPerHour.IsUnknown() {
+		// The instance type was not known, or the catalog not reachable, at plan time.
+		plan.CostPerHour = instanceTypeCost(r.client, plan.InstanceType.ValueString())
+	}
 
 	var last *cloudriftapi.InstanceAndUsageInfo
 
@@ -337,6 +680,7 @@ func (r *virtualMachineResource) Create(ctx context.Context, req resource.Create
 			resp.Diagnostics.Append(populateModelFromInstanceResponse(&plan, last)...)
 		}
 		resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+		resp.Diagnostics.Append(resp.Identity.Set(ctx, newVirtualMachineIdentity(id, r.client.TeamID))...)
 	}
 
 	// abandonRentedInstance releases the backend-side VM on hard-failure paths
@@ -380,8 +724,10 @@ func (r *virtualMachineResource) Create(ctx context.Context, req resource.Create
 			}
 			return
 
-		case <-time.After(InstancePollingInterval):
-			current, err := r.client.GetInstanceIncludingInactive(id)
+		case polled := <-r.client.PollInstance(id):
+			// The lookup is batched with the ones of every other Virtual
+			// Machine being created, one list request per polling interval.
+			current, err := polled.Instance, polled.Err
 			if err != nil {
 				if errors.Is(err, cloudriftapi.ErrNotFound) {
 					// The rent call already returned this id, so a not-found
@@ -505,15 +851,51 @@ func (r *virtualMachineResource) Read(ctx context.Context, req resource.ReadRequ
 	if resp.Diagnostics.HasError() {
 		return
 	}
+
+	diags = resp.Identity.Set(ctx, newVirtualMachineIdentity(state.ID.ValueString(), r.client.TeamID))
+	resp.Diagnostics.Append(diags...)
 }
 
 func (r *virtualMachineResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
 	// Currently Cloudrift does not seem to have an API for updating Rented Virtual Machine Instances
-	// As of now, there seems to be only Rent/Terminate endpoint.
-	resp.Diagnostics.AddError(
-		"Unsupported Method",
-		"Update is not supported for CloudRfit Virtual Machine Instance",
-	)
+	// As of now, there seems to be only Rent/Terminate endpoint. Every attribute sent at rent time
+	// forces replacement, the only in-place updates are moves between equivalent startup
+	// configurations (metadata.startup_commands -> user_data.shell_script) and the adoption of
+	// the inputs an import could not recover, which only need the state to be refreshed.
+	var plan virtualMachineModel
+	diags := req.Plan.Get(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	vm, err := r.client.GetInstance(plan.ID.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Error updating CloudRift Virtual Machine",
+			"Cloud not fetch CloudRift Virtual Machine with ID: "+plan.ID.ValueString()+" : "+err.Error(),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(populateModelFromInstanceResponse(&plan, vm)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if plan.CostPerHour.IsUnknown() {
+		plan.CostPerHour = instanceTypeCost(r.client, plan.InstanceType.ValueString())
+	}
+
+	diags = resp.State.Set(ctx, &plan)
+	resp.Diagnostics.Append(diags...)
+	diags = resp.Identity.Set(ctx, newVirtualMachineIdentity(plan.ID.ValueString(), r.client.TeamID))
+	resp.Diagnostics.Append(diags...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The configuration is adopted now, later changes replace the instance again.
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, importedPrivateStateKey, nil)...)
 }
 
 func (r *virtualMachineResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
@@ -561,15 +943,14 @@ func (r *virtualMachineResource) Delete(ctx context.Context, req resource.Delete
 				)
 			}
 			return
-		case <-time.After(InstancePollingInterval):
+		case polled := <-r.client.PollInstance(id):
 			// The instance is considered gone from Terraform's perspective as
 			// soon as the backend acknowledges deactivation — either by
-			// returning ErrNotFound (Inactive) or by reporting Deactivating
-			// status. We don't need to wait for the backend's own
+			// no longer listing it, by reporting Inactive or by reporting
+			// Deactivating status. We don't need to wait for the backend's own
 			// Deactivating→Inactive transition, which can stall indefinitely
 			// on capacity-failure cases.
-			current, err := r.client.GetInstance(id)
-			if err != nil {
+			if err := polled.Err; err != nil {
 				if errors.Is(err, cloudriftapi.ErrNotFound) {
 					return
 				}
@@ -579,7 +960,8 @@ func (r *virtualMachineResource) Delete(ctx context.Context, req resource.Delete
 				)
 				return
 			}
-			if current.Status == cloudriftapi.InstanceStatusDeactivating {
+			switch polled.Instance.Status {
+			case cloudriftapi.InstanceStatusInactive, cloudriftapi.InstanceStatusDeactivating:
 				return
 			}
 		}
@@ -660,6 +1042,9 @@ func populateModelFromInstanceResponse(m *virtualMachineModel, data *cloudriftap
 	}
 	portMappingObjType := types.ObjectType{AttrTypes: portMappingAttrTypes}
 
+	// guest port -> port on the public IP, used to build port_endpoints below.
+	forwarded := make(map[int64]int64)
+
 	if data.PortMappings != nil && len(*data.PortMappings) > 0 {
 		var pmValues []attr.Value
 		for i, pm := range *data.PortMappings {
@@ -685,6 +1070,7 @@ func populateModelFromInstanceResponse(m *virtualMachineModel, data *cloudriftap
 			})
 			diags = append(diags, d...)
 			pmValues = append(pmValues, obj)
+			forwarded[int64(guestPort)] = int64(hostPort)
 		}
 		m.PortMappings, valueDiags = types.ListValue(portMappingObjType, pmValues)
 		diags = append(diags, valueDiags...)
@@ -692,12 +1078,16 @@ func populateModelFromInstanceResponse(m *virtualMachineModel, data *cloudriftap
 		m.PortMappings = types.ListNull(portMappingObjType)
 	}
 
+	m.PortEndpoints, valueDiags = types.MapValue(types.StringType, portEndpoints(data.HostAddress, forwarded, m.ExposedPorts))
+	diags = append(diags, valueDiags...)
+
 	// Since write-only attributes are supported on newer tf versions, have a workaround.
 	// Carry over the previous state for the write only attributes, since the API for fetching
 	// Instances does not return these.
 	// https://discuss.hashicorp.com/t/handling-attribute-required-during-create-but-not-returned-during-read/74613
 	//
 	// state.Metadata = state.Metadata
+	// state.UserData = state.UserData
 	// state.Recipe = state.Recipe
 	// state.Datacenter = state.Datacenter
 	// state.SSHKeyID = state.SSHKeyID
@@ -705,6 +1095,176 @@ func populateModelFromInstanceResponse(m *virtualMachineModel, data *cloudriftap
 	return diags
 }
 
+// portEndpoints maps guest ports to the public "host:port" endpoint they are
+// reachable on. A shared-IP instance reports port_mappings, and only the
+// forwarded ports are reachable. A dedicated-IP instance reports none, so the
+// exposed ports are reachable on the public IP as they are.
+func portEndpoints(hostAddress *string, forwarded map[int64]int64, exposedPorts types.List) map[string]attr.Value {
+	endpoints := make(map[string]attr.Value)
+	if hostAddress == nil || *hostAddress == "" {
+		return endpoints
+	}
+
+	if len(forwarded) == 0 {
+		for _, e := range exposedPorts.Elements() {
+			port, ok := e.(types.Int64)
+			if !ok || port.IsNull() || port.IsUnknown() {
+				continue
+			}
+			forwarded[port.ValueInt64()] = port.ValueInt64()
+		}
+	}
+
+	for guest, host := range forwarded {
+		endpoints[strconv.FormatInt(guest, 10)] = types.StringValue(net.JoinHostPort(*hostAddress, strconv.FormatInt(host, 10)))
+	}
+
+	return endpoints
+}
+
+// ImportState accepts either the instance ID or its name, or the resource
+// identity. The instance list returns neither the image nor the authorized keys
+// of a VM, so only id, name, instance_type and (when the catalog leaves no
+// doubt) datacenter can be reconstructed. The remaining inputs are adopted from
+// the configuration on the first apply instead of forcing a replacement, see
+// requiresReplaceUnlessAdopted.
 func (r *virtualMachineResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
-	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+	var (
+		vm  *cloudriftapi.InstanceAndUsageInfo
+		err error
+	)
+
+	idOrName := strings.TrimSpace(req.ID)
+	if idOrName == "" && req.Identity != nil {
+		var identity virtualMachineIdentityModel
+		resp.Diagnostics.Append(req.Identity.Get(ctx, &identity)...)
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		// Instances are only listed within the scope of their team, an
+		// identity of another team would otherwise be reported as missing.
+		if teamID := identity.TeamID.ValueString(); teamID != r.client.TeamID {
+			resp.Diagnostics.AddError(
+				"Error importing CloudRift Virtual Machine",
+				fmt.Sprintf("Instance %s belongs to %s but the provider is configured for %s, set the provider's team_id to the team of the instance.",
+					identity.ID.ValueString(), describeTeam(teamID), describeTeam(r.client.TeamID)),
+			)
+			return
+		}
+
+		idOrName = identity.ID.ValueString()
+		vm, err = r.client.GetInstance(idOrName)
+	} else {
+		vm, err = r.client.GetInstance(idOrName)
+		if err != nil {
+			byName, nameErr := r.client.GetInstanceByName(idOrName)
+			switch {
+			case nameErr == nil:
+				vm, err = byName, nil
+			case !errors.Is(nameErr, cloudriftapi.ErrNotFound):
+				err = nameErr
+			}
+		}
+	}
+	if err != nil {
+		if errors.Is(err, cloudriftapi.ErrNotFound) {
+			err = fmt.Errorf("no instance with ID or name %q", idOrName)
+		}
+		resp.Diagnostics.AddError(
+			"Error importing CloudRift Virtual Machine",
+			"Could not import CloudRift Virtual Machine "+idOrName+": "+err.Error(),
+		)
+		return
+	}
+
+	resp.Diagnostics.Append(resp.Identity.Set(ctx, newVirtualMachineIdentity(vm.Id, r.client.TeamID))...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), vm.Id)...)
+	if vm.InstanceName != nil && *vm.InstanceName != "" {
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), *vm.InstanceName)...)
+	}
+
+	if vm.ResourceInfo != nil {
+		instanceType := vm.ResourceInfo.InstanceType
+		resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("instance_type"), instanceType)...)
+
+		catalog, err := r.client.ListInstanceTypes()
+		if err != nil {
+			resp.Diagnostics.AddError(
+				"Error importing CloudRift Virtual Machine",
+				"Could not list CloudRift Instance Types to resolve the datacenter: "+err.Error(),
+			)
+			return
+		}
+		if datacenter, ok := datacenterForInstanceType(catalog, instanceType); ok {
+			resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("datacenter"), datacenter)...)
+		} else {
+			resp.Diagnostics.AddWarning(
+				"Datacenter of the imported Virtual Machine not resolved",
+				fmt.Sprintf("Instance type %q is offered in more than one datacenter, the datacenter of instance %s will be taken from the configuration on the next apply.", instanceType, vm.Id),
+			)
+		}
+		if v := findInstanceVariant(catalog, instanceType); v != nil {
+			resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("cost_per_hour"), v.CostPerHour)...)
+		}
+	}
+
+	resp.Diagnostics.Append(resp.Private.SetKey(ctx, importedPrivateStateKey, []byte("true"))...)
+}
+
+// describeTeam names the scope of a team ID in diagnostics.
+func describeTeam(teamID string) string {
+	if teamID == "" {
+		return "the personal account"
+	}
+	return fmt.Sprintf("team %q", teamID)
+}
+
+// datacenterForInstanceType returns the only datacenter the instance type is
+// offered in, the instance list does not report the datacenter of a VM.
+func datacenterForInstanceType(catalog *cloudriftapi.ListInstanceTypesResponseProto, instanceType string) (string, bool) {
+	for _, t := range catalog.Data.InstanceTypes {
+		for _, v := range t.Variants {
+			if v.Name != instanceType || len(v.NodesPerDc) != 1 {
+				continue
+			}
+			for dc := range v.NodesPerDc {
+				return dc, true
+			}
+		}
+	}
+	return "", false
+}
+
+// importedPrivateStateKey marks a Virtual Machine imported but not yet
+// applied, whose write-only inputs are still missing from the state.
+const importedPrivateStateKey = "imported"
+
+// privateState is the subset of the private state data shared by the
+// requests of the different plan modifiers.
+type privateState interface {
+	GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+}
+
+// adoptingImportedInputs reports whether the resource was imported and has not
+// been applied since.
+func adoptingImportedInputs(ctx context.Context, private privateState) bool {
+	if private == nil {
+		return false
+	}
+	imported, diags := private.GetKey(ctx, importedPrivateStateKey)
+	return !diags.HasError() && string(imported) == "true"
+}
+
+// requiresReplaceUnlessAdopted forces replacement on change, except when an
+// imported Virtual Machine takes a value the import could not recover from the
+// configuration.
+func requiresReplaceUnlessAdopted() planmodifier.String {
+	return stringplanmodifier.RequiresReplaceIf(
+		func(ctx context.Context, req planmodifier.StringRequest, resp *stringplanmodifier.RequiresReplaceIfFuncResponse) {
+			resp.RequiresReplace = !(req.StateValue.IsNull() && adoptingImportedInputs(ctx, req.Private))
+		},
+		"Changing the value forces replacement.",
+		"Changing the value forces replacement.",
+	)
 }
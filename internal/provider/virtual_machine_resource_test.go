@@ -1,20 +1,27 @@
 package provider
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
-	"net/http/httptest"
 	"regexp"
-	"sync/atomic"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudrifttest"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
 	"github.com/hashicorp/terraform-plugin-framework/diag"
+	fwresource "github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tftypes"
 	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/knownvalue"
+	"github.com/hashicorp/terraform-plugin-testing/plancheck"
+	"github.com/hashicorp/terraform-plugin-testing/statecheck"
 	"github.com/hashicorp/terraform-plugin-testing/terraform"
+	"github.com/hashicorp/terraform-plugin-testing/tfversion"
 )
 
 func Test_VirtualMachineResource_TeamId(t *testing.T) {
@@ -23,20 +30,7 @@ func Test_VirtualMachineResource_TeamId(t *testing.T) {
 	keyName := "anotheruser-key"
 	publicKey := "ssh-rsa AAAA anotheruser"
 	teamID := "team-123"
-	var capturedTeamID string
-
-	server := newVMTestServer(keyName, publicKey, func(req *http.Request) {
-		body, _ := io.ReadAll(req.Body)
-		var parsed struct {
-			Data struct {
-				TeamID *string `json:"team_id"`
-			} `json:"data"`
-		}
-		_ = json.Unmarshal(body, &parsed)
-		if parsed.Data.TeamID != nil {
-			capturedTeamID = *parsed.Data.TeamID
-		}
-	})
+	server := newTestAPI(t)
 
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
@@ -56,8 +50,14 @@ func Test_VirtualMachineResource_TeamId(t *testing.T) {
 					}
 				`, keyName, publicKey),
 				Check: resource.TestCheckFunc(func(s *terraform.State) error {
-					if capturedTeamID != teamID {
-						return fmt.Errorf("expected team_id %q in rent request, got %q", teamID, capturedTeamID)
+					var rented struct {
+						TeamID *string `json:"team_id"`
+					}
+					if err := server.LastRequest("/api/v1/instances/rent", &rented); err != nil {
+						return err
+					}
+					if rented.TeamID == nil || *rented.TeamID != teamID {
+						return fmt.Errorf("expected team_id %q in rent request, got %v", teamID, rented.TeamID)
 					}
 					return nil
 				}),
@@ -68,7 +68,7 @@ func Test_VirtualMachineResource_TeamId(t *testing.T) {
 
 // Test_VirtualMachineResource_RecipeUrl verifies that a recipe holding a URL
 // sends that URL straight through to the rent request, bypassing the recipe
-// catalog lookup (whose default test image_url is "test").
+// catalog lookup.
 func Test_VirtualMachineResource_RecipeUrl(t *testing.T) {
 	t.Parallel()
 
@@ -76,22 +76,7 @@ func Test_VirtualMachineResource_RecipeUrl(t *testing.T) {
 	publicKey := "ssh-rsa AAAA anotheruser"
 	// Mixed case on purpose: URL paths are case-sensitive, unlike recipe names.
 	customImageURL := "https://example.com/Custom-Image.IMG"
-	var capturedImageURL string
-
-	server := newVMTestServer(keyName, publicKey, func(req *http.Request) {
-		body, _ := io.ReadAll(req.Body)
-		var parsed struct {
-			Data struct {
-				Config struct {
-					VirtualMachine struct {
-						ImageUrl string `json:"image_url"`
-					} `json:"VirtualMachine"`
-				} `json:"config"`
-			} `json:"data"`
-		}
-		_ = json.Unmarshal(body, &parsed)
-		capturedImageURL = parsed.Data.Config.VirtualMachine.ImageUrl
-	})
+	server := newTestAPI(t)
 
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
@@ -111,8 +96,18 @@ func Test_VirtualMachineResource_RecipeUrl(t *testing.T) {
 					}
 				`, keyName, publicKey, customImageURL),
 				Check: resource.TestCheckFunc(func(s *terraform.State) error {
-					if capturedImageURL != customImageURL {
-						return fmt.Errorf("expected image_url %q in rent request, got %q", customImageURL, capturedImageURL)
+					var rented struct {
+						Config struct {
+							VirtualMachine struct {
+								ImageUrl string `json:"image_url"`
+							} `json:"VirtualMachine"`
+						} `json:"config"`
+					}
+					if err := server.LastRequest("/api/v1/instances/rent", &rented); err != nil {
+						return err
+					}
+					if got := rented.Config.VirtualMachine.ImageUrl; got != customImageURL {
+						return fmt.Errorf("expected image_url %q in rent request, got %q", customImageURL, got)
 					}
 					return nil
 				}),
@@ -121,6 +116,64 @@ func Test_VirtualMachineResource_RecipeUrl(t *testing.T) {
 	})
 }
 
+// Test_VirtualMachineResource_ExposedPorts verifies that exposed_ports are
+// sent as the VirtualMachine "ports" of the rent request.
+func Test_VirtualMachineResource_ExposedPorts(t *testing.T) {
+	t.Parallel()
+
+	keyName := "anotheruser-key"
+	publicKey := "ssh-rsa AAAA anotheruser"
+	server := newTestAPI(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig(server.URL, "1.0") + fmt.Sprintf(`
+					resource "cloudrift_ssh_key" "primary" {
+					  name       = "%s"
+					  public_key = "%s"
+					}
+
+					resource "cloudrift_virtual_machine" "machine0" {
+					  recipe        = "ubuntu"
+					  datacenter    = "us-east-nc-nr-1"
+					  instance_type = "rtx49-10c-kn.1"
+					  ssh_key_id    = cloudrift_ssh_key.primary.id
+					  exposed_ports = [22, 8888]
+					}
+				`, keyName, publicKey),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckFunc(func(s *terraform.State) error {
+						var rented struct {
+							Config struct {
+								VirtualMachine struct {
+									Ports []string `json:"ports"`
+								} `json:"VirtualMachine"`
+							} `json:"config"`
+						}
+						if err := server.LastRequest("/api/v1/instances/rent", &rented); err != nil {
+							return err
+						}
+						if got := rented.Config.VirtualMachine.Ports; fmt.Sprint(got) != "[22 8888]" {
+							return fmt.Errorf("expected ports [22 8888] in rent request, got %v", got)
+						}
+						return nil
+					}),
+					// The test instance has a dedicated IP and no port_mappings.
+					resource.TestCheckFunc(func(s *terraform.State) error {
+						attrs := s.RootModule().Resources["cloudrift_virtual_machine.machine0"].Primary.Attributes
+						if want := attrs["public_ip"] + ":8888"; attrs["port_endpoints.8888"] != want {
+							return fmt.Errorf("expected port_endpoints.8888 %q, got %q", want, attrs["port_endpoints.8888"])
+						}
+						return nil
+					}),
+				),
+			},
+		},
+	})
+}
+
 // Test_VirtualMachineResource_RecipeRequired verifies that a missing, empty or
 // whitespace-only recipe is rejected at plan time.
 func Test_VirtualMachineResource_RecipeRequired(t *testing.T) {
@@ -128,7 +181,7 @@ func Test_VirtualMachineResource_RecipeRequired(t *testing.T) {
 
 	keyName := "anotheruser-key"
 	publicKey := "ssh-rsa AAAA anotheruser"
-	server := newVMTestServer(keyName, publicKey, nil)
+	server := newTestAPI(t)
 
 	testCases := []struct {
 		name    string
@@ -175,51 +228,10 @@ func Test_VirtualMachineResource_ToleratesTransientNotFound(t *testing.T) {
 
 	keyName := "anotheruser-key"
 	publicKey := "ssh-rsa AAAA anotheruser"
-
-	activeResponse := `
-	{
-		"data": {
-			"instances": [
-				{
-					"id": "1",
-					"node_id": "1",
-					"node_mode": "Virtual Machine",
-					"node_status": "Ready",
-					"host_address": "127.0.0.1",
-					"virtual_machines": [{"vmid": 100, "name": "vm-1", "ready": true}],
-					"status": "Active"
-				}
-			]
-		}
-	}`
-
-	var listCalls, terminated int32
-	server := defaultHttpTestServer(map[string]func(w http.ResponseWriter, req *http.Request){
-		"/api/v1/instances/list": func(w http.ResponseWriter, _ *http.Request) {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			// After terminate (destroy step), report gone so Delete converges.
-			// Otherwise: first poll not listed yet, subsequent polls Active.
-			if atomic.LoadInt32(&terminated) == 1 || atomic.AddInt32(&listCalls, 1) <= 1 {
-				_, _ = w.Write([]byte(`{"data":{"instances":[]}}`))
-				return
-			}
-			_, _ = w.Write([]byte(activeResponse))
-		},
-		"/api/v1/instances/terminate": func(w http.ResponseWriter, _ *http.Request) {
-			atomic.StoreInt32(&terminated, 1)
-			w.WriteHeader(http.StatusOK)
-		},
-		"/api/v1/instances/rent": func(w http.ResponseWriter, _ *http.Request) {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write([]byte(`{"data":{"instance_ids":["1"]}}`))
-		},
-		"/api/v1/ssh-keys/add":   sshKeyAddHandler(),
-		"/api/v1/ssh-keys/list":  sshKeyListHandlerWithKey(keyName, publicKey),
-		"/api/v1/ssh-keys/11111": sshKeyDeleteHandler(),
-	})
-	defer server.Close()
+	// The first poll of Create comes one polling interval after the rent.
+	server := newTestAPI(t, cloudrifttest.WithTimeline(cloudrifttest.Timeline{
+		Unlisted: InstancePollingInterval + time.Second,
+	}))
 
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
@@ -239,7 +251,7 @@ func Test_VirtualMachineResource_ToleratesTransientNotFound(t *testing.T) {
 					}
 				`, keyName, publicKey),
 				Check: resource.ComposeAggregateTestCheckFunc(
-					resource.TestCheckResourceAttr("cloudrift_virtual_machine.machine0", "id", "1"),
+					resource.TestCheckResourceAttrSet("cloudrift_virtual_machine.machine0", "id"),
 					resource.TestCheckResourceAttr("cloudrift_virtual_machine.machine0", "status", "Active"),
 				),
 			},
@@ -253,20 +265,7 @@ func Test_VirtualMachineResource_Name(t *testing.T) {
 	keyName := "anotheruser-key"
 	publicKey := "ssh-rsa AAAA anotheruser"
 	vmName := "mycluster-a3f2-pool1-01"
-	var capturedName string
-
-	server := newVMTestServer(keyName, publicKey, func(req *http.Request) {
-		body, _ := io.ReadAll(req.Body)
-		var parsed struct {
-			Data struct {
-				Name *string `json:"name"`
-			} `json:"data"`
-		}
-		_ = json.Unmarshal(body, &parsed)
-		if parsed.Data.Name != nil {
-			capturedName = *parsed.Data.Name
-		}
-	})
+	server := newTestAPI(t)
 
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
@@ -287,8 +286,14 @@ func Test_VirtualMachineResource_Name(t *testing.T) {
 					}
 				`, keyName, publicKey, vmName),
 				Check: resource.TestCheckFunc(func(s *terraform.State) error {
-					if capturedName != vmName {
-						return fmt.Errorf("expected name %q in rent request, got %q", vmName, capturedName)
+					var rented struct {
+						Name *string `json:"name"`
+					}
+					if err := server.LastRequest("/api/v1/instances/rent", &rented); err != nil {
+						return err
+					}
+					if rented.Name == nil || *rented.Name != vmName {
+						return fmt.Errorf("expected name %q in rent request, got %v", vmName, rented.Name)
 					}
 					return nil
 				}),
@@ -302,7 +307,7 @@ func Test_VirtualMachineResrouce(t *testing.T) {
 
 	keyName := "anotheruser-key"
 	publicKey := "ssh-rsa AAAA anotheruser"
-	server := newVMTestServer(keyName, publicKey, nil)
+	server := newTestAPI(t)
 
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
@@ -326,6 +331,51 @@ func Test_VirtualMachineResrouce(t *testing.T) {
 	})
 }
 
+// Test_VirtualMachineResource_FakeAPI runs the lifecycle of a Virtual Machine
+// and its SSH key against the stateful fake of the CloudRift API.
+func Test_VirtualMachineResource_FakeAPI(t *testing.T) {
+	t.Parallel()
+
+	server := cloudrifttest.NewServer()
+	defer server.Close()
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		CheckDestroy: func(*terraform.State) error {
+			for _, inst := range server.Instances() {
+				if inst.Status != cloudriftapi.InstanceStatusInactive {
+					return fmt.Errorf("instance %s was left %s", inst.Id, inst.Status)
+				}
+			}
+			if keys := server.SSHKeys(); len(keys) != 0 {
+				return fmt.Errorf("SSH keys were left: %v", keys)
+			}
+			return nil
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfig(server.URL, cloudriftapi.ProtoUpcoming) + fmt.Sprintf(`
+					resource "cloudrift_ssh_key" "primary" {
+					  name       = "primary"
+					  public_key = "ssh-ed25519 AAAA primary"
+					}
+
+					resource "cloudrift_virtual_machine" "machine0" {
+					  recipe        = %q
+					  datacenter    = %q
+					  instance_type = "rtx49-10c-kn.1"
+					  ssh_key_id    = cloudrift_ssh_key.primary.id
+					}
+				`, cloudrifttest.DefaultRecipes[0].Name, cloudrifttest.DefaultDatacenter),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("cloudrift_virtual_machine.machine0", "status", string(cloudriftapi.InstanceStatusActive)),
+					resource.TestCheckResourceAttrSet("cloudrift_virtual_machine.machine0", "public_ip"),
+				),
+			},
+		},
+	})
+}
+
 // Test_VirtualMachineResource_FailsOnTerminalStatus covers every instance
 // status that must abort Create and best-effort terminate the rented VM so it
 // doesn't leak. A freshly rented VM that is listed as Inactive has failed to
@@ -338,17 +388,17 @@ func Test_VirtualMachineResource_FailsOnTerminalStatus(t *testing.T) {
 	publicKey := "ssh-rsa AAAA anotheruser"
 
 	for _, tc := range []struct {
-		status  string
+		status  cloudriftapi.InstanceStatus
 		wantErr string
 	}{
-		{"Inactive", `reached terminal status "Inactive"`},
-		{"Deactivating", `reached terminal status "Deactivating"`},
-		{"Failed", `reached terminal status "Failed"`}, // server 0.59.0+
+		{cloudriftapi.InstanceStatusInactive, `reached terminal status "Inactive"`},
+		{cloudriftapi.InstanceStatusDeactivating, `reached terminal status "Deactivating"`},
+		{cloudriftapi.InstanceStatusFailed, `reached terminal status "Failed"`}, // server 0.59.0+
 	} {
-		t.Run(tc.status, func(t *testing.T) {
+		t.Run(string(tc.status), func(t *testing.T) {
 			t.Parallel()
 
-			server, terminateCalls := newVMTestServerWithStatus(keyName, publicKey, tc.status, false)
+			server := newTestAPI(t, cloudrifttest.WithTimeline(cloudrifttest.Timeline{Outcome: tc.status}))
 
 			resource.Test(t, resource.TestCase{
 				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
@@ -372,152 +422,13 @@ func Test_VirtualMachineResource_FailsOnTerminalStatus(t *testing.T) {
 				},
 			})
 
-			if got := atomic.LoadInt32(terminateCalls); got < 1 {
+			if got := server.Requests("/api/v1/instances/terminate"); got < 1 {
 				t.Fatalf("expected Create to call /instances/terminate at least once to release the failed VM, got %d calls", got)
 			}
 		})
 	}
 }
 
-// newVMTestServerWithStatus creates a test server where the instance reports
-// the given status and VM readiness. After terminate is called, the instance
-// list returns empty so the test framework's destroy cleanup completes.
-// The returned int32 pointer counts how many /instances/terminate calls the
-// server has observed — used to verify best-effort cleanup on failed creates.
-func newVMTestServerWithStatus(keyName, publicKey, status string, vmReady bool) (*httptest.Server, *int32) {
-	var terminateCalls int32
-	var terminated int32 // atomic: written by /terminate, read by /list concurrently
-
-	instanceResponse := fmt.Sprintf(`
-	{
-		"data": {
-			"instances": [
-				{
-					"id": "1",
-					"node_id": "1",
-					"node_mode": "Virtual Machine",
-					"node_status": "Ready",
-					"host_address": "127.0.0.1",
-					"internal_host_address": "10.0.0.1",
-					"resource_info": {
-						"provider_name": "provider",
-						"instance_type": "rtx49-10c-kn.1"
-					},
-					"virtual_machines": [
-						{
-							"vmid": 100,
-							"name": "vm-1",
-							"ready": %v
-						}
-					],
-					"status": "%s"
-				}
-			]
-		}
-	}
-	`, vmReady, status)
-
-	server := defaultHttpTestServer(map[string]func(w http.ResponseWriter, req *http.Request){
-		"/api/v1/instances/terminate": func(w http.ResponseWriter, _ *http.Request) {
-			atomic.AddInt32(&terminateCalls, 1)
-			atomic.StoreInt32(&terminated, 1)
-			w.WriteHeader(http.StatusOK)
-		},
-		"/api/v1/instances/list": func(w http.ResponseWriter, _ *http.Request) {
-			w.Header().Set("Content-Type", "json")
-			w.WriteHeader(http.StatusOK)
-			if atomic.LoadInt32(&terminated) == 1 {
-				_, _ = w.Write([]byte(`{"data": {"instances": []}}`))
-				return
-			}
-			_, _ = w.Write([]byte(instanceResponse))
-		},
-		"/api/v1/instances/rent": func(w http.ResponseWriter, _ *http.Request) {
-			w.Header().Set("Content-Type", "json")
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write([]byte(`
-				{
-					"data": {
-					 	"instance_ids": [
-							"1"
-						]
-					}
-				}
-			`))
-		},
-		"/api/v1/ssh-keys/add":   sshKeyAddHandler(),
-		"/api/v1/ssh-keys/list":  sshKeyListHandlerWithKey(keyName, publicKey),
-		"/api/v1/ssh-keys/11111": sshKeyDeleteHandler(),
-	})
-
-	return server, &terminateCalls
-}
-
-// newVMTestServer creates a test server with instance, SSH key, and rent
-// handlers. The optional onRent callback is invoked on each rent request
-// before the response is written, allowing tests to inspect the request body.
-func newVMTestServer(keyName, publicKey string, onRent func(req *http.Request)) *httptest.Server {
-	status := "Active"
-	instanceResponse := `
-	{
-		"data": {
-			"instances": [
-				{
-					"id": "1",
-					"node_id": "1",
-					"node_mode": "Virtual Machine",
-					"node_status": "Ready",
-					"host_address": "127.0.0.1",
-					"internal_host_address": "10.0.0.1",
-					"resource_info": {
-						"provider_name": "provider",
-						"instance_type": "rtx49-10c-kn.1"
-					},
-					"virtual_machines": [
-						{
-							"vmid": 100,
-							"name": "vm-1",
-							"ready": true
-						}
-					],
-					"status": "%s"
-				}
-			]
-		}
-	}
-	`
-	return defaultHttpTestServer(map[string]func(w http.ResponseWriter, req *http.Request){
-		"/api/v1/instances/terminate": func(w http.ResponseWriter, _ *http.Request) {
-			status = "Inactive"
-			w.WriteHeader(http.StatusOK)
-		},
-		"/api/v1/instances/list": func(w http.ResponseWriter, _ *http.Request) {
-			w.Header().Set("Content-Type", "json")
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write(fmt.Appendf(nil, instanceResponse, status))
-		},
-		"/api/v1/instances/rent": func(w http.ResponseWriter, req *http.Request) {
-			if onRent != nil {
-				onRent(req)
-			}
-			w.Header().Set("Content-Type", "json")
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write([]byte(`
-				{
-					"data": {
-					 	"instance_ids": [
-							"1"
-						]
-					}
-				}
-			`))
-		},
-		"/api/v1/ssh-keys/add":   sshKeyAddHandler(),
-		"/api/v1/ssh-keys/list":  sshKeyListHandlerWithKey(keyName, publicKey),
-		"/api/v1/ssh-keys/11111": sshKeyDeleteHandler(),
-	})
-}
-
 // Test_VirtualMachineResource_DeleteOnStuckDeactivating verifies that Delete
 // treats an instance reporting Deactivating status as destroyed, rather than
 // polling until the 5-minute timeout.
@@ -534,7 +445,9 @@ func Test_VirtualMachineResource_DeleteOnStuckDeactivating(t *testing.T) {
 
 	keyName := "anotheruser-key"
 	publicKey := "ssh-rsa AAAA anotheruser"
-	server := newVMTestServerStuckDeactivating(keyName, publicKey)
+	// The backend acknowledges the terminate request but gets stuck: the
+	// instance flips to Deactivating and never advances to Inactive.
+	server := newTestAPI(t, cloudrifttest.WithTimeline(cloudrifttest.Timeline{Deactivating: -1}))
 
 	resource.Test(t, resource.TestCase{
 		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
@@ -563,74 +476,6 @@ func Test_VirtualMachineResource_DeleteOnStuckDeactivating(t *testing.T) {
 	})
 }
 
-// newVMTestServerStuckDeactivating starts as Active+ready (so Create
-// succeeds), and after the first /instances/terminate call flips the instance
-// to Deactivating and leaves it there indefinitely — never returning empty
-// or Inactive. This simulates the CloudRift backend getting stuck mid-teardown
-// when capacity is tight.
-func newVMTestServerStuckDeactivating(keyName, publicKey string) *httptest.Server {
-	status := "Active"
-	instanceResponse := `
-	{
-		"data": {
-			"instances": [
-				{
-					"id": "1",
-					"node_id": "1",
-					"node_mode": "Virtual Machine",
-					"node_status": "Ready",
-					"host_address": "127.0.0.1",
-					"internal_host_address": "10.0.0.1",
-					"resource_info": {
-						"provider_name": "provider",
-						"instance_type": "rtx49-10c-kn.1"
-					},
-					"virtual_machines": [
-						{
-							"vmid": 100,
-							"name": "vm-1",
-							"ready": true
-						}
-					],
-					"status": "%s"
-				}
-			]
-		}
-	}
-	`
-
-	return defaultHttpTestServer(map[string]func(w http.ResponseWriter, req *http.Request){
-		"/api/v1/instances/terminate": func(w http.ResponseWriter, _ *http.Request) {
-			// Simulate backend acknowledging the terminate request but
-			// getting stuck: status flips to Deactivating and never
-			// advances to Inactive.
-			status = "Deactivating"
-			w.WriteHeader(http.StatusOK)
-		},
-		"/api/v1/instances/list": func(w http.ResponseWriter, _ *http.Request) {
-			w.Header().Set("Content-Type", "json")
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write(fmt.Appendf(nil, instanceResponse, status))
-		},
-		"/api/v1/instances/rent": func(w http.ResponseWriter, _ *http.Request) {
-			w.Header().Set("Content-Type", "json")
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write([]byte(`
-				{
-					"data": {
-						"instance_ids": [
-							"1"
-						]
-					}
-				}
-			`))
-		},
-		"/api/v1/ssh-keys/add":   sshKeyAddHandler(),
-		"/api/v1/ssh-keys/list":  sshKeyListHandlerWithKey(keyName, publicKey),
-		"/api/v1/ssh-keys/11111": sshKeyDeleteHandler(),
-	})
-}
-
 // Test_PopulateModelFromInstanceResponse_NullableFields guards the Plugin
 // Framework contract: a Computed attribute that ends as Unknown after apply
 // triggers "Provider returned invalid result object after apply" in OpenTofu.
@@ -800,3 +645,341 @@ func Test_PopulateModelFromInstanceResponse_LoginInfoVariants(t *testing.T) {
 		})
 	}
 }
+
+// Test_PopulateModelFromInstanceResponse_PortEndpoints covers how
+// port_endpoints is derived for shared-IP (port_mappings) and dedicated-IP
+// (exposed_ports on public_ip) instances.
+func Test_PopulateModelFromInstanceResponse_PortEndpoints(t *testing.T) {
+	t.Parallel()
+
+	publicIP := "203.0.113.10"
+	exposed := types.ListValueMust(types.Int64Type, []attr.Value{types.Int64Value(22), types.Int64Value(8888)})
+
+	tests := []struct {
+		name         string
+		hostAddress  *string
+		portMappings *[][]interface{}
+		exposedPorts types.List
+		want         map[string]string
+	}{
+		{
+			name:         "shared IP uses port_mappings",
+			hostAddress:  &publicIP,
+			portMappings: &[][]interface{}{{float64(40022), float64(22)}, {float64(48888), float64(8888)}},
+			exposedPorts: exposed,
+			want:         map[string]string{"22": "203.0.113.10:40022", "8888": "203.0.113.10:48888"},
+		},
+		{
+			name:         "dedicated IP exposes ports directly",
+			hostAddress:  &publicIP,
+			exposedPorts: exposed,
+			want:         map[string]string{"22": "203.0.113.10:22", "8888": "203.0.113.10:8888"},
+		},
+		{
+			name:         "no public IP yet",
+			exposedPorts: exposed,
+			want:         map[string]string{},
+		},
+		{
+			name:         "no exposed ports",
+			hostAddress:  &publicIP,
+			exposedPorts: types.ListNull(types.Int64Type),
+			want:         map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			m := virtualMachineModel{ExposedPorts: tt.exposedPorts}
+			diags := populateModelFromInstanceResponse(&m, &cloudriftapi.InstanceAndUsageInfo{
+				HostAddress:  tt.hostAddress,
+				PortMappings: tt.portMappings,
+			})
+			for _, d := range diags {
+				if d.Severity() == diag.SeverityError {
+					t.Fatalf("unexpected error diagnostic: %s — %s", d.Summary(), d.Detail())
+				}
+			}
+
+			if m.PortEndpoints.IsNull() || m.PortEndpoints.IsUnknown() {
+				t.Fatalf("port_endpoints must be known after populate, got %v", m.PortEndpoints)
+			}
+			got := make(map[string]string)
+			for k, v := range m.PortEndpoints.Elements() {
+				s, ok := v.(types.String)
+				if !ok {
+					t.Fatalf("port_endpoints[%s]: got %T, want types.String", k, v)
+				}
+				got[k] = s.ValueString()
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("port_endpoints: got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Test_VirtualMachineResource_Import verifies that a Virtual Machine can be
+// imported by ID and by name. The recipe and ssh_key_id cannot be read back
+// from the API and are the only attributes missing after the import.
+func Test_VirtualMachineResource_Import(t *testing.T) {
+	t.Parallel()
+
+	keyName := "anotheruser-key"
+	publicKey := "ssh-rsa AAAA anotheruser"
+	server := newTestAPI(t)
+
+	config := providerConfig(server.URL, "1.0") + fmt.Sprintf(`
+		resource "cloudrift_ssh_key" "primary" {
+		  name       = "%s"
+		  public_key = "%s"
+		}
+
+		resource "cloudrift_virtual_machine" "machine0" {
+		  name          = "vm-one"
+		  recipe        = "ubuntu"
+		  datacenter    = "us-east-nc-nr-1"
+		  instance_type = "rtx49-10c-kn.1"
+		  ssh_key_id    = cloudrift_ssh_key.primary.id
+		}
+	`, keyName, publicKey)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+			},
+			{
+				ResourceName:            "cloudrift_virtual_machine.machine0",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"recipe", "ssh_key_id"},
+			},
+			{
+				ResourceName:            "cloudrift_virtual_machine.machine0",
+				ImportState:             true,
+				ImportStateId:           "vm-one",
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"recipe", "ssh_key_id"},
+			},
+			{
+				ResourceName:  "cloudrift_virtual_machine.machine0",
+				ImportState:   true,
+				ImportStateId: "vm-two",
+				ExpectError:   regexp.MustCompile(`no instance with ID or name "vm-two"`),
+			},
+		},
+	})
+}
+
+// Test_VirtualMachineResource_ImportAdoptsConfiguration verifies that the
+// first apply after an import adopts the recipe and ssh_key_id from the
+// configuration in place, instead of replacing the imported instance.
+func Test_VirtualMachineResource_ImportAdoptsConfiguration(t *testing.T) {
+	t.Parallel()
+
+	publicKey := "ssh-rsa AAAA anotheruser"
+	server := newTestAPI(t, cloudrifttest.WithSSHKeys(cloudriftapi.SshKey{Id: "11111", Name: "anotheruser-key", PublicKey: publicKey}))
+
+	client, err := cloudriftapi.NewCustom(server.URL, "test", "1.0", "")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	rented, err := client.RentPublicInstanceVM(cloudriftapi.RentVMOptions{
+		Recipe:       "ubuntu",
+		Datacenter:   cloudrifttest.DefaultDatacenter,
+		InstanceType: "rtx49-10c-kn.1",
+		Name:         "vm-one",
+		PublicKeys:   []string{publicKey},
+	})
+	if err != nil {
+		t.Fatalf("RentPublicInstanceVM: %v", err)
+	}
+
+	config := providerConfig(server.URL, "1.0") + `
+		resource "cloudrift_virtual_machine" "machine0" {
+		  name          = "vm-one"
+		  recipe        = "ubuntu"
+		  datacenter    = "us-east-nc-nr-1"
+		  instance_type = "rtx49-10c-kn.1"
+		  ssh_key_id    = "11111"
+
+		  user_data = {
+		    shell_script = "echo hello"
+		  }
+		}
+	`
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:             config,
+				ResourceName:       "cloudrift_virtual_machine.machine0",
+				ImportState:        true,
+				ImportStateId:      rented.Data.InstanceIds[0],
+				ImportStatePersist: true,
+			},
+			{
+				Config: config,
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("cloudrift_virtual_machine.machine0", plancheck.ResourceActionUpdate),
+					},
+				},
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("cloudrift_virtual_machine.machine0", "recipe", "ubuntu"),
+					resource.TestCheckResourceAttr("cloudrift_virtual_machine.machine0", "ssh_key_id", "11111"),
+				),
+			},
+			{
+				// Once adopted, a changed recipe replaces the instance again.
+				Config: strings.Replace(config, `"ubuntu"`, `"ubuntu-2"`, 1),
+				ConfigPlanChecks: resource.ConfigPlanChecks{
+					PreApply: []plancheck.PlanCheck{
+						plancheck.ExpectResourceAction("cloudrift_virtual_machine.machine0", plancheck.ResourceActionDestroyBeforeCreate),
+					},
+				},
+			},
+		},
+	})
+}
+
+func Test_DatacenterForInstanceType(t *testing.T) {
+	t.Parallel()
+
+	catalog := &cloudriftapi.ListInstanceTypesResponseProto{}
+	catalog.Data.InstanceTypes = []cloudriftapi.InstanceType{
+		{
+			Name: "rtx49",
+			Variants: []cloudriftapi.InstanceVariantInfo{
+				{Name: "rtx49-10c-kn.1", NodesPerDc: map[string]int32{"dc-1": 2}},
+				{Name: "rtx49-20c-kn.2", NodesPerDc: map[string]int32{"dc-1": 1, "dc-2": 1}},
+			},
+		},
+	}
+
+	tests := []struct {
+		instanceType string
+		want         string
+		wantOK       bool
+	}{
+		{instanceType: "rtx49-10c-kn.1", want: "dc-1", wantOK: true},
+		{instanceType: "rtx49-20c-kn.2", wantOK: false},
+		{instanceType: "unknown", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		got, ok := datacenterForInstanceType(catalog, tt.instanceType)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("datacenterForInstanceType(%q) = (%q, %v), want (%q, %v)", tt.instanceType, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func Test_VirtualMachineResource_Identity(t *testing.T) {
+	t.Parallel()
+
+	keyName := "anotheruser-key"
+	publicKey := "ssh-rsa AAAA anotheruser"
+	server := newTestAPI(t)
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		TerraformVersionChecks: []tfversion.TerraformVersionCheck{
+			tfversion.SkipBelow(tfversion.Version1_12_0),
+		},
+		Steps: []resource.TestStep{
+			{
+				Config: providerConfigWithTeamID(server.URL, "1.0", "team-123") + fmt.Sprintf(`
+					resource "cloudrift_ssh_key" "primary" {
+					  name       = "%s"
+					  public_key = "%s"
+					}
+
+					resource "cloudrift_virtual_machine" "machine0" {
+					  name          = "vm-one"
+					  recipe        = "ubuntu"
+					  datacenter    = "us-east-nc-nr-1"
+					  instance_type = "rtx49-10c-kn.1"
+					  ssh_key_id    = cloudrift_ssh_key.primary.id
+					}
+				`, keyName, publicKey),
+				ConfigStateChecks: []statecheck.StateCheck{
+					statecheck.ExpectIdentity("cloudrift_virtual_machine.machine0", map[string]knownvalue.Check{
+						"id":      knownvalue.NotNull(),
+						"team_id": knownvalue.StringExact("team-123"),
+					}),
+				},
+			},
+			{
+				ResourceName:            "cloudrift_virtual_machine.machine0",
+				ImportState:             true,
+				ImportStateKind:         resource.ImportBlockWithResourceIdentity,
+				ImportStateVerifyIgnore: []string{"recipe", "ssh_key_id"},
+			},
+		},
+	})
+}
+
+// Test_VirtualMachineResource_ImportIdentityTeamMismatch verifies that an
+// identity of another team is rejected instead of reported as missing.
+func Test_VirtualMachineResource_ImportIdentityTeamMismatch(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	server := newTestAPI(t)
+
+	client, err := cloudriftapi.NewCustom(server.URL, "test", "", "team-123")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	r := &virtualMachineResource{client: client}
+
+	var schemaResp fwresource.SchemaResponse
+	r.Schema(ctx, fwresource.SchemaRequest{}, &schemaResp)
+	var identitySchemaResp fwresource.IdentitySchemaResponse
+	r.IdentitySchema(ctx, fwresource.IdentitySchemaRequest{}, &identitySchemaResp)
+	identityType := identitySchemaResp.IdentitySchema.Type().TerraformType(ctx)
+
+	tests := []struct {
+		name    string
+		teamID  tftypes.Value
+		wantErr string
+	}{
+		{name: "other team", teamID: tftypes.NewValue(tftypes.String, "team-456"), wantErr: `team "team-456"`},
+		{name: "personal account", teamID: tftypes.NewValue(tftypes.String, nil), wantErr: "the personal account"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := fwresource.ImportStateRequest{
+				Identity: &tfsdk.ResourceIdentity{
+					Schema: identitySchemaResp.IdentitySchema,
+					Raw: tftypes.NewValue(identityType, map[string]tftypes.Value{
+						"id":      tftypes.NewValue(tftypes.String, "1"),
+						"team_id": tt.teamID,
+					}),
+				},
+			}
+			resp := fwresource.ImportStateResponse{
+				State: tfsdk.State{
+					Schema: schemaResp.Schema,
+					Raw:    tftypes.NewValue(schemaResp.Schema.Type().TerraformType(ctx), nil),
+				},
+				Identity: &tfsdk.ResourceIdentity{
+					Schema: identitySchemaResp.IdentitySchema,
+					Raw:    tftypes.NewValue(identityType, nil),
+				},
+			}
+			r.ImportState(ctx, req, &resp)
+
+			if !resp.Diagnostics.HasError() || !strings.Contains(resp.Diagnostics[0].Detail(), tt.wantErr) {
+				t.Errorf("expected an error mentioning %s, got %v", tt.wantErr, resp.Diagnostics)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/objectplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"gopkg.in/yaml.v3"
+)
+
+// cloudConfigHeader is the first line cloud-init requires to treat user data
+// as cloud-config rather than a script.
+const cloudConfigHeader = "#cloud-config"
+
+type virtualMachineUserDataModel struct {
+	ShellScript  types.String `tfsdk:"shell_script"`
+	CloudConfig  types.String `tfsdk:"cloud_config"`
+	CloudinitURL types.String `tfsdk:"cloudinit_url"`
+}
+
+// startupConfig is the effective first boot configuration of a Virtual
+// Machine, regardless of whether it was given through the deprecated
+// base64 `metadata.startup_commands` or the `user_data` block.
+type startupConfig struct {
+	ShellScript  string
+	CloudConfig  string
+	CloudinitURL string
+}
+
+// newStartupConfig resolves the effective startup configuration. It returns
+// false if any of the inputs is not known yet.
+func newStartupConfig(metadata *virtualMachineMetadataModel, userData *virtualMachineUserDataModel) (startupConfig, bool, error) {
+	var cfg startupConfig
+
+	if metadata != nil {
+		if metadata.StartupCommands.IsUnknown() {
+			return cfg, false, nil
+		}
+		script, err := decodeStartupCommands(metadata.StartupCommands.ValueString())
+		if err != nil {
+			return cfg, true, err
+		}
+		cfg.ShellScript = script
+	}
+
+	if userData != nil {
+		if userData.ShellScript.IsUnknown() || userData.CloudConfig.IsUnknown() || userData.CloudinitURL.IsUnknown() {
+			return cfg, false, nil
+		}
+		if !userData.ShellScript.IsNull() {
+			cfg.ShellScript = strings.TrimSpace(userData.ShellScript.ValueString())
+		}
+		cfg.CloudConfig = userData.CloudConfig.ValueString()
+		cfg.CloudinitURL = userData.CloudinitURL.ValueString()
+	}
+
+	return cfg, true, nil
+}
+
+// cloudInit returns the cloud-init override sent at rent time, empty to keep
+// the recipe's own configuration.
+func (c startupConfig) cloudInit() string {
+	if c.CloudConfig != "" {
+		return c.CloudConfig
+	}
+	return c.CloudinitURL
+}
+
+// decodeStartupCommands decodes the base64 encoded script of the deprecated
+// `metadata.startup_commands` attribute.
+func decodeStartupCommands(commands string) (string, error) {
+	if commands == "" {
+		return "", nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(commands)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode base64 encoded startup commands: %w", err)
+	}
+	return strings.TrimSpace(string(decoded)), nil
+}
+
+// validateCloudConfig checks that the content is a cloud-config document
+// cloud-init will accept: the "#cloud-config" header followed by a YAML mapping.
+func validateCloudConfig(content string) error {
+	firstLine, _, _ := strings.Cut(content, "\n")
+	if strings.TrimSpace(firstLine) != cloudConfigHeader {
+		return fmt.Errorf("the first line must be %q", cloudConfigHeader)
+	}
+
+	var doc map[string]any
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return fmt.Errorf("invalid YAML: %w", err)
+	}
+	return nil
+}
+
+func validateStartupConfig(config virtualMachineModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if config.Metadata != nil && !config.Metadata.StartupCommands.IsUnknown() {
+		if _, err := decodeStartupCommands(config.Metadata.StartupCommands.ValueString()); err != nil {
+			diags.AddAttributeError(
+				path.Root("metadata").AtName("startup_commands"),
+				"Invalid Virtual Machine Configuration",
+				"Attribute \"metadata.startup_commands\" must be base64 encoded, use \"user_data.shell_script\" for a plain text script: "+err.Error(),
+			)
+		}
+	}
+
+	if config.UserData == nil {
+		return diags
+	}
+
+	if config.Metadata != nil && !config.Metadata.StartupCommands.IsNull() && !config.UserData.ShellScript.IsNull() {
+		diags.AddAttributeError(
+			path.Root("user_data").AtName("shell_script"),
+			"Invalid Virtual Machine Configuration",
+			"Attributes \"metadata.startup_commands\" and \"user_data.shell_script\" cannot be set together, move the script to \"user_data.shell_script\".",
+		)
+	}
+
+	if !config.UserData.CloudConfig.IsNull() && !config.UserData.CloudinitURL.IsNull() {
+		diags.AddAttributeError(
+			path.Root("user_data").AtName("cloud_config"),
+			"Invalid Virtual Machine Configuration",
+			"Attributes \"user_data.cloud_config\" and \"user_data.cloudinit_url\" cannot be set together, both replace the recipe's cloud-init configuration.",
+		)
+	}
+
+	if cc := config.UserData.CloudConfig; !cc.IsNull() && !cc.IsUnknown() {
+		if err := validateCloudConfig(cc.ValueString()); err != nil {
+			diags.AddAttributeError(
+				path.Root("user_data").AtName("cloud_config"),
+				"Invalid Virtual Machine Configuration",
+				"Attribute \"user_data.cloud_config\" is not a valid cloud-config document: "+err.Error(),
+			)
+		}
+	}
+
+	return diags
+}
+
+// requiresReplaceIfStartupConfigChanged replaces the Virtual Machine only when
+// the effective startup configuration changes, so that moving a script from
+// the deprecated `metadata` attribute to `user_data` is an in-place update.
+func requiresReplaceIfStartupConfigChanged() planmodifier.Object {
+	return objectplanmodifier.RequiresReplaceIf(
+		func(ctx context.Context, req planmodifier.ObjectRequest, resp *objectplanmodifier.RequiresReplaceIfFuncResponse) {
+			current, known := startupConfigFrom(ctx, req.State.GetAttribute)
+			if known && current == (startupConfig{}) && adoptingImportedInputs(ctx, req.Private) {
+				// The instance list does not return the startup configuration
+				// of an imported Virtual Machine, take it from the configuration.
+				return
+			}
+			if !known {
+				resp.RequiresReplace = true
+				return
+			}
+			planned, known := startupConfigFrom(ctx, req.Plan.GetAttribute)
+			if !known {
+				resp.RequiresReplace = true
+				return
+			}
+			resp.RequiresReplace = planned != current
+		},
+		"Changing the effective startup configuration forces replacement.",
+		"Changing the effective startup configuration forces replacement.",
+	)
+}
+
+func startupConfigFrom(ctx context.Context, get func(context.Context, path.Path, any) diag.Diagnostics) (startupConfig, bool) {
+	var metadata *virtualMachineMetadataModel
+	var userData *virtualMachineUserDataModel
+
+	if diags := get(ctx, path.Root("metadata"), &metadata); diags.HasError() {
+		return startupConfig{}, false
+	}
+	if diags := get(ctx, path.Root("user_data"), &userData); diags.HasError() {
+		return startupConfig{}, false
+	}
+
+	cfg, known, err := newStartupConfig(metadata, userData)
+	return cfg, known && err == nil
+}
+
+// upgradeVirtualMachineStateV0 upgrades a version 0 state, which has no
+// `user_data`. The deprecated `metadata` is kept as it was, so configurations
+// still using it plan no changes; moving the script to
+// `user_data.shell_script` is an in-place update, as the effective startup
+// configuration stays the same.
+func upgradeVirtualMachineStateV0(_ context.Context, req resource.UpgradeStateRequest, resp *resource.UpgradeStateResponse) {
+	// Decode numbers as json.Number so they are written back unchanged.
+	dec := json.NewDecoder(bytes.NewReader(req.RawState.JSON))
+	dec.UseNumber()
+
+	var raw map[string]any
+	if err := dec.Decode(&raw); err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to Upgrade Virtual Machine State",
+			"Could not parse the prior state of the Virtual Machine: "+err.Error(),
+		)
+		return
+	}
+
+	if metadata, ok := raw["metadata"].(map[string]any); ok {
+		if commands, ok := metadata["startup_commands"].(string); ok {
+			if _, err := decodeStartupCommands(commands); err != nil {
+				resp.Diagnostics.AddError(
+					"Unable to Upgrade Virtual Machine State",
+					"The \"metadata.startup_commands\" of the prior state are invalid: "+err.Error(),
+				)
+				return
+			}
+		}
+	}
+	raw["user_data"] = nil
+
+	upgraded, err := json.Marshal(raw)
+	if err != nil {
+		resp.Diagnostics.AddError(
+			"Unable to Upgrade Virtual Machine State",
+			"Could not encode the upgraded state of the Virtual Machine: "+err.Error(),
+		)
+		return
+	}
+
+	resp.DynamicValue = &tfprotov6.DynamicValue{JSON: upgraded}
+}
@@ -0,0 +1,206 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	tfresource "github.com/hashicorp/terraform-plugin-testing/helper/resource"
+	"github.com/hashicorp/terraform-plugin-testing/terraform"
+)
+
+// Test_VirtualMachineResource_UserData verifies that user_data.shell_script is
+// sent as plain text and user_data.cloudinit_url overrides the recipe's one.
+func Test_VirtualMachineResource_UserData(t *testing.T) {
+	t.Parallel()
+
+	keyName := "anotheruser-key"
+	publicKey := "ssh-rsa AAAA anotheruser"
+	server := newTestAPI(t)
+
+	tfresource.Test(t, tfresource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+		Steps: []tfresource.TestStep{
+			{
+				Config: providerConfig(server.URL, "1.0") + fmt.Sprintf(`
+					resource "cloudrift_ssh_key" "primary" {
+					  name       = "%s"
+					  public_key = "%s"
+					}
+
+					resource "cloudrift_virtual_machine" "machine0" {
+					  recipe        = "ubuntu"
+					  datacenter    = "us-east-nc-nr-1"
+					  instance_type = "rtx49-10c-kn.1"
+					  ssh_key_id    = cloudrift_ssh_key.primary.id
+
+					  user_data = {
+					    shell_script  = "#!/bin/bash\necho hello > /tmp/hello\n"
+					    cloudinit_url = "https://example.com/custom.cloudinit"
+					  }
+					}
+				`, keyName, publicKey),
+				Check: tfresource.TestCheckFunc(func(s *terraform.State) error {
+					var rented struct {
+						Config struct {
+							VirtualMachine struct {
+								CloudinitCommands string `json:"cloudinit_commands"`
+								CloudinitUrl      string `json:"cloudinit_url"`
+							} `json:"VirtualMachine"`
+						} `json:"config"`
+					}
+					if err := server.LastRequest("/api/v1/instances/rent", &rented); err != nil {
+						return err
+					}
+					if got := rented.Config.VirtualMachine.CloudinitCommands; got != "#!/bin/bash\necho hello > /tmp/hello" {
+						return fmt.Errorf("expected plain text cloudinit_commands in rent request, got %q", got)
+					}
+					if got := rented.Config.VirtualMachine.CloudinitUrl; got != "https://example.com/custom.cloudinit" {
+						return fmt.Errorf("expected overridden cloudinit_url in rent request, got %q", got)
+					}
+					return nil
+				}),
+			},
+		},
+	})
+}
+
+// Test_VirtualMachineResource_UserDataInvalid verifies that broken startup
+// configurations are rejected at plan time.
+func Test_VirtualMachineResource_UserDataInvalid(t *testing.T) {
+	t.Parallel()
+
+	server := newTestAPI(t)
+
+	testCases := []struct {
+		name    string
+		extra   string
+		errorRe string
+	}{
+		{
+			name:    "cloud_config without header",
+			extra:   `user_data = { cloud_config = "packages: [htop]" }`,
+			errorRe: `(?s)not a valid cloud-config.*#cloud-config`,
+		},
+		{
+			name:    "cloud_config invalid yaml",
+			extra:   `user_data = { cloud_config = "#cloud-config\npackages: [htop" }`,
+			errorRe: `(?s)not a valid cloud-config.*invalid YAML`,
+		},
+		{
+			name:    "cloud_config with cloudinit_url",
+			extra:   `user_data = { cloud_config = "#cloud-config\n", cloudinit_url = "https://example.com/x" }`,
+			errorRe: `cannot be set together`,
+		},
+		{
+			name:    "startup_commands not base64",
+			extra:   `metadata = { startup_commands = "echo hello" }`,
+			errorRe: `must be base64 encoded`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tfresource.Test(t, tfresource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactories,
+				Steps: []tfresource.TestStep{
+					{
+						Config: providerConfig(server.URL, "1.0") + fmt.Sprintf(`
+							resource "cloudrift_virtual_machine" "machine0" {
+							  recipe        = "ubuntu"
+							  datacenter    = "us-east-nc-nr-1"
+							  instance_type = "rtx49-10c-kn.1"
+							  ssh_key_id    = "11111"
+							  %s
+							}
+						`, tc.extra),
+						ExpectError: regexp.MustCompile(tc.errorRe),
+					},
+				},
+			})
+		})
+	}
+}
+
+func Test_ValidateCloudConfig(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]bool{
+		"#cloud-config\npackages:\n  - htop\n": true,
+		"#cloud-config\r\nruncmd: [ls]\n":      true,
+		"#cloud-config\n":                      true,
+		"packages:\n  - htop\n":                false,
+		"#!/bin/bash\necho hello\n":            false,
+		"#cloud-config\npackages: [htop\n":     false,
+	}
+
+	for input, valid := range cases {
+		if err := validateCloudConfig(input); (err == nil) != valid {
+			t.Errorf("validateCloudConfig(%q) = %v, want valid=%v", input, err, valid)
+		}
+	}
+}
+
+// Test_UpgradeVirtualMachineStateV0 verifies that the base64 encoded
+// metadata.startup_commands of a version 0 state are kept, with every other
+// attribute left untouched and no user_data.
+func Test_UpgradeVirtualMachineStateV0(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		prior     string
+		wantState map[string]any
+	}{
+		{
+			name:  "base64 startup_commands",
+			prior: `{"id":"1","metadata":{"startup_commands":"IyEvYmluL2Jhc2gKZWNobyBoZWxsbwo="},"virtual_machines":[{"vmid":9007199254740993}]}`,
+			wantState: map[string]any{
+				"id":               "1",
+				"metadata":         map[string]any{"startup_commands": "IyEvYmluL2Jhc2gKZWNobyBoZWxsbwo="},
+				"user_data":        nil,
+				"virtual_machines": []any{map[string]any{"vmid": json.Number("9007199254740993")}},
+			},
+		},
+		{
+			name:  "no metadata",
+			prior: `{"id":"1","metadata":null}`,
+			wantState: map[string]any{
+				"id":        "1",
+				"metadata":  nil,
+				"user_data": nil,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := resource.UpgradeStateRequest{RawState: &tfprotov6.RawState{JSON: []byte(tt.prior)}}
+			var resp resource.UpgradeStateResponse
+			upgradeVirtualMachineStateV0(context.Background(), req, &resp)
+			if resp.Diagnostics.HasError() {
+				t.Fatalf("unexpected error diagnostics: %v", resp.Diagnostics)
+			}
+			if resp.DynamicValue == nil {
+				t.Fatal("expected the upgraded state to be set")
+			}
+
+			var got map[string]any
+			dec := json.NewDecoder(bytes.NewReader(resp.DynamicValue.JSON))
+			dec.UseNumber()
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("upgraded state is not valid JSON: %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.wantState) {
+				t.Errorf("upgraded state: got %v, want %v", got, tt.wantState)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"context"
+
+	"github.com/hashicorp/terraform-plugin-framework/function"
+)
+
+var _ function.Function = &vramGiBFunction{}
+
+type vramGiBFunction struct{}
+
+func NewVRAMGiBFunction() function.Function {
+	return &vramGiBFunction{}
+}
+
+func (f *vramGiBFunction) Metadata(_ context.Context, _ function.MetadataRequest, resp *function.MetadataResponse) {
+	resp.Name = "vram_gib"
+}
+
+func (f *vramGiBFunction) Definition(_ context.Context, _ function.DefinitionRequest, resp *function.DefinitionResponse) {
+	resp.Definition = function.Definition{
+		Summary:             "Convert bytes to GiB",
+		MarkdownDescription: "Converts a size in bytes, such as the `vram` of the `cloudrift_instance_types` data source, to GiB.",
+		Parameters: []function.Parameter{
+			function.Int64Parameter{
+				Name:                "bytes",
+				MarkdownDescription: "Size in bytes.",
+			},
+		},
+		Return: function.Float64Return{},
+	}
+}
+
+func (f *vramGiBFunction) Run(ctx context.Context, req function.RunRequest, resp *function.RunResponse) {
+	var bytes int64
+
+	resp.Error = function.ConcatFuncErrors(req.Arguments.Get(ctx, &bytes))
+	if resp.Error != nil {
+		return
+	}
+
+	if bytes < 0 {
+		resp.Error = function.NewArgumentFuncError(0, "bytes must not be negative")
+		return
+	}
+
+	resp.Error = function.ConcatFuncErrors(resp.Result.Set(ctx, float64(bytes)/(1<<30)))
+}
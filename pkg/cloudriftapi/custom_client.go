@@ -2,7 +2,8 @@ package cloudriftapi
 
 import (
 	"bytes"
-	"encoding/base64"
+	"context"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"net/http"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -20,6 +22,14 @@ import (
 // an empty response/Inactive resource.
 var ErrNotFound = errors.New("resource not found")
 
+// ErrCredentialsForbidden is returned by GetInstanceCredentials when the
+// API key lacks the ViewInstanceCredentials permission.
+var ErrCredentialsForbidden = errors.New("the API key lacks the ViewInstanceCredentials permission")
+
+// ErrUnsupportedVersion is returned when the CloudRift server rejects the
+// protocol version of a request.
+var ErrUnsupportedVersion = errors.New("unsupported protocol version")
+
 const (
 	Endpoint = "https://api.cloudrift.ai"
 )
@@ -58,6 +68,62 @@ func WithTimeout(d time.Duration) HttpClientOption {
 	}
 }
 
+// DefaultUserAgent is the User-Agent of the client unless set with
+// WithUserAgent.
+const DefaultUserAgent = "terraform-provider-cloudrift"
+
+// WithUserAgent overrides the User-Agent header of the requests. An empty
+// string is ignored, leaving DefaultUserAgent in place.
+func WithUserAgent(ua string) HttpClientOption {
+	return func(hc *HttpClient) {
+		if ua != "" {
+			hc.userAgent = ua
+		}
+	}
+}
+
+// RequestIDHeader is the header carrying the ID generated for every request,
+// quote it when reporting an issue to CloudRift support.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestError is an error of a request to the CloudRift API, annotated with
+// the ID the request was sent with.
+type RequestError struct {
+	RequestID string
+	Err       error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%s (%s: %s)", e.Err, RequestIDHeader, e.RequestID)
+}
+
+func (e *RequestError) Unwrap() error { return e.Err }
+
+// withRequestID annotates err with the request ID of req, once.
+func withRequestID(req *http.Request, err error) error {
+	if err == nil {
+		return nil
+	}
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		return err
+	}
+	id := req.Header.Get(RequestIDHeader)
+	if id == "" {
+		return err
+	}
+	return &RequestError{RequestID: id, Err: err}
+}
+
+type retryAttemptKey struct{}
+
+// RetryAttempt returns the retry attempt of the request sent by
+// DoRequestWithApiToken, 0 for the first try.
+func RetryAttempt(req *http.Request) int {
+	attempt, _ := req.Context().Value(retryAttemptKey{}).(int)
+	return attempt
+}
+
 type AuthData struct {
 	Token string
 }
@@ -70,11 +136,37 @@ type HttpClient struct {
 	ProtoVersion string
 	TeamID       string // Optional team ID for team-scoped operations.
 
+	capabilities *CapabilitiesResponseProto
+
+	// ctx is the context of the requests, set with WithContext. The caches
+	// and the poller are shared with the clients it returns.
+	ctx context.Context
+
+	recipesMu  *sync.RWMutex
 	vmRecipies map[string]*RecipeDetails1
+
+	pollInterval time.Duration
+	poller       *instancePoller
+
+	userAgent string
+
+	// transport is configured by the options, wrapTransport wraps it once
+	// they are applied. Errors of the options are returned by NewCustom.
+	transport     *http.Transport
+	wrapTransport func(http.RoundTripper) http.RoundTripper
+	optionErrs    []error
 }
 
 func NewCustom(endpoint, token, protoVersion, teamID string, opts ...HttpClientOption) (*HttpClient, error) {
+	return NewCustomWithContext(context.Background(), endpoint, token, protoVersion, teamID, opts...)
+}
+
+// NewCustomWithContext is NewCustom sending the requests of the setup, the
+// authentication, the protocol negotiation and the recipe listing, with ctx.
+// The returned client does not keep ctx, see WithContext.
+func NewCustomWithContext(ctx context.Context, endpoint, token, protoVersion, teamID string, opts ...HttpClientOption) (*HttpClient, error) {
 	c := HttpClient{
+		ctx:     ctx,
 		HostURL: endpoint,
 		auth:    AuthData{Token: token},
 		retries: 0,
@@ -83,13 +175,27 @@ func NewCustom(endpoint, token, protoVersion, teamID string, opts ...HttpClientO
 		},
 		ProtoVersion: protoVersion,
 		TeamID:       teamID,
+		recipesMu:    &sync.RWMutex{},
 		vmRecipies:   make(map[string]*RecipeDetails1),
+		pollInterval: DefaultPollInterval,
+		poller:       &instancePoller{},
+		userAgent:    DefaultUserAgent,
+		transport:    http.DefaultTransport.(*http.Transport).Clone(),
 	}
 
 	for _, o := range opts {
 		o(&c)
 	}
 
+	if err := errors.Join(c.optionErrs...); err != nil {
+		return nil, fmt.Errorf("invalid client configuration: %w", err)
+	}
+
+	c.HTTPClient.Transport = c.transport
+	if c.wrapTransport != nil {
+		c.HTTPClient.Transport = c.wrapTransport(c.transport)
+	}
+
 	if endpoint == "" {
 		c.HostURL = Endpoint
 	}
@@ -99,18 +205,14 @@ func NewCustom(endpoint, token, protoVersion, teamID string, opts ...HttpClientO
 		c.HostURL += "/"
 	}
 
-	// Default to ~upcoming. As of API v061 every dated version is rejected
-	// by /instances/list ("unsupported version"); ~upcoming is the only one
-	// the server accepts. Pin to a dated version again once one supports the
-	// ByStatus+scope selector and the connection-info mask.
-	if protoVersion == "" {
-		c.ProtoVersion = ProtoUpcoming
-	}
-
 	if err := c.Auth(); err != nil {
 		return nil, fmt.Errorf("failed to authenticated: %w", err)
 	}
 
+	if err := c.negotiateProtoVersion(protoVersion); err != nil {
+		return nil, fmt.Errorf("failed to negotiate the protocol version: %w", err)
+	}
+
 	if err := c.refreshVMRecipeCache(); err != nil {
 		return nil, fmt.Errorf("failed to refresh recipes cache: %w", err)
 	}
@@ -119,9 +221,27 @@ func NewCustom(endpoint, token, protoVersion, teamID string, opts ...HttpClientO
 		return nil, errors.New("no recipes for VMs found")
 	}
 
+	c.ctx = nil
 	return &c, nil
 }
 
+// WithContext returns a client sending its requests with ctx, e.g. to log
+// them along with the Terraform operation they belong to and cancel them with
+// it. The returned client shares the caches and the instance poller of c.
+func (c *HttpClient) WithContext(ctx context.Context) *HttpClient {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// context returns the context of the requests of the client.
+func (c *HttpClient) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 func (c *HttpClient) refreshVMRecipeCache() error {
 	recipes, err := c.ListRecipes()
 	if err != nil {
@@ -132,6 +252,9 @@ func (c *HttpClient) refreshVMRecipeCache() error {
 		return fmt.Errorf("invalid JSON response")
 	}
 
+	c.recipesMu.Lock()
+	defer c.recipesMu.Unlock()
+
 	for _, group := range recipes.Data.Groups {
 		for _, r := range group.Recipes {
 			var vmDetails RecipeDetails1
@@ -150,13 +273,19 @@ func (c *HttpClient) refreshVMRecipeCache() error {
 	return nil
 }
 
+func (c *HttpClient) cachedVMRecipe(recipe string) *RecipeDetails1 {
+	c.recipesMu.RLock()
+	defer c.recipesMu.RUnlock()
+	return c.vmRecipies[recipe]
+}
+
 func (c *HttpClient) findVMRecipe(recipe string) (*RecipeDetails1, error) {
-	found := c.vmRecipies[recipe]
+	found := c.cachedVMRecipe(recipe)
 	if found == nil {
 		if err := c.refreshVMRecipeCache(); err != nil {
 			return nil, err
 		}
-		found = c.vmRecipies[recipe]
+		found = c.cachedVMRecipe(recipe)
 	}
 
 	if found == nil {
@@ -166,6 +295,32 @@ func (c *HttpClient) findVMRecipe(recipe string) (*RecipeDetails1, error) {
 	return found, nil
 }
 
+// HasVMRecipe reports whether the recipe catalog has a Virtual Machine recipe
+// with the given name, the cache is refreshed once on a miss.
+func (c *HttpClient) HasVMRecipe(recipe string) (bool, error) {
+	recipe = strings.ToLower(strings.TrimSpace(recipe))
+	if c.cachedVMRecipe(recipe) != nil {
+		return true, nil
+	}
+	if err := c.refreshVMRecipeCache(); err != nil {
+		return false, err
+	}
+	return c.cachedVMRecipe(recipe) != nil, nil
+}
+
+// VMRecipeNames returns the sorted names of the cached Virtual Machine recipes.
+func (c *HttpClient) VMRecipeNames() []string {
+	c.recipesMu.RLock()
+	defer c.recipesMu.RUnlock()
+
+	names := make([]string, 0, len(c.vmRecipies))
+	for name := range c.vmRecipies {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
 func (c *HttpClient) Auth() error {
 	req, err := http.NewRequest(http.MethodPost, c.HostURL+"api/v1/auth/me", nil)
 	if err != nil {
@@ -193,7 +348,7 @@ func (c *HttpClient) Auth() error {
 	}
 
 	if resp.Data.Email == "" {
-		return errors.New("invalid api token")
+		return withRequestID(req, errors.New("invalid api token"))
 	}
 	return nil
 }
@@ -209,16 +364,29 @@ func (c *HttpClient) ListRecipes() (*ListRecipesResponseProto, error) {
 		return nil, err
 	}
 	if resp == nil || resp.JSON200 == nil {
-		return nil, errors.New(
+		return nil, withRequestID(req, errors.New(
 			"listing recipes failed, expected response with code 200 which was not returned, but no error occurred with the request itself, most likely the API changed or the response has a missing 'Content-Type' for json",
-		)
+		))
 	}
 	return resp.JSON200, nil
 }
 
+// DoRequestWithApiToken sends the request authenticated with the API token,
+// tagged with the User-Agent of the client and a fresh X-Request-ID. Errors
+// carry the request ID, see RequestError.
 func DoRequestWithApiToken[Parsed any](c *HttpClient, req *http.Request, parse func(resp *http.Response) (*Parsed, error)) (*Parsed, error) {
 	req.Header.Add("X-API-KEY", c.auth.Token)
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set(RequestIDHeader, rand.Text())
+
+	parsed, err := doRequest(c, req, parse)
+	return parsed, withRequestID(req, err)
+}
 
+func doRequest[Parsed any](c *HttpClient, req *http.Request, parse func(resp *http.Response) (*Parsed, error)) (*Parsed, error) {
+	if c.ctx != nil {
+		req = req.WithContext(c.ctx)
+	}
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
 		// perform retries, if the client was configured as retryable.
@@ -236,6 +404,7 @@ func DoRequestWithApiToken[Parsed any](c *HttpClient, req *http.Request, parse f
 			}
 			time.Sleep(backoff)
 			backoff = backoff << 1
+			req = req.WithContext(context.WithValue(req.Context(), retryAttemptKey{}, c.retries-retries+1))
 			resp, err = c.HTTPClient.Do(req)
 			if err == nil {
 				break
@@ -258,6 +427,11 @@ func DoRequestWithApiToken[Parsed any](c *HttpClient, req *http.Request, parse f
 		if err != nil {
 			return nil, err
 		}
+		// The API has no error code for it, a rejected version is a bad
+		// request whose message names it.
+		if resp.StatusCode == http.StatusBadRequest && strings.Contains(strings.ToLower(string(body)), "unsupported version") {
+			return nil, fmt.Errorf("request %s failed: %s: body: %s: %w", req.URL.String(), resp.Status, string(body), ErrUnsupportedVersion)
+		}
 		return nil, fmt.Errorf("request %s failed: %s: body: %s", req.URL.String(), resp.Status, string(body))
 	}
 
@@ -283,9 +457,9 @@ func (c *HttpClient) AddSSHKey(name, publicKey string) (*GenerateSshKeyResponseP
 		return nil, wrapSSHKeyAuthError(err)
 	}
 	if resp == nil || resp.JSON201 == nil {
-		return nil, errors.New(
+		return nil, withRequestID(req, errors.New(
 			"adding ssh-key failed, expected response with code 201 which was not returned, but no error occurred with the request itself, most likely the API changed, or the response has a missing 'Content-Type' for json",
-		)
+		))
 	}
 	return resp.JSON201, nil
 }
@@ -311,26 +485,33 @@ func (c *HttpClient) ListSSHKeys() ([]SshKey, error) {
 		return nil, wrapSSHKeyAuthError(err)
 	}
 	if resp == nil || resp.JSON200 == nil {
-		return nil, errors.New(
+		return nil, withRequestID(req, errors.New(
 			"listing ssh-keys failed, expected response with code 200 which was not returned, but no error occurred with the request itself, most likely the API changed, or the response has a missing 'Content-Type' for json",
-		)
+		))
 	}
 	return resp.JSON200.Data.Keys, nil
 }
 
 func (c *HttpClient) TerminateInstance(id string) error {
-	id = strings.TrimSpace(id)
-	if id == "" {
+	return c.TerminateInstances([]string{id})
+}
+
+// TerminateInstances terminates the instances with the IDs.
+func (c *HttpClient) TerminateInstances(ids []string) error {
+	if len(ids) == 0 || slices.ContainsFunc(ids, func(id string) bool { return strings.TrimSpace(id) == "" }) {
 		return errors.New("empty instance id")
 	}
 
 	var selector InstancesSelector
 	// Always terminate by specific instance ID to avoid accidentally
 	// terminating other instances.
-	if err := selector.FromInstancesSelector0(InstancesSelector0{ById: []string{id}}); err != nil {
+	if err := selector.FromInstancesSelector0(InstancesSelector0{ById: ids}); err != nil {
 		return err
 	}
+	return c.terminateInstances(selector)
+}
 
+func (c *HttpClient) terminateInstances(selector InstancesSelector) error {
 	body, err := marshalVersionedRequest(c.ProtoVersion, struct {
 		Selector InstancesSelector `json:"selector"`
 	}{Selector: selector})
@@ -347,45 +528,61 @@ func (c *HttpClient) TerminateInstance(id string) error {
 	return err
 }
 
-// isImageURL reports whether a recipe value is a direct image URL rather than
+// IsImageURL reports whether a recipe value is a direct image URL rather than
 // a name from the recipe catalog. URI schemes are case-insensitive, so the
 // scheme is matched that way while the rest of the URL is left untouched.
-func isImageURL(recipe string) bool {
+func IsImageURL(recipe string) bool {
 	lower := strings.ToLower(recipe)
 	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
 }
 
-func (c *HttpClient) RentPublicInstanceVM(recipe, datacenter, instance, commands, name string, pubKeys []string) (*RentInstanceResponseProto, error) {
-	recipe = strings.TrimSpace(recipe)
+// RentVMOptions describes a single public VM rental.
+type RentVMOptions struct {
+	// Recipe is either a name from the recipe catalog or a direct image URL.
+	Recipe       string
+	Datacenter   string
+	InstanceType string
+	// Name is optional, shown in the CloudRift dashboard.
+	Name string
+	// PublicKeys authorized to SSH into the VM, at least one is required.
+	PublicKeys []string
+	// Ports to expose on the VM, left to the platform defaults when empty.
+	Ports []string
+	// StartupCommands is a plain text script run after the first boot.
+	StartupCommands string
+	// CloudInit overrides the recipe's cloud-init configuration. The API
+	// field takes either the URL of a cloud-init file or its content.
+	CloudInit string
+	// ReuseEnvironmentID boots the VM from the disk of a saved environment
+	// instead of a fresh OS, the rental is pinned to NodeID which must be the
+	// node of the environment.
+	ReuseEnvironmentID string
+	NodeID             string
+	// ClusterName groups the VM with the other instances of the cluster.
+	ClusterName string
+}
+
+func (c *HttpClient) RentPublicInstanceVM(opts RentVMOptions) (*RentInstanceResponseProto, error) {
+	recipe := strings.TrimSpace(opts.Recipe)
 	if recipe == "" {
 		return nil, errors.New("empty recipe")
 	}
-	if len(pubKeys) == 0 || slices.Contains(pubKeys, "") {
+	if len(opts.PublicKeys) == 0 || slices.Contains(opts.PublicKeys, "") {
 		return nil, errors.New("no ssh key specified")
 	}
-	if datacenter == "" {
+	if opts.Datacenter == "" {
 		return nil, errors.New("empty datacenter")
 	}
-	if instance == "" {
+	if opts.InstanceType == "" {
 		return nil, errors.New("empty instance")
 	}
-	if commands != "" {
-		raw := []byte(commands)
-		l := base64.StdEncoding.DecodedLen(len(raw))
-		dst := make([]byte, l)
-		written, err := base64.StdEncoding.Decode(dst, raw)
-		if err != nil {
-			return nil, fmt.Errorf("failed to decode base64 encoded startup commands: %w", err)
-		}
-		commands = string(dst[:written])
-		commands = strings.TrimSpace(commands)
-	}
+	commands := strings.TrimSpace(opts.StartupCommands)
 
 	var vmConfig InstanceConfiguration1
 	// A recipe is either a catalog name ("ubuntu") or a direct image URL. URLs
 	// go straight through without a catalog lookup, and are not lowercased
 	// since URL paths are case-sensitive.
-	if isImageURL(recipe) {
+	if IsImageURL(recipe) {
 		vmConfig.VirtualMachine.ImageUrl = recipe
 	} else {
 		recipe = strings.ToLower(recipe)
@@ -396,14 +593,22 @@ func (c *HttpClient) RentPublicInstanceVM(recipe, datacenter, instance, commands
 		vmConfig.VirtualMachine.CloudinitUrl = &details.VirtualMachine.CloudinitUrl
 		vmConfig.VirtualMachine.ImageUrl = details.VirtualMachine.ImageUrl
 	}
+	if opts.CloudInit != "" {
+		vmConfig.VirtualMachine.CloudinitUrl = &opts.CloudInit
+	}
 
 	var keySelector InstanceSshKeySelector
-	if err := keySelector.FromInstanceSshKeySelector1(InstanceSshKeySelector1{PublicKeys: pubKeys}); err != nil {
+	if err := keySelector.FromInstanceSshKeySelector1(InstanceSshKeySelector1{PublicKeys: opts.PublicKeys}); err != nil {
 		return nil, err
 	}
 
 	vmConfig.VirtualMachine.CloudinitCommands = &commands
 	vmConfig.VirtualMachine.SshKey = &keySelector
+	// Ports are only sent when requested, leaving the platform defaults (and
+	// thus the port_mappings it picks on shared-IP datacenters) untouched otherwise.
+	if len(opts.Ports) > 0 {
+		vmConfig.VirtualMachine.Ports = &opts.Ports
+	}
 
 	buf := new(bytes.Buffer)
 	enc := json.NewEncoder(buf)
@@ -419,21 +624,35 @@ func (c *HttpClient) RentPublicInstanceVM(recipe, datacenter, instance, commands
 		union: json.RawMessage(bytes.Clone(buf.Bytes())),
 	}
 
-	var nodeSelector NodeSelector0
-	nodeSelector.ByInstanceTypeAndLocation.Datacenters = &[]string{datacenter}
-	nodeSelector.ByInstanceTypeAndLocation.InstanceType = instance
-
 	var instanceSelector NodeSelector
-	if err := instanceSelector.FromNodeSelector0(nodeSelector); err != nil {
-		return nil, err
+	if opts.NodeID != "" {
+		var nodeSelector NodeSelector1
+		nodeSelector.ByNodeId.NodeId = opts.NodeID
+		nodeSelector.ByNodeId.InstanceType = opts.InstanceType
+		if err := instanceSelector.FromNodeSelector1(nodeSelector); err != nil {
+			return nil, err
+		}
+	} else {
+		var nodeSelector NodeSelector0
+		nodeSelector.ByInstanceTypeAndLocation.Datacenters = &[]string{opts.Datacenter}
+		nodeSelector.ByInstanceTypeAndLocation.InstanceType = opts.InstanceType
+		if err := instanceSelector.FromNodeSelector0(nodeSelector); err != nil {
+			return nil, err
+		}
 	}
 
 	var reqData RentInstanceRequestProto
 	reqData.Data.Selector = instanceSelector
 	reqData.Data.WithPublicIp = true
 	reqData.Data.Config = instanceConfiguration
-	if name != "" {
-		reqData.Data.Name = &name
+	if opts.Name != "" {
+		reqData.Data.Name = &opts.Name
+	}
+	if opts.ReuseEnvironmentID != "" {
+		reqData.Data.ReuseEnvironmentId = &opts.ReuseEnvironmentID
+	}
+	if opts.ClusterName != "" {
+		reqData.Data.ClusterName = &opts.ClusterName
 	}
 	if c.TeamID != "" {
 		reqData.Data.TeamId = &c.TeamID
@@ -463,9 +682,9 @@ func (c *HttpClient) RentPublicInstanceVM(recipe, datacenter, instance, commands
 	}
 
 	if resp == nil || resp.JSON200 == nil {
-		return nil, errors.New(
+		return nil, withRequestID(req, errors.New(
 			"renting instance failed, expected response with code 200 which was not returned, but no error occurred with the request itself, most likely the API changed, or the response has a missing 'Content-Type' for json",
-		)
+		))
 	}
 
 	return resp.JSON200, nil
@@ -478,12 +697,16 @@ func (c *HttpClient) listInstances(selector InstancesSelector) (*ListInstancesRe
 	// not request with_credentials: the provider stores no password, and asking
 	// for it without ViewInstanceCredentials is a 403.
 	withConnectionInfo := true
+	return c.listInstancesWithMask(selector, InstanceInfoFlags{WithConnectionInfo: &withConnectionInfo})
+}
+
+func (c *HttpClient) listInstancesWithMask(selector InstancesSelector, mask InstanceInfoFlags) (*ListInstancesResponseProto, error) {
 	body, err := marshalVersionedRequest(c.ProtoVersion, struct {
 		Selector InstancesSelector  `json:"selector"`
 		Mask     *InstanceInfoFlags `json:"mask,omitempty"`
 	}{
 		Selector: selector,
-		Mask:     &InstanceInfoFlags{WithConnectionInfo: &withConnectionInfo},
+		Mask:     &mask,
 	})
 	if err != nil {
 		return nil, err
@@ -507,43 +730,55 @@ func (c *HttpClient) doListInstances(body io.Reader) (*ListInstancesResponseProt
 	}
 
 	if resp == nil || resp.JSON200 == nil {
-		return nil, errors.New(
+		return nil, withRequestID(req, errors.New(
 			"listing instances failed, expected response with code 200 which was not returned, but no error occurred with the request itself, most likely the API changed, or the response has a missing 'Content-Type' for json",
-		)
+		))
 	}
 
 	return resp.JSON200, nil
 }
 
+// DefaultListedInstanceStatuses are the statuses ListInstances selects, every
+// instance that is not yet, or no longer, terminated.
+var DefaultListedInstanceStatuses = []InstanceStatus{
+	InstanceStatusActive,
+	InstanceStatusInitializing,
+	InstanceStatusDeactivating,
+}
+
 func (c *HttpClient) ListInstances() (*ListInstancesResponseProto, error) {
-	statuses := StatusSelector{
-		Statuses: []InstanceStatus{
-			InstanceStatusActive,
-			InstanceStatusInitializing,
-			InstanceStatusDeactivating,
-		},
+	return c.ListInstancesByStatus(DefaultListedInstanceStatuses)
+}
+
+// ListInstancesByStatus lists the instances in any of the given statuses,
+// within the scope of the configured team if any.
+func (c *HttpClient) ListInstancesByStatus(statuses []InstanceStatus) (*ListInstancesResponseProto, error) {
+	selector, err := c.statusSelector(statuses)
+	if err != nil {
+		return nil, err
 	}
+	return c.listInstances(selector)
+}
+
+// statusSelector selects the instances in any of the given statuses, within
+// the scope of the configured team if any.
+func (c *HttpClient) statusSelector(statuses []InstanceStatus) (InstancesSelector, error) {
+	selected := StatusSelector{Statuses: statuses}
 	// Team accounts see their instances only when the listing is scoped to the
 	// team; a default (personal) scope hides them. Personal accounts omit scope.
 	if c.TeamID != "" {
 		var scope SelectorScope
 		if err := scope.FromSelectorScope1(SelectorScope1{Teams: []string{c.TeamID}}); err != nil {
-			return nil, err
+			return InstancesSelector{}, err
 		}
-		statuses.Scope = &scope
+		selected.Scope = &scope
 	}
 	var selector InstancesSelector
-	if err := selector.FromInstancesSelector1(InstancesSelector1{ByStatus: statuses}); err != nil {
-		return nil, err
-	}
-	return c.listInstances(selector)
+	err := selector.FromInstancesSelector1(InstancesSelector1{ByStatus: selected})
+	return selector, err
 }
 
-// GetInstanceIncludingInactive is like GetInstance but returns the instance
-// even when its status is Inactive, returning ErrNotFound only when the id is
-// absent from the list. Create uses it to tell a terminal Inactive apart from
-// an id that is simply not listed yet (eventual consistency right after rent).
-func (c *HttpClient) GetInstanceIncludingInactive(id string) (*InstanceAndUsageInfo, error) {
+func (c *HttpClient) GetInstance(id string) (*InstanceAndUsageInfo, error) {
 	instances, err := c.listInstancesForGet(id)
 	if err != nil {
 		return nil, err
@@ -551,6 +786,11 @@ func (c *HttpClient) GetInstanceIncludingInactive(id string) (*InstanceAndUsageI
 
 	for _, i := range instances.Data.Instances {
 		if i.Id == id {
+			if i.Status == InstanceStatusInactive {
+				// listing ById will return the Instance
+				// thus, check if still active.
+				return nil, ErrNotFound
+			}
 			return &i, nil
 		}
 	}
@@ -558,19 +798,46 @@ func (c *HttpClient) GetInstanceIncludingInactive(id string) (*InstanceAndUsageI
 	return nil, ErrNotFound
 }
 
-func (c *HttpClient) GetInstance(id string) (*InstanceAndUsageInfo, error) {
-	instances, err := c.listInstancesForGet(id)
+// ListClusterInstances returns the instances of the cluster, including the
+// inactive ones.
+func (c *HttpClient) ListClusterInstances(name string) ([]InstanceAndUsageInfo, error) {
+	var selector InstancesSelector
+	if err := selector.FromInstancesSelector2(InstancesSelector2{ByClusterName: name}); err != nil {
+		return nil, err
+	}
+
+	instances, err := c.listInstances(selector)
 	if err != nil {
 		return nil, err
 	}
+	return instances.Data.Instances, nil
+}
+
+// GetInstanceCredentials is like GetInstance but also requests the login
+// password and the rendered connection instructions of the instance. The
+// caller must not persist them. Keys without the ViewInstanceCredentials
+// permission get ErrCredentialsForbidden.
+func (c *HttpClient) GetInstanceCredentials(id string) (*InstanceAndUsageInfo, error) {
+	var selector InstancesSelector
+	if err := selector.FromInstancesSelector0(InstancesSelector0{ById: []string{id}}); err != nil {
+		return nil, err
+	}
+
+	// The instructions are part of the connection payload, so both flags are needed.
+	withConnectionInfo, withCredentials := true, true
+	instances, err := c.listInstancesWithMask(selector, InstanceInfoFlags{
+		WithConnectionInfo: &withConnectionInfo,
+		WithCredentials:    &withCredentials,
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "403") {
+			return nil, fmt.Errorf("%w: %w", ErrCredentialsForbidden, err)
+		}
+		return nil, err
+	}
 
 	for _, i := range instances.Data.Instances {
-		if i.Id == id {
-			if i.Status == InstanceStatusInactive {
-				// listing ById will return the Instance
-				// thus, check if still active.
-				return nil, ErrNotFound
-			}
+		if i.Id == id && i.Status != InstanceStatusInactive {
 			return &i, nil
 		}
 	}
@@ -578,6 +845,36 @@ func (c *HttpClient) GetInstance(id string) (*InstanceAndUsageInfo, error) {
 	return nil, ErrNotFound
 }
 
+// GetInstanceByName returns the instance with the given name among the
+// instances ListInstances returns. Names are not unique, so more than one
+// match is an error listing the candidate IDs.
+func (c *HttpClient) GetInstanceByName(name string) (*InstanceAndUsageInfo, error) {
+	instances, err := c.ListInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []InstanceAndUsageInfo
+	for _, i := range instances.Data.Instances {
+		if i.InstanceName != nil && *i.InstanceName == name {
+			matched = append(matched, i)
+		}
+	}
+
+	switch len(matched) {
+	case 0:
+		return nil, ErrNotFound
+	case 1:
+		return &matched[0], nil
+	default:
+		ids := make([]string, 0, len(matched))
+		for _, i := range matched {
+			ids = append(ids, i.Id)
+		}
+		return nil, fmt.Errorf("instance name %q is ambiguous, it matches instances: %s", name, strings.Join(ids, ", "))
+	}
+}
+
 // listInstancesForGet returns the instance list GetInstance should filter
 // over. Since API v061, ById resolves instances for both personal and team
 // accounts (with host_address populated via the connection-info mask set in
@@ -642,9 +939,329 @@ func (c *HttpClient) ListInstanceTypes() (*ListInstanceTypesResponseProto, error
 	}
 
 	if resp == nil || resp.JSON200 == nil {
-		return nil, errors.New(
+		return nil, withRequestID(req, errors.New(
 			"listing instance-types failed, expected response with code 200 which was not returned, but no error occurred with the request itself, most likely the API changed, or the response has a missing 'Content-Type' for json",
-		)
+		))
+	}
+
+	return resp.JSON200, nil
+}
+
+// GetInstanceMetrics returns the metrics of the GPUs allocated to the
+// instances. Instances the API reports no metrics for, e.g. because they are
+// not running, are missing from the result.
+func (c *HttpClient) GetInstanceMetrics(ids []string) ([]InstanceMetrics, error) {
+	var selector InstanceMetricsSelector
+	if err := selector.FromInstanceMetricsSelector0(InstanceMetricsSelector0{ById: ids}); err != nil {
+		return nil, err
+	}
+
+	body, err := marshalVersionedRequest(c.ProtoVersion, struct {
+		Selector InstanceMetricsSelector `json:"selector"`
+	}{Selector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := NewInstanceMetricsRequestWithBody(c.HostURL, "application/json", body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := DoRequestWithApiToken(c, req, ParseInstanceMetricsResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil || resp.JSON200 == nil {
+		return nil, withRequestID(req, errors.New(
+			"reading instance metrics failed, expected response with code 200 which was not returned, but no error occurred with the request itself, most likely the API changed, or the response has a missing 'Content-Type' for json",
+		))
+	}
+
+	return resp.JSON200.Data.Metrics, nil
+}
+
+// ListSavedEnvironments returns the saved environments of the team of the
+// client, or of the owner of the API key if no team is set.
+func (c *HttpClient) ListSavedEnvironments() ([]SavedEnvironment, error) {
+	var teamID *string
+	if c.TeamID != "" {
+		teamID = &c.TeamID
+	}
+
+	body, err := marshalVersionedRequest(c.ProtoVersion, struct {
+		TeamID *string `json:"team_id,omitempty"`
+	}{TeamID: teamID})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := NewListSavedEnvironmentsRequestWithBody(c.HostURL, "application/json", body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := DoRequestWithApiToken(c, req, ParseListSavedEnvironmentsResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil || resp.JSON200 == nil {
+		return nil, withRequestID(req, errors.New(
+			"listing saved environments failed, expected response with code 200 which was not returned, but no error occurred with the request itself, most likely the API changed, or the response has a missing 'Content-Type' for json",
+		))
+	}
+
+	return resp.JSON200.Data.SavedEnvironments, nil
+}
+
+// GetSavedEnvironment returns the saved environment with the ID, ErrNotFound
+// if there is no such environment, e.g. because its disk was erased.
+func (c *HttpClient) GetSavedEnvironment(id string) (*SavedEnvironment, error) {
+	envs, err := c.ListSavedEnvironments()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range envs {
+		if e.Id == id {
+			return &e, nil
+		}
+	}
+
+	return nil, fmt.Errorf("saved environment %s: %w", id, ErrNotFound)
+}
+
+// GetAccountInfo returns the account of the owner of the API key.
+func (c *HttpClient) GetAccountInfo() (*AccountInfoProto, error) {
+	req, err := NewGetAccountInfoRequest(c.HostURL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := DoRequestWithApiToken(c, req, ParseGetAccountInfoResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil || resp.JSON200 == nil {
+		return nil, withRequestID(req, errors.New(
+			"reading account info failed, expected response with code 200 which was not returned, but no error occurred with the request itself, most likely the API changed, or the response has a missing 'Content-Type' for json",
+		))
+	}
+
+	return resp.JSON200, nil
+}
+
+// GetTeamAccountInfo returns the account information of a team the owner of
+// the API key is a member of, ErrNotFound if there is no such team.
+func (c *HttpClient) GetTeamAccountInfo(teamID string) (*TeamAccountInfo, error) {
+	var mine TeamListSelector
+	if err := mine.FromTeamListSelector0(Mine); err != nil {
+		return nil, err
+	}
+
+	withAccountInfo := true
+	body, err := marshalVersionedRequest(c.ProtoVersion, struct {
+		Selector        *TeamListSelector `json:"selector,omitempty"`
+		WithAccountInfo *bool             `json:"with_account_info,omitempty"`
+	}{Selector: &mine, WithAccountInfo: &withAccountInfo})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := NewListTeamsRequestWithBody(c.HostURL, "application/json", body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := DoRequestWithApiToken(c, req, ParseListTeamsResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil || resp.JSON200 == nil {
+		return nil, withRequestID(req, errors.New(
+			"listing teams failed, expected response with code 200 which was not returned, but no error occurred with the request itself, most likely the API changed, or the response has a missing 'Content-Type' for json",
+		))
+	}
+
+	for _, team := range resp.JSON200.Data.Teams {
+		if team.Id != teamID {
+			continue
+		}
+		if team.AccountInfo == nil {
+			return nil, withRequestID(req, fmt.Errorf("the account information of team %s was not returned, the API key may not be allowed to view it", teamID))
+		}
+		return team.AccountInfo, nil
+	}
+
+	return nil, fmt.Errorf("team %s: %w", teamID, ErrNotFound)
+}
+
+// Balance returns the balance in USD of the team of the client, or of the
+// personal account if no team is set.
+func (c *HttpClient) Balance() (float64, error) {
+	if c.TeamID == "" {
+		info, err := c.GetAccountInfo()
+		if err != nil {
+			return 0, err
+		}
+		return info.Data.Balance, nil
+	}
+
+	info, err := c.GetTeamAccountInfo(c.TeamID)
+	if err != nil {
+		return 0, err
+	}
+	// Team balances are reported in cents.
+	return float64(info.Balance) / 100, nil
+}
+
+// ListTransactions returns the transactions of the team of the client, or of
+// the personal account if no team is set, created between from and to. A
+// zero bound is left to the API, which defaults to the last 30 days.
+func (c *HttpClient) ListTransactions(from, to time.Time) ([]Transaction, error) {
+	selector, err := c.accountSelector()
+	if err != nil {
+		return nil, err
+	}
+
+	var fromStr, toStr *string
+	if !from.IsZero() {
+		s := from.UTC().Format(time.RFC3339)
+		fromStr = &s
+	}
+	if !to.IsZero() {
+		s := to.UTC().Format(time.RFC3339)
+		toStr = &s
+	}
+
+	body, err := marshalVersionedRequest(c.ProtoVersion, struct {
+		Selector AccountSelector `json:"selector"`
+		From     *string         `json:"from,omitempty"`
+		To       *string         `json:"to,omitempty"`
+	}{Selector: selector, From: fromStr, To: toStr})
+	if err != nil {
+		return nil, err
+	}
+
+	// The spec does not declare the request body of this endpoint, so the
+	// generated request builder cannot be used.
+	req, err := http.NewRequest(http.MethodPost, c.HostURL+"api/v1/account/transactions/list", body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := DoRequestWithApiToken(c, req, ParseListTransactionsResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil || resp.JSON200 == nil {
+		return nil, withRequestID(req, errors.New(
+			"listing transactions failed, expected response with code 200 which was not returned, but no error occurred with the request itself, most likely the API changed, or the response has a missing 'Content-Type' for json",
+		))
+	}
+
+	return resp.JSON200.Data.Transactions, nil
+}
+
+// accountSelector selects the team of the client, or the personal account of
+// the owner of the API key if no team is set.
+func (c *HttpClient) accountSelector() (AccountSelector, error) {
+	var selector AccountSelector
+	if c.TeamID != "" {
+		return selector, selector.FromAccountSelector1(AccountSelector1{ByTeam: c.TeamID})
+	}
+	return selector, selector.FromAccountSelector0(ByToken)
+}
+
+// GetAutoTopUp returns the auto top-up state of the team of the client, or of
+// the personal account if no team is set. The settings of the state are nil
+// if auto top-up was never configured.
+func (c *HttpClient) GetAutoTopUp() (*AutoTopUpStateProto, error) {
+	selector, err := c.accountSelector()
+	if err != nil {
+		return nil, err
+	}
+
+	withAutoTopUp := true
+	body, err := marshalVersionedRequest(c.ProtoVersion, struct {
+		Selector      AccountSelector `json:"selector"`
+		WithAutoTopUp *bool           `json:"with_auto_top_up,omitempty"`
+	}{Selector: selector, WithAutoTopUp: &withAutoTopUp})
+	if err != nil {
+		return nil, err
+	}
+
+	// The spec declares neither the request body of this endpoint nor the
+	// auto top-up state of its response, so the generated request builder
+	// and parser cannot be used.
+	req, err := http.NewRequest(http.MethodPost, c.HostURL+"api/v1/account/info", body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return DoRequestWithApiToken(c, req, parseAutoTopUpAccountInfo)
+}
+
+func parseAutoTopUpAccountInfo(resp *http.Response) (*AutoTopUpStateProto, error) {
+	defer resp.Body.Close()
+
+	var info struct {
+		Version string `json:"version"`
+		Data    struct {
+			AutoTopUp json.RawMessage `json:"auto_top_up"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, fmt.Errorf("decoding account info: %w", err)
+	}
+	if len(info.Data.AutoTopUp) == 0 || string(info.Data.AutoTopUp) == "null" {
+		return nil, errors.New("the auto top-up state was not returned, the API key may not be allowed to manage billing of the account")
+	}
+
+	state := AutoTopUpStateProto{Version: info.Version}
+	if err := json.Unmarshal(info.Data.AutoTopUp, &state.Data); err != nil {
+		return nil, fmt.Errorf("decoding auto top-up state: %w", err)
+	}
+	return &state, nil
+}
+
+// UpdateAutoTopUp creates or updates the auto top-up settings of the team of
+// the client, or of the personal account if no team is set.
+func (c *HttpClient) UpdateAutoTopUp(settings AutoTopUpSettings) (*AutoTopUpStateProto, error) {
+	selector, err := c.accountSelector()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := marshalVersionedRequest(c.ProtoVersion, struct {
+		Selector AccountSelector   `json:"selector"`
+		Settings AutoTopUpSettings `json:"settings"`
+	}{Selector: selector, Settings: settings})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := NewUpdateAutoTopUpRequestWithBody(c.HostURL, "application/json", body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := DoRequestWithApiToken(c, req, ParseUpdateAutoTopUpResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil || resp.JSON200 == nil {
+		return nil, withRequestID(req, errors.New(
+			"updating auto top-up failed, expected response with code 200 which was not returned, but no error occurred with the request itself, most likely the API changed, or the response has a missing 'Content-Type' for json",
+		))
 	}
 
 	return resp.JSON200, nil
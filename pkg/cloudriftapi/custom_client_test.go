@@ -2,7 +2,7 @@ package cloudriftapi
 
 import "testing"
 
-func Test_isImageURL(t *testing.T) {
+func Test_IsImageURL(t *testing.T) {
 	t.Parallel()
 
 	cases := map[string]bool{
@@ -18,8 +18,8 @@ func Test_isImageURL(t *testing.T) {
 	}
 
 	for input, want := range cases {
-		if got := isImageURL(input); got != want {
-			t.Errorf("isImageURL(%q) = %v, want %v", input, got, want)
+		if got := IsImageURL(input); got != want {
+			t.Errorf("IsImageURL(%q) = %v, want %v", input, got, want)
 		}
 	}
 }
@@ -3,10 +3,15 @@
 //  1. Download the raw spec:
 //     curl -s https://api.cloudrift.ai/api-docs/openapi2.json > api_raw.json
 //
-//  2. Patch the spec (fixes oapi-codegen incompatibilities):
+//  2. Check the drift from the committed spec, the custom client depends on
+//     fields and response codes the generated types do not guard, e.g.
+//     host_address only listed with the with_connection_info mask:
+//     go run ./specdrift
+//
+//  3. Patch the spec (fixes oapi-codegen incompatibilities):
 //     go run patchspec.go
 //
-//  3. Generate the Go client:
+//  4. Generate the Go client:
 //     go generate
 //
 // The patchspec.go tool applies the following transformations to api_raw.json → api.json:
@@ -0,0 +1,148 @@
+package cloudriftapi
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultPollInterval is the cadence of the shared instance poller behind
+// PollInstance. Override with WithPollInterval.
+const DefaultPollInterval = 5 * time.Second
+
+// WithPollInterval overrides the cadence of the shared instance poller. A
+// non-positive duration is ignored, leaving DefaultPollInterval in place.
+func WithPollInterval(d time.Duration) HttpClientOption {
+	return func(hc *HttpClient) {
+		if d > 0 {
+			hc.pollInterval = d
+		}
+	}
+}
+
+// InstancePollResult is the outcome of a single PollInstance lookup.
+type InstancePollResult struct {
+	Instance *InstanceAndUsageInfo
+	Err      error
+}
+
+// instancePoller coalesces the instance lookups of concurrent callers, e.g.
+// the Create of every Virtual Machine of a large apply, into one ById list
+// request per poll interval.
+type instancePoller struct {
+	mu      sync.Mutex
+	pending map[string][]chan InstancePollResult
+	running bool
+}
+
+// PollInstance looks the instance up on the next tick of the shared poller.
+// Unlike GetInstance the instance is returned whatever its status, Inactive
+// included, and ErrNotFound only if it is not listed.
+//
+// The returned channel receives exactly one result. It is buffered, so a
+// caller that stops waiting, on a timeout or cancel, never blocks the poller.
+func (c *HttpClient) PollInstance(id string) <-chan InstancePollResult {
+	ch := make(chan InstancePollResult, 1)
+
+	p := c.poller
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.pending == nil {
+		p.pending = make(map[string][]chan InstancePollResult)
+	}
+	p.pending[id] = append(p.pending[id], ch)
+
+	if !p.running {
+		p.running = true
+		// The poller outlives the caller starting it, it keeps the values of
+		// its context, e.g. the logger, but not its cancellation.
+		go c.WithContext(context.WithoutCancel(c.context())).runPoller()
+	}
+	return ch
+}
+
+// runPoller flushes the pending lookups every poll interval and exits once a
+// tick finds none, the next PollInstance starts it again.
+func (c *HttpClient) runPoller() {
+	interval := c.pollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	p := c.poller
+	for {
+		time.Sleep(interval)
+
+		p.mu.Lock()
+		batch := p.pending
+		p.pending = nil
+		if len(batch) == 0 {
+			p.running = false
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		c.flushInstanceLookups(batch)
+	}
+}
+
+// flushInstanceLookups resolves the batch with a single ById list request and
+// fans the results out to the waiting callers. If the request fails, each
+// instance is looked up on its own, so that the error only reaches the
+// callers of the instances it concerns.
+func (c *HttpClient) flushInstanceLookups(batch map[string][]chan InstancePollResult) {
+	ids := make([]string, 0, len(batch))
+	for id := range batch {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	found, err := c.lookupInstances(ids)
+	if err != nil && len(ids) > 1 {
+		for _, id := range ids {
+			found, err := c.lookupInstances([]string{id})
+			resolveInstanceLookups(id, batch[id], found, err)
+		}
+		return
+	}
+	for _, id := range ids {
+		resolveInstanceLookups(id, batch[id], found, err)
+	}
+}
+
+// lookupInstances lists the instances by id, whatever their status.
+func (c *HttpClient) lookupInstances(ids []string) (map[string]*InstanceAndUsageInfo, error) {
+	var selector InstancesSelector
+	if err := selector.FromInstancesSelector0(InstancesSelector0{ById: ids}); err != nil {
+		return nil, err
+	}
+	instances, err := c.listInstances(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]*InstanceAndUsageInfo, len(instances.Data.Instances))
+	for i := range instances.Data.Instances {
+		found[instances.Data.Instances[i].Id] = &instances.Data.Instances[i]
+	}
+	return found, nil
+}
+
+// resolveInstanceLookups sends the result of the lookup of id to its waiters.
+func resolveInstanceLookups(id string, waiters []chan InstancePollResult, found map[string]*InstanceAndUsageInfo, err error) {
+	for _, ch := range waiters {
+		switch inst, ok := found[id]; {
+		case err != nil:
+			ch <- InstancePollResult{Err: err}
+		case !ok:
+			ch <- InstancePollResult{Err: ErrNotFound}
+		default:
+			// Every waiter gets its own copy.
+			cp := *inst
+			ch <- InstancePollResult{Instance: &cp}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package cloudriftapi
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ProtoVersions are the protocol versions known to the client, newest first,
+// and ~upcoming: servers that reject every dated version, as the API did
+// since v061 for the ByStatus+scope selector and the connection-info mask,
+// still accept it.
+var ProtoVersions = []string{
+	Proto20260510,
+	Proto20250610,
+	Proto20250529,
+	Proto20250321,
+	Proto20250210,
+	Proto20240922,
+	ProtoUpcoming,
+}
+
+// ListCapabilities returns the features of the server available to the
+// requester, and the latest protocol version the server released.
+func (c *HttpClient) ListCapabilities() (*CapabilitiesResponseProto, error) {
+	req, err := NewListCapabilitiesRequest(c.HostURL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := DoRequestWithApiToken(c, req, ParseListCapabilitiesResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp == nil || resp.JSON200 == nil {
+		return nil, withRequestID(req, errors.New(
+			"listing capabilities failed, expected response with code 200 which was not returned, but no error occurred with the request itself, most likely the API changed, or the response has a missing 'Content-Type' for json",
+		))
+	}
+
+	return resp.JSON200, nil
+}
+
+// Capabilities returns the capabilities listed while negotiating the protocol
+// version, nil if the server does not list them.
+func (c *HttpClient) Capabilities() *CapabilitiesResponseProto {
+	return c.capabilities
+}
+
+// negotiateProtoVersion sets ProtoVersion to the pinned version, or to the
+// first of candidateProtoVersions the server accepts. A pinned version is not
+// probed, it is only checked against the latest version the server lists. Any
+// error of a probe other than a rejected version fails the negotiation.
+func (c *HttpClient) negotiateProtoVersion(pinned string) error {
+	caps, err := c.ListCapabilities()
+	switch {
+	case errors.Is(err, ErrNotFound):
+		// Servers predating /capabilities/list, probe every version.
+	case err != nil:
+		return fmt.Errorf("failed to list capabilities: %w", err)
+	default:
+		c.capabilities = caps
+	}
+
+	if pinned != "" {
+		if latest := c.latestProtoVersion(); isDatedProtoVersion(pinned) && isDatedProtoVersion(latest) && pinned > latest {
+			return fmt.Errorf("%w %q, the latest version of the CloudRift API is %q; pin it or leave the version empty to negotiate one",
+				ErrUnsupportedVersion, pinned, latest)
+		}
+		c.ProtoVersion = pinned
+		return nil
+	}
+
+	for _, v := range c.candidateProtoVersions() {
+		err := c.probeProtoVersion(v)
+		if err == nil {
+			c.ProtoVersion = v
+			return nil
+		}
+		if !errors.Is(err, ErrUnsupportedVersion) {
+			return fmt.Errorf("failed to probe the protocol version %q: %w", v, err)
+		}
+	}
+
+	latest := ""
+	if v := c.latestProtoVersion(); v != "" {
+		latest = fmt.Sprintf(", the latest version of the server is %q", v)
+	}
+	return fmt.Errorf("%w, the CloudRift API supports none of the versions known to the client (%s)%s, upgrade the client",
+		ErrUnsupportedVersion, strings.Join(ProtoVersions, ", "), latest)
+}
+
+// latestProtoVersion returns the latest version the server listed with its
+// capabilities, empty if it does not list them.
+func (c *HttpClient) latestProtoVersion() string {
+	if c.capabilities == nil {
+		return ""
+	}
+	return c.capabilities.Version
+}
+
+// candidateProtoVersions returns the versions to probe in order: the latest
+// version of the server if the client knows it, ~upcoming, then the other
+// dated versions of ProtoVersions up to the latest one of the server, newest
+// first.
+func (c *HttpClient) candidateProtoVersions() []string {
+	latest := c.latestProtoVersion()
+
+	var candidates []string
+	if latest != ProtoUpcoming && slices.Contains(ProtoVersions, latest) {
+		candidates = append(candidates, latest)
+	}
+	candidates = append(candidates, ProtoUpcoming)
+	for _, v := range ProtoVersions {
+		if v == ProtoUpcoming || v == latest {
+			continue
+		}
+		// Dated versions compare in order as strings.
+		if isDatedProtoVersion(latest) && v > latest {
+			continue
+		}
+		candidates = append(candidates, v)
+	}
+	return candidates
+}
+
+func isDatedProtoVersion(v string) bool {
+	_, err := time.Parse(time.DateOnly, v)
+	return err == nil
+}
+
+// probeProtoVersion sends the /instances/list request the resources depend on,
+// with the team scope and connection-info mask, in the given version.
+func (c *HttpClient) probeProtoVersion(version string) error {
+	selector, err := c.statusSelector([]InstanceStatus{InstanceStatusInitializing})
+	if err != nil {
+		return err
+	}
+
+	withConnectionInfo := true
+	body, err := marshalVersionedRequest(version, struct {
+		Selector InstancesSelector  `json:"selector"`
+		Mask     *InstanceInfoFlags `json:"mask,omitempty"`
+	}{
+		Selector: selector,
+		Mask:     &InstanceInfoFlags{WithConnectionInfo: &withConnectionInfo},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.doListInstances(body)
+	return err
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// endpointDependencies are the endpoints the custom client sends requests to,
+// with the response code it decodes.
+var endpointDependencies = map[string]string{
+	"post /api/v1/auth/me":                           "200",
+	"post /api/v1/capabilities/list":                 "200",
+	"post /api/v1/account/info":                      "200",
+	"post /api/v1/account/transactions/list":         "200",
+	"post /api/v1/account/auto-top-up/update":        "200",
+	"post /api/v1/teams/list":                        "200",
+	"post /api/v1/recipes/list":                      "200",
+	"post /api/v1/instance-types/list":               "200",
+	"post /api/v1/instances/list":                    "200",
+	"post /api/v1/instances/rent":                    "200",
+	"post /api/v1/instances/terminate":               "200",
+	"post /api/v1/instances/metrics":                 "200",
+	"post /api/v1/instances/saved-environments/list": "200",
+	"post /api/v1/ssh-keys/add":                      "201",
+	"post /api/v1/ssh-keys/list":                     "200",
+	"delete /api/v1/ssh-keys/{ssh_key_id}":           "200",
+}
+
+// fieldDependencies are the fields of the schemas the custom client and the
+// resources read or send. Since the v061 masking, the addresses of the
+// instances are only listed with the with_connection_info mask.
+var fieldDependencies = map[string][]string{
+	"InstanceAndUsageInfo": {
+		"id", "status", "instance_name", "node_id", "node_mode", "created_at",
+		"host_address", "internal_host_address", "resource_info", "virtual_machines",
+	},
+	"InstanceVirtualMachineInfo": {"vmid", "name", "ready", "state", "login_info"},
+	"InstanceResourceInfo":       {"instance_type", "cost_per_hour", "provider_name"},
+	"InstanceInfoFlags":          {"with_connection_info", "with_credentials"},
+	"ListInstancesRequest":       {"selector", "mask"},
+	"ListInstancesResponse":      {"instances"},
+	"InstancesSelector":          {"ById", "ByStatus", "ByClusterName"},
+	"StatusSelector":             {"statuses", "scope"},
+	"SelectorScope":              {"Teams"},
+	"RentInstanceRequest":        {"selector", "config", "name", "cluster_name", "team_id", "reuse_environment_id"},
+	"RentInstanceResponse":       {"instance_ids"},
+	"NodeSelector":               {"ByInstanceTypeAndLocation", "ByNodeId"},
+	"TerminateInstancesRequest":  {"selector"},
+	"SshKey":                     {"id", "name", "public_key"},
+	"ListSshKeysResponse":        {"keys"},
+	"GenerateSshKeyResponse":     {"public_key"},
+	"InstanceVariantInfo":        {"name", "cost_per_hour", "gpu_count", "available_nodes", "available_nodes_per_dc", "nodes_per_dc"},
+	"ListInstanceTypesResponse":  {"instance_types"},
+	"ListRecipesResponse":        {"groups"},
+	"RecipeGroup":                {"name", "recipes"},
+	"CapabilitiesResponseProto":  {"version", "data"},
+}
+
+// schemaDependencies are the schemas whose every enum value the client
+// handles.
+var schemaDependencies = []string{"InstanceStatus", "SelectorScope"}
+
+// isDependency reports whether the custom client depends on the location of
+// a Drift.
+func isDependency(location string) bool {
+	if _, ok := endpointDependencies[location]; ok {
+		return true
+	}
+	schema, field, ok := strings.Cut(location, ".")
+	if !ok {
+		_, ok = fieldDependencies[schema]
+		return ok || slices.Contains(schemaDependencies, schema)
+	}
+	return slices.Contains(fieldDependencies[schema], field)
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Kind is the kind of a difference between two specs.
+type Kind string
+
+const (
+	RemovedEndpoint  Kind = "removed endpoint"
+	ChangedResponse  Kind = "changed response code"
+	RemovedSchema    Kind = "removed schema"
+	RemovedField     Kind = "removed field"
+	RenamedField     Kind = "renamed field"
+	ChangedType      Kind = "changed type"
+	RemovedEnumValue Kind = "removed enum value"
+)
+
+// Drift is a difference of the new spec breaking the clients of the old one.
+type Drift struct {
+	Kind Kind
+	// Location is the endpoint, "post /api/v1/instances/list", or the schema
+	// and field, "InstanceAndUsageInfo.host_address".
+	Location string
+	Detail   string
+	// Dependency is set when the custom client depends on the location.
+	Dependency bool
+}
+
+func (d Drift) String() string {
+	if d.Detail == "" {
+		return fmt.Sprintf("%s: %s", d.Kind, d.Location)
+	}
+	return fmt.Sprintf("%s: %s (%s)", d.Kind, d.Location, d.Detail)
+}
+
+type spec struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	Responses map[string]json.RawMessage `json:"responses"`
+}
+
+// schema holds the parts of both OpenAPI 3.0 and 3.1 schemas the drift
+// depends on, the raw spec is 3.1 while the patched one is 3.0.
+type schema struct {
+	Ref        string             `json:"$ref"`
+	Type       json.RawMessage    `json:"type"`
+	Items      json.RawMessage    `json:"items"`
+	Properties map[string]*schema `json:"properties"`
+	OneOf      []*schema          `json:"oneOf"`
+	AnyOf      []*schema          `json:"anyOf"`
+	AllOf      []*schema          `json:"allOf"`
+	Enum       []any              `json:"enum"`
+}
+
+func parseSpec(b []byte) (*spec, error) {
+	var s spec
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Diff returns the drift of the new spec from the old one, sorted by location.
+func Diff(old, new *spec) []Drift {
+	var drift []Drift
+	drift = append(drift, diffEndpoints(old, new)...)
+	drift = append(drift, diffSchemas(old, new)...)
+
+	for i := range drift {
+		drift[i].Dependency = isDependency(drift[i].Location)
+	}
+	slices.SortFunc(drift, func(a, b Drift) int {
+		return cmp.Or(strings.Compare(a.Location, b.Location), strings.Compare(a.String(), b.String()))
+	})
+	return drift
+}
+
+func diffEndpoints(old, new *spec) []Drift {
+	var drift []Drift
+	for path, methods := range old.Paths {
+		for method, op := range methods {
+			location := method + " " + path
+			newOp := new.Paths[path][method]
+			if newOp == nil {
+				drift = append(drift, Drift{Kind: RemovedEndpoint, Location: location})
+				continue
+			}
+			oldCodes, newCodes := successCodes(path, op), successCodes(path, newOp)
+			if slices.Equal(oldCodes, newCodes) {
+				continue
+			}
+			detail := fmt.Sprintf("%s -> %s", strings.Join(oldCodes, ", "), strings.Join(newCodes, ", "))
+			if code, ok := endpointDependencies[location]; ok && !slices.Contains(newCodes, code) {
+				detail += ", the client decodes " + code
+			}
+			drift = append(drift, Drift{Kind: ChangedResponse, Location: location, Detail: detail})
+		}
+	}
+	return drift
+}
+
+// successCodes returns the 2xx response codes of the operation as patchspec.go
+// patches them: 201 becomes 200, the API answers 200, except on the endpoints
+// genuinely answering 201.
+func successCodes(path string, op *operation) []string {
+	var codes []string
+	for code := range op.Responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	if path != "/api/v1/ssh-keys/add" && !slices.Contains(codes, "200") {
+		if i := slices.Index(codes, "201"); i >= 0 {
+			codes[i] = "200"
+		}
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// patchedTypes are the fields patchspec.go retypes, as the API answers them
+// differently than the spec declares them.
+var patchedTypes = map[string]bool{
+	"InstanceUserInstructions.instructions_template": true,
+}
+
+func diffSchemas(old, new *spec) []Drift {
+	var drift []Drift
+	for name, oldSchema := range old.Components.Schemas {
+		newSchema := new.Components.Schemas[name]
+		if newSchema == nil {
+			drift = append(drift, Drift{Kind: RemovedSchema, Location: name})
+			continue
+		}
+
+		oldFields, newFields := fields(oldSchema), fields(newSchema)
+		for field, oldField := range oldFields {
+			location := name + "." + field
+			newField, ok := newFields[field]
+			if !ok {
+				drift = append(drift, removedField(location, oldField, oldFields, newFields))
+				continue
+			}
+			if oldType, newType := typeName(oldField), typeName(newField); oldType != newType && !patchedTypes[location] {
+				drift = append(drift, Drift{Kind: ChangedType, Location: location, Detail: oldType + " -> " + newType})
+			}
+		}
+
+		newEnum := enumValues(newSchema)
+		for _, v := range enumValues(oldSchema) {
+			if !slices.Contains(newEnum, v) {
+				drift = append(drift, Drift{Kind: RemovedEnumValue, Location: name, Detail: fmt.Sprint(v)})
+			}
+		}
+	}
+	return drift
+}
+
+// removedField reports the field as renamed when the new schema adds exactly
+// one field of the same type, as removed otherwise.
+func removedField(location string, oldField *schema, oldFields, newFields map[string]*schema) Drift {
+	var candidates []string
+	for field, newField := range newFields {
+		if _, ok := oldFields[field]; !ok && typeName(newField) == typeName(oldField) {
+			candidates = append(candidates, field)
+		}
+	}
+	if len(candidates) == 1 {
+		return Drift{Kind: RenamedField, Location: location, Detail: "to " + candidates[0] + "?"}
+	}
+	return Drift{Kind: RemovedField, Location: location}
+}
+
+// enumValues returns the enum values of the schema and of its inline oneOf
+// members, e.g. Personal of SelectorScope.
+func enumValues(s *schema) []any {
+	values := slices.Clone(s.Enum)
+	for _, m := range s.OneOf {
+		values = append(values, m.Enum...)
+	}
+	return values
+}
+
+// fields returns the properties of the schema, with the ones of its inline
+// oneOf, anyOf and allOf members, e.g. ById of InstancesSelector.
+func fields(s *schema) map[string]*schema {
+	out := map[string]*schema{}
+	for name, p := range s.Properties {
+		out[name] = p
+	}
+	for _, members := range [][]*schema{s.OneOf, s.AnyOf, s.AllOf} {
+		for _, m := range members {
+			for name, p := range m.Properties {
+				out[name] = p
+			}
+		}
+	}
+	return out
+}
+
+// typeName describes the type of the schema, regardless of its nullability,
+// which OpenAPI 3.0 and 3.1 express differently.
+func typeName(s *schema) string {
+	if s == nil {
+		return "any"
+	}
+	if s.Ref != "" {
+		return strings.TrimPrefix(s.Ref, "#/components/schemas/")
+	}
+
+	var members []string
+	for _, m := range slices.Concat(s.OneOf, s.AnyOf, s.AllOf) {
+		if t := typeName(m); t != "null" && !slices.Contains(members, t) {
+			members = append(members, t)
+		}
+	}
+	if len(members) > 0 && len(s.Properties) == 0 {
+		return strings.Join(members, "|")
+	}
+
+	var types []string
+	var single string
+	if json.Unmarshal(s.Type, &single) == nil {
+		types = []string{single}
+	} else {
+		_ = json.Unmarshal(s.Type, &types)
+	}
+	if slices.Equal(types, []string{"null"}) {
+		return "null"
+	}
+	types = slices.DeleteFunc(types, func(t string) bool { return t == "null" })
+	switch t := strings.Join(types, "|"); t {
+	case "":
+		if len(s.Properties) > 0 {
+			return "object"
+		}
+		return "any"
+	case "array":
+		var items schema
+		// "items": false of the raw spec leaves the items untyped.
+		if json.Unmarshal(s.Items, &items) != nil {
+			return "array<any>"
+		}
+		return "array<" + typeName(&items) + ">"
+	default:
+		return t
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func mustReadSpec(t *testing.T, path string) *spec {
+	t.Helper()
+
+	s, err := readSpec(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func Test_Diff_Fixtures(t *testing.T) {
+	t.Parallel()
+
+	drift := Diff(mustReadSpec(t, "testdata/api.json"), mustReadSpec(t, "testdata/api_raw.json"))
+
+	got := make([]string, 0, len(drift))
+	for _, d := range drift {
+		got = append(got, fmt.Sprintf("%v %s", d.Dependency, d))
+	}
+	// The 201 of the raw spec, the nullable types and "items": false are
+	// patched by patchspec.go, they are no drift.
+	want := []string{
+		"false changed type: InstanceAndUsageInfo.extra_field (integer -> string)",
+		"true renamed field: InstanceAndUsageInfo.host_address (to public_address?)",
+		"true removed field: InstanceInfoFlags.with_connection_info",
+		"true removed enum value: InstanceStatus (Failed)",
+		"false removed schema: Legacy",
+		"true changed response code: post /api/v1/instances/rent (200 -> 202, the client decodes 200)",
+		"false removed endpoint: post /api/v1/internal/thing",
+		"true removed endpoint: post /api/v1/recipes/list",
+		"true changed response code: post /api/v1/ssh-keys/add (201 -> 200, the client decodes 201)",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("unexpected drift\ngot:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func Test_Diff_CommittedSpecs(t *testing.T) {
+	t.Parallel()
+
+	// api.json is patched from api_raw.json, the patches are no drift.
+	if drift := Diff(mustReadSpec(t, "../api.json"), mustReadSpec(t, "../api_raw.json")); len(drift) != 0 {
+		t.Errorf("expected no drift between the committed specs, got %v", drift)
+	}
+}
+
+func Test_Dependencies_InCommittedSpec(t *testing.T) {
+	t.Parallel()
+
+	committed := mustReadSpec(t, "../api.json")
+
+	for location, code := range endpointDependencies {
+		method, path, _ := strings.Cut(location, " ")
+		op := committed.Paths[path][method]
+		if op == nil {
+			t.Errorf("endpoint dependency %q is not in api.json", location)
+			continue
+		}
+		if !slices.Contains(successCodes(path, op), code) {
+			t.Errorf("endpoint dependency %q does not answer %s in api.json", location, code)
+		}
+	}
+	for name, deps := range fieldDependencies {
+		s := committed.Components.Schemas[name]
+		if s == nil {
+			t.Errorf("schema dependency %q is not in api.json", name)
+			continue
+		}
+		schemaFields := fields(s)
+		for _, field := range deps {
+			if _, ok := schemaFields[field]; !ok {
+				t.Errorf("field dependency %s.%s is not in api.json", name, field)
+			}
+		}
+	}
+	for _, name := range schemaDependencies {
+		if s := committed.Components.Schemas[name]; s == nil || len(enumValues(s)) == 0 {
+			t.Errorf("enum dependency %q is not in api.json", name)
+		}
+	}
+}
+
+func Test_Run(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	if code := run(&out, "testdata/api.json", "testdata/api_raw.json", false); code != 1 {
+		t.Errorf("expected the exit code 1 on breaking drift, got %d", code)
+	}
+	if strings.Contains(out.String(), "Legacy") || !strings.Contains(out.String(), "ERROR renamed field: InstanceAndUsageInfo.host_address") {
+		t.Errorf("expected only the breaking drift, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "6 breaking the custom client, 3 elsewhere") {
+		t.Errorf("expected the summary, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	run(&out, "testdata/api.json", "testdata/api_raw.json", true)
+	if !strings.Contains(out.String(), "removed schema: Legacy") {
+		t.Errorf("expected the drift elsewhere to be listed with -all, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code := run(&out, "testdata/api.json", "testdata/api.json", false); code != 0 {
+		t.Errorf("expected the exit code 0 without drift, got %d:\n%s", code, out.String())
+	}
+	if code := run(&out, "testdata/api.json", "testdata/missing.json", false); code != 2 {
+		t.Errorf("expected the exit code 2 on a missing spec, got %d", code)
+	}
+}
@@ -0,0 +1,79 @@
+// specdrift reports the drift of a newly downloaded CloudRift API spec from
+// the committed one, before regenerating the client:
+//
+//	curl -s https://api.cloudrift.ai/api-docs/openapi2.json > api_raw.json
+//	go run ./specdrift
+//
+// Removed endpoints, schemas, fields and enum values, renamed fields, changed
+// types and changed response codes the custom client depends on are errors,
+// the tool then exits with 1. The drift elsewhere is only listed with -all.
+//
+// Usage: go run ./specdrift [-all] [-old api.json] [-new api_raw.json]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	all := flag.Bool("all", false, "also list the drift the custom client does not depend on")
+	oldPath := flag.String("old", "api.json", "committed, patched spec")
+	newPath := flag.String("new", "api_raw.json", "newly downloaded, raw spec")
+	flag.Parse()
+
+	os.Exit(run(os.Stdout, *oldPath, *newPath, *all))
+}
+
+// run prints the drift of the spec at newPath from the one at oldPath and
+// returns the exit code.
+func run(w io.Writer, oldPath, newPath string, all bool) int {
+	old, err := readSpec(oldPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	new, err := readSpec(newPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	var breaking, other []Drift
+	for _, d := range Diff(old, new) {
+		if d.Dependency {
+			breaking = append(breaking, d)
+		} else {
+			other = append(other, d)
+		}
+	}
+
+	for _, d := range breaking {
+		fmt.Fprintf(w, "ERROR %s\n", d)
+	}
+	if all {
+		for _, d := range other {
+			fmt.Fprintf(w, "      %s\n", d)
+		}
+	}
+	fmt.Fprintf(w, "%s -> %s: %d breaking the custom client, %d elsewhere\n", oldPath, newPath, len(breaking), len(other))
+
+	if len(breaking) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func readSpec(path string) (*spec, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	s, err := parseSpec(b)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return s, nil
+}
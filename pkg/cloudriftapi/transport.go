@@ -0,0 +1,123 @@
+package cloudriftapi
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// WithTransportWrapper wraps the transport of the client, e.g. to log the
+// requests. The wrapper gets the transport configured by the other options.
+// Wrappers of several options are chained, the wrapper of the last option is
+// the closest to the network.
+func WithTransportWrapper(wrap func(http.RoundTripper) http.RoundTripper) HttpClientOption {
+	return func(hc *HttpClient) {
+		if wrap == nil {
+			return
+		}
+		if outer := hc.wrapTransport; outer != nil {
+			hc.wrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+				return outer(wrap(rt))
+			}
+			return
+		}
+		hc.wrapTransport = wrap
+	}
+}
+
+// WithProxy sends the requests through the proxy at proxyURL instead of the
+// one from the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables. An
+// empty URL is ignored.
+func WithProxy(proxyURL string) HttpClientOption {
+	return func(hc *HttpClient) {
+		if proxyURL == "" {
+			return
+		}
+		u, err := url.Parse(proxyURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			hc.optionErrs = append(hc.optionErrs, fmt.Errorf("invalid proxy URL %q, expected e.g. http://proxy.example.com:3128", proxyURL))
+			return
+		}
+		hc.transport.Proxy = http.ProxyURL(u)
+	}
+}
+
+// WithCACertPEM trusts the PEM encoded CA certificates in addition to the
+// ones of the system, e.g. the CA of a TLS-intercepting proxy. An empty PEM
+// is ignored.
+func WithCACertPEM(pem []byte) HttpClientOption {
+	return func(hc *HttpClient) {
+		if len(pem) == 0 {
+			return
+		}
+		cfg := hc.tlsConfig()
+		if cfg.RootCAs == nil {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			cfg.RootCAs = pool
+		}
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			hc.optionErrs = append(hc.optionErrs, fmt.Errorf("no PEM encoded CA certificate found"))
+		}
+	}
+}
+
+// WithCACertFile is like WithCACertPEM with the certificates read from the
+// file at path. An empty path is ignored.
+func WithCACertFile(path string) HttpClientOption {
+	return func(hc *HttpClient) {
+		if path == "" {
+			return
+		}
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			hc.optionErrs = append(hc.optionErrs, fmt.Errorf("reading CA certificate file: %w", err))
+			return
+		}
+		if len(pem) == 0 {
+			hc.optionErrs = append(hc.optionErrs, fmt.Errorf("CA certificate file %s is empty", path))
+			return
+		}
+		WithCACertPEM(pem)(hc)
+	}
+}
+
+// WithClientCertificate authenticates the client with the PEM encoded
+// certificate and private key for mutual TLS. It is ignored if both are
+// empty.
+func WithClientCertificate(certPEM, keyPEM []byte) HttpClientOption {
+	return func(hc *HttpClient) {
+		if len(certPEM) == 0 && len(keyPEM) == 0 {
+			return
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			hc.optionErrs = append(hc.optionErrs, fmt.Errorf("loading client certificate: %w", err))
+			return
+		}
+		cfg := hc.tlsConfig()
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+}
+
+// WithInsecureSkipVerify disables the verification of the server
+// certificate. Only meant for lab endpoints with self-signed certificates.
+func WithInsecureSkipVerify(skip bool) HttpClientOption {
+	return func(hc *HttpClient) {
+		if skip {
+			hc.tlsConfig().InsecureSkipVerify = true //nolint:gosec // opt-in for lab endpoints.
+		}
+	}
+}
+
+func (hc *HttpClient) tlsConfig() *tls.Config {
+	if hc.transport.TLSClientConfig == nil {
+		hc.transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return hc.transport.TLSClientConfig
+}
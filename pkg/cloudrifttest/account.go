@@ -0,0 +1,177 @@
+package cloudrifttest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+)
+
+// AutoTopUp returns the auto top-up settings of the account, nil if they were
+// never set.
+func (s *Server) AutoTopUp() *cloudriftapi.AutoTopUpSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.autoTopUp == nil {
+		return nil
+	}
+	settings := *s.autoTopUp
+	return &settings
+}
+
+// autoTopUpState derives the auto top-up state from the settings, as the API
+// does for an account without failed charges.
+func (s *Server) autoTopUpState() map[string]any {
+	status := cloudriftapi.AutoTopUpStatusOff
+	if s.autoTopUp != nil && s.autoTopUp.Enabled {
+		status = cloudriftapi.AutoTopUpStatusActive
+	}
+	return map[string]any{
+		"status":                 status,
+		"spent_this_month_cents": 0,
+		"settings":               s.autoTopUp,
+	}
+}
+
+// personalAccount reports whether the account selector of a request selects
+// the account of the API token, the only account of the fake.
+func personalAccount(selector json.RawMessage) bool {
+	if len(selector) == 0 || string(selector) == "null" {
+		return true
+	}
+	var named cloudriftapi.AccountSelector0
+	return json.Unmarshal(selector, &named) == nil && named == cloudriftapi.ByToken
+}
+
+func (s *Server) handleAccountInfo(w http.ResponseWriter, r *http.Request) {
+	// The balance is requested without a body, the auto top-up state with a
+	// versioned one.
+	var data struct {
+		Selector      json.RawMessage `json:"selector"`
+		WithAutoTopUp *bool           `json:"with_auto_top_up"`
+	}
+	version := ""
+	if b, _ := io.ReadAll(r.Body); len(b) > 0 {
+		var body struct {
+			Version string          `json:"version"`
+			Data    json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(b, &body); err != nil || json.Unmarshal(body.Data, &data) != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		version = body.Version
+	}
+	if !personalAccount(data.Selector) {
+		writeError(w, http.StatusBadRequest, "only the account of the API token is supported")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info := map[string]any{"balance": s.balance}
+	if data.WithAutoTopUp != nil && *data.WithAutoTopUp {
+		info["auto_top_up"] = s.autoTopUpState()
+	}
+	writeData(w, http.StatusOK, version, info)
+}
+
+func (s *Server) handleUpdateAutoTopUp(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Selector json.RawMessage                `json:"selector"`
+		Settings cloudriftapi.AutoTopUpSettings `json:"settings"`
+	}
+	version, ok := s.decodeRequest(w, r, &data)
+	if !ok {
+		return
+	}
+	if !personalAccount(data.Selector) {
+		writeError(w, http.StatusBadRequest, "only the account of the API token is supported")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.autoTopUp = &data.Settings
+	writeData(w, http.StatusOK, version, s.autoTopUpState())
+}
+
+func (s *Server) handleListTeams(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		WithAccountInfo *bool `json:"with_account_info"`
+	}
+	version, ok := s.decodeRequest(w, r, &data)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	teams := make([]cloudriftapi.TeamInfo, 0, len(s.teams))
+	for _, t := range s.teams {
+		if data.WithAccountInfo == nil || !*data.WithAccountInfo {
+			t.AccountInfo = nil
+		}
+		teams = append(teams, t)
+	}
+	writeData(w, http.StatusOK, version, map[string]any{"teams": teams, "total": len(teams)})
+}
+
+// knownAccount reports whether the account selector of a request selects
+// the account of the API token or one of its teams.
+func (s *Server) knownAccount(selector json.RawMessage) bool {
+	if personalAccount(selector) {
+		return true
+	}
+	var team cloudriftapi.AccountSelector1
+	return json.Unmarshal(selector, &team) == nil &&
+		slices.ContainsFunc(s.teams, func(t cloudriftapi.TeamInfo) bool { return t.Id == team.ByTeam })
+}
+
+func (s *Server) handleListTransactions(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Selector json.RawMessage `json:"selector"`
+		From     *string         `json:"from"`
+		To       *string         `json:"to"`
+	}
+	version, ok := s.decodeRequest(w, r, &data)
+	if !ok {
+		return
+	}
+
+	var from, to time.Time
+	for _, bound := range []struct {
+		value *string
+		into  *time.Time
+	}{{data.From, &from}, {data.To, &to}} {
+		if bound.value == nil {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, *bound.value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid time range: "+err.Error())
+			return
+		}
+		*bound.into = t
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.knownAccount(data.Selector) {
+		writeError(w, http.StatusBadRequest, "unknown account")
+		return
+	}
+
+	transactions := make([]cloudriftapi.Transaction, 0, len(s.transactions))
+	for _, t := range s.transactions {
+		created, err := time.Parse(time.RFC3339, t.CreatedAt)
+		if err == nil && ((!from.IsZero() && created.Before(from)) || (!to.IsZero() && created.After(to))) {
+			continue
+		}
+		transactions = append(transactions, t)
+	}
+	writeData(w, http.StatusOK, version, map[string]any{"transactions": transactions})
+}
@@ -0,0 +1,347 @@
+package cloudrifttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// CassetteMode selects whether a Cassette records the interactions with the
+// CloudRift API or replays them.
+type CassetteMode string
+
+const (
+	// CassetteRecord sends the requests to the API and records the scrubbed
+	// interactions, saved by Cassette.Save.
+	CassetteRecord CassetteMode = "record"
+	// CassetteReplay answers the requests with the recorded interactions,
+	// without any network access.
+	CassetteReplay CassetteMode = "replay"
+)
+
+// ParseCassetteMode parses the mode of a cassette, "" when the requests go to
+// the API unrecorded.
+func ParseCassetteMode(s string) (CassetteMode, error) {
+	switch mode := CassetteMode(s); mode {
+	case "", CassetteRecord, CassetteReplay:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown cassette mode %q, expected %q or %q", s, CassetteRecord, CassetteReplay)
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Body   string `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body,omitempty"`
+}
+
+// cassetteFile is the JSON layout of a cassette on disk.
+type cassetteFile struct {
+	Values       map[string]string `json:"values,omitempty"`
+	Interactions []Interaction     `json:"interactions"`
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	ipv4Pattern  = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	// addressPattern matches the JSON fields of the API holding addresses,
+	// a string or an array of strings. Other dotted quads, e.g. versions,
+	// are left alone.
+	addressPattern = regexp.MustCompile(`"(?:host_address|internal_host_address|ip_address|ip_addresses|local_ip|node_ip_address|public_ips|static_ip)"\s*:\s*(?:"[^"]*"|\[[^\]]*\])`)
+)
+
+// maxScrubbedAddresses is the number of addresses of the 192.0.2.0/24
+// documentation range the IPv4 addresses of a cassette are replaced by.
+const maxScrubbedAddresses = 254
+
+// ScrubbedEmail replaces the email addresses in the recorded interactions.
+const ScrubbedEmail = "user@example.com"
+
+// Cassette records the interactions of an HttpClient with the CloudRift API
+// into a file, or replays them from it. Install it with
+//
+//	cloudriftapi.WithTransportWrapper(cassette.Wrap)
+//
+// The interactions are scrubbed when saved: the request headers, with the
+// API token, are never recorded, the secrets registered with Redact are
+// replaced by their placeholder, the emails by ScrubbedEmail and the IPv4
+// addresses of the address fields by addresses of the 192.0.2.0/24
+// documentation range. Saving fails past maxScrubbedAddresses distinct
+// addresses rather than reusing one.
+//
+// A replayed request is answered by the first unused interaction with the
+// same method, path and body, else with the same method and path. Once they
+// are all used, the last one is repeated, e.g. for extra polls of a status.
+type Cassette struct {
+	path string
+	mode CassetteMode
+
+	mu           sync.Mutex
+	values       map[string]string
+	interactions []Interaction
+	used         []bool
+	redactions   []string
+	ips          map[string]string
+}
+
+// NewCassette returns the cassette at path, loaded from the file when
+// replaying.
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	c := &Cassette{
+		path:   path,
+		mode:   mode,
+		values: map[string]string{},
+		ips:    map[string]string{},
+	}
+
+	switch mode {
+	case CassetteRecord:
+	case CassetteReplay:
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cassette: %w", err)
+		}
+		var f cassetteFile
+		if err := json.Unmarshal(b, &f); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %q: %w", path, err)
+		}
+		if f.Values != nil {
+			c.values = f.Values
+		}
+		c.interactions = f.Interactions
+		c.used = make([]bool, len(f.Interactions))
+	default:
+		return nil, fmt.Errorf("cassette %q: unknown mode %q", path, mode)
+	}
+	return c, nil
+}
+
+// Mode returns the mode of the cassette.
+func (c *Cassette) Mode() CassetteMode {
+	return c.mode
+}
+
+// Redact replaces the secret by the placeholder in the saved interactions and
+// values. Empty secrets are ignored.
+func (c *Cassette) Redact(secret, placeholder string) {
+	if secret == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.redactions = append(c.redactions, secret, placeholder)
+}
+
+// Value returns the value when recording, and records it under the name.
+// When replaying, it returns the recorded value instead, so that the requests
+// of the test match the recorded ones, e.g. for generated names or values
+// read from the environment.
+func (c *Cassette) Value(name, value string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.mode == CassetteReplay {
+		recorded, ok := c.values[name]
+		if !ok {
+			return "", fmt.Errorf("cassette %q has no recorded value %q", c.path, name)
+		}
+		return recorded, nil
+	}
+	c.values[name] = value
+	return value, nil
+}
+
+// Save scrubs the recorded interactions and values, and writes them to the
+// file of the cassette, creating its directory. It does nothing when
+// replaying.
+func (c *Cassette) Save() error {
+	if c.mode != CassetteRecord {
+		return nil
+	}
+
+	c.mu.Lock()
+	f := cassetteFile{Values: map[string]string{}, Interactions: make([]Interaction, len(c.interactions))}
+	var err error
+	for i, interaction := range c.interactions {
+		if interaction.Request.Body, err = c.scrub(interaction.Request.Body); err != nil {
+			break
+		}
+		if interaction.Response.Body, err = c.scrub(interaction.Response.Body); err != nil {
+			break
+		}
+		f.Interactions[i] = interaction
+	}
+	for name, value := range c.values {
+		if err != nil {
+			break
+		}
+		f.Values[name], err = c.scrub(value)
+	}
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to scrub cassette %q: %w", c.path, err)
+	}
+
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette %q: %w", c.path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("failed to save cassette: %w", err)
+	}
+	if err := os.WriteFile(c.path, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to save cassette: %w", err)
+	}
+	return nil
+}
+
+// Wrap returns the transport recording the requests sent through next, or
+// replaying them without using next at all.
+func (c *Cassette) Wrap(next http.RoundTripper) http.RoundTripper {
+	return &cassetteTransport{cassette: c, next: next}
+}
+
+// scrub must be called with c.mu held.
+func (c *Cassette) scrub(s string) (string, error) {
+	if len(c.redactions) > 0 {
+		s = strings.NewReplacer(c.redactions...).Replace(s)
+	}
+	s = emailPattern.ReplaceAllString(s, ScrubbedEmail)
+
+	var err error
+	s = addressPattern.ReplaceAllStringFunc(s, func(field string) string {
+		return ipv4Pattern.ReplaceAllStringFunc(field, func(addr string) string {
+			ip := net.ParseIP(addr)
+			if ip == nil || ip.IsLoopback() || ip.IsUnspecified() {
+				return addr
+			}
+			scrubbed, ok := c.ips[addr]
+			if !ok {
+				if len(c.ips) == maxScrubbedAddresses {
+					err = fmt.Errorf("more than %d distinct addresses to scrub", maxScrubbedAddresses)
+					return addr
+				}
+				// The same address is always replaced by the same one, so
+				// that the recorded state stays consistent.
+				scrubbed = fmt.Sprintf("192.0.2.%d", len(c.ips)+1)
+				c.ips[addr] = scrubbed
+			}
+			return scrubbed
+		})
+	})
+	return s, err
+}
+
+func (c *Cassette) record(req RecordedRequest, resp RecordedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.interactions = append(c.interactions, Interaction{Request: req, Response: resp})
+}
+
+func (c *Cassette) replay(req RecordedRequest) (RecordedResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	matchesPath := func(i int) bool {
+		return c.interactions[i].Request.Method == req.Method && c.interactions[i].Request.Path == req.Path
+	}
+	last := -1
+	for _, exactBody := range []bool{true, false} {
+		for i := range c.interactions {
+			if !matchesPath(i) {
+				continue
+			}
+			last = i
+			if c.used[i] || (exactBody && c.interactions[i].Request.Body != req.Body) {
+				continue
+			}
+			c.used[i] = true
+			return c.interactions[i].Response, nil
+		}
+	}
+	if last < 0 {
+		return RecordedResponse{}, fmt.Errorf("cassette %q has no recorded interaction for %s %s", c.path, req.Method, req.Path)
+	}
+	return c.interactions[last].Response, nil
+}
+
+type cassetteTransport struct {
+	cassette *Cassette
+	next     http.RoundTripper
+}
+
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded := RecordedRequest{Method: req.Method, Path: req.URL.Path}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the request body: %w", err)
+		}
+		recorded.Body = string(body)
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if t.cassette.mode == CassetteReplay {
+		r, err := t.cassette.replay(recorded)
+		if err != nil {
+			return nil, err
+		}
+		resp := &http.Response{
+			Status:        fmt.Sprintf("%d %s", r.Status, http.StatusText(r.Status)),
+			StatusCode:    r.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        http.Header{},
+			Body:          io.NopCloser(strings.NewReader(r.Body)),
+			ContentLength: int64(len(r.Body)),
+			Request:       req,
+		}
+		if r.ContentType != "" {
+			resp.Header.Set("Content-Type", r.ContentType)
+		}
+		return resp, nil
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.cassette.record(recorded, RecordedResponse{
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        string(body),
+	})
+	return resp, nil
+}
@@ -0,0 +1,153 @@
+package cloudrifttest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+)
+
+const cassettePublicKey = "ssh-ed25519 AAAA secret-key"
+
+// cassetteScenario adds an SSH key, rents an instance and returns the
+// instance as listed once active.
+func cassetteScenario(t *testing.T, client *cloudriftapi.HttpClient, keyName string) *cloudriftapi.InstanceAndUsageInfo {
+	t.Helper()
+
+	if _, err := client.AddSSHKey(keyName, cassettePublicKey); err != nil {
+		t.Fatalf("AddSSHKey: %v", err)
+	}
+	resp, err := client.RentPublicInstanceVM(cloudriftapi.RentVMOptions{
+		Recipe:       DefaultRecipes[0].Name,
+		Datacenter:   DefaultDatacenter,
+		InstanceType: "rtx49-10c-kn.1",
+		PublicKeys:   []string{cassettePublicKey},
+	})
+	if err != nil {
+		t.Fatalf("RentPublicInstanceVM: %v", err)
+	}
+	inst, err := client.GetInstance(resp.Data.InstanceIds[0])
+	if err != nil {
+		t.Fatalf("GetInstance: %v", err)
+	}
+	return inst
+}
+
+func Test_Cassette_RecordAndReplay(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "cassettes", "lifecycle.json")
+
+	s := NewServer(WithToken("secret-token"))
+	recorder, err := NewCassette(path, CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.Redact(cassettePublicKey, "ssh-ed25519 REDACTED")
+	keyName, _ := recorder.Value("key_name", "key-1")
+
+	client, err := cloudriftapi.NewCustom(s.URL, "secret-token", "", "", cloudriftapi.WithTransportWrapper(recorder.Wrap))
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	recorded := cassetteScenario(t, client, keyName)
+	if recorded.HostAddress == nil || !strings.HasPrefix(*recorded.HostAddress, "203.0.113.") {
+		t.Fatalf("expected the address of the fake while recording, got %+v", recorded.HostAddress)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	s.Close()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-token", "secret-key", "203.0.113.", Email} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("expected %q to be scrubbed from the cassette:\n%s", secret, b)
+		}
+	}
+
+	// The server is closed, the replayed client never reaches the network.
+	player, err := NewCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyName, err = player.Value("key_name", "")
+	if err != nil || keyName != "key-1" {
+		t.Fatalf("expected the recorded key name, got %q, %v", keyName, err)
+	}
+	client, err = cloudriftapi.NewCustom(s.URL, "any", "", "", cloudriftapi.WithTransportWrapper(player.Wrap))
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	replayed := cassetteScenario(t, client, keyName)
+	if replayed.Id != recorded.Id || replayed.Status != cloudriftapi.InstanceStatusActive {
+		t.Errorf("expected the recorded instance %q, got %+v", recorded.Id, replayed)
+	}
+	if replayed.HostAddress == nil || !strings.HasPrefix(*replayed.HostAddress, "192.0.2.") {
+		t.Errorf("expected a scrubbed address, got %+v", replayed.HostAddress)
+	}
+
+	// Requests beyond the recorded ones repeat the last response of the path.
+	if again, err := client.GetInstance(recorded.Id); err != nil || again.Status != cloudriftapi.InstanceStatusActive {
+		t.Errorf("expected the last response to be repeated, got %+v, %v", again, err)
+	}
+	if err := client.DeleteSSHKey("key-id"); err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Errorf("expected an unrecorded request to fail, got %v", err)
+	}
+}
+
+func Test_Cassette_Errors(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ParseCassetteMode("rewind"); err == nil {
+		t.Error("expected an unknown mode to be rejected")
+	}
+	if _, err := NewCassette(filepath.Join(t.TempDir(), "missing.json"), CassetteReplay); err == nil {
+		t.Error("expected a missing cassette to fail replaying")
+	}
+
+	path := filepath.Join(t.TempDir(), "empty.json")
+	if err := os.WriteFile(path, []byte(`{"interactions": []}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := NewCassette(path, CassetteReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Value("key_name", "new"); err == nil {
+		t.Error("expected a value missing from the cassette to fail")
+	}
+}
+
+func Test_Cassette_Scrub(t *testing.T) {
+	t.Parallel()
+
+	c, err := NewCassette(filepath.Join(t.TempDir(), "scrub.json"), CassetteRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.scrub(`{"host_address": "203.0.113.7", "public_ips": ["198.51.100.1", "203.0.113.7"], "driver": "550.54.14.1", "local_ip": "127.0.0.1"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"host_address": "192.0.2.1", "public_ips": ["192.0.2.2", "192.0.2.1"], "driver": "550.54.14.1", "local_ip": "127.0.0.1"}`
+	if got != want {
+		t.Errorf("unexpected scrubbed body\ngot:  %s\nwant: %s", got, want)
+	}
+
+	for i := range maxScrubbedAddresses {
+		if _, err := c.scrub(fmt.Sprintf(`{"host_address": "10.0.%d.%d"}`, i/256, i%256)); err != nil {
+			if i+2 < maxScrubbedAddresses {
+				t.Fatalf("unexpected error after %d addresses: %v", i+2, err)
+			}
+			return
+		}
+	}
+	t.Error("expected scrubbing to fail once the documentation range is exhausted")
+}
@@ -0,0 +1,457 @@
+package cloudrifttest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+)
+
+// Timeline is how long a rented instance stays in each transitional status:
+// Initializing, then Active, and once terminated Deactivating, then Inactive.
+// The zero Timeline activates and deactivates the instances right away.
+type Timeline struct {
+	// Unlisted is how long a rented instance is missing from the instance
+	// lists, as the API lists new instances with a delay.
+	Unlisted     time.Duration
+	Initializing time.Duration
+	// Deactivating is negative for instances stuck in Deactivating forever.
+	Deactivating time.Duration
+	// Outcome is the status the initialization ends in, Active if empty, e.g.
+	// Failed for an instance that never comes up.
+	Outcome cloudriftapi.InstanceStatus
+}
+
+// LoginUsername is the user of the Virtual Machines, logging in with the
+// password of their instance.
+const LoginUsername = "riftuser"
+
+// instructionsTemplate is the template of the instructions of an instance to
+// log in, as the API renders it once decoded.
+const instructionsTemplate = "ssh {user}@{host}, password: {password}"
+
+type instance struct {
+	info       cloudriftapi.InstanceAndUsageInfo
+	password   string
+	cluster    string
+	teamID     string
+	timeline   Timeline
+	rentedAt   time.Time
+	terminated time.Time
+	// status overrides the Timeline once set with SetInstanceStatus.
+	status cloudriftapi.InstanceStatus
+}
+
+func (i *instance) currentStatus(now time.Time) cloudriftapi.InstanceStatus {
+	switch {
+	case i.status != "":
+		return i.status
+	case !i.terminated.IsZero():
+		if i.timeline.Deactivating < 0 || now.Sub(i.terminated) < i.timeline.Deactivating {
+			return cloudriftapi.InstanceStatusDeactivating
+		}
+		return cloudriftapi.InstanceStatusInactive
+	case now.Sub(i.rentedAt) < i.timeline.Initializing:
+		return cloudriftapi.InstanceStatusInitializing
+	case i.timeline.Outcome != "":
+		return i.timeline.Outcome
+	default:
+		return cloudriftapi.InstanceStatusActive
+	}
+}
+
+// snapshot returns the instance as listed at now.
+func (i *instance) snapshot(now time.Time) cloudriftapi.InstanceAndUsageInfo {
+	info := i.info
+	info.Status = i.currentStatus(now)
+	info.VirtualMachines = slices.Clone(i.info.VirtualMachines)
+	ready := info.Status == cloudriftapi.InstanceStatusActive
+	for vm := range info.VirtualMachines {
+		info.VirtualMachines[vm].Ready = ready
+		info.VirtualMachines[vm].State = cloudriftapi.Shutoff
+		if ready {
+			info.VirtualMachines[vm].State = cloudriftapi.Running
+		}
+	}
+	if !ready {
+		info.HostAddress, info.InternalHostAddress = nil, nil
+	}
+	return info
+}
+
+// withCredentials sets the login info of the Virtual Machines of a snapshot
+// and the instructions to log in, as listed with the credentials mask.
+func (i *instance) withCredentials(info *cloudriftapi.InstanceAndUsageInfo) {
+	for vm := range info.VirtualMachines {
+		var login cloudriftapi.InstanceLoginInfo
+		var password cloudriftapi.InstanceLoginInfo0
+		password.UsernameAndPassword.Username, password.UsernameAndPassword.Password = LoginUsername, i.password
+		_ = login.FromInstanceLoginInfo0(password)
+		info.VirtualMachines[vm].LoginInfo = &login
+	}
+	if info.HostAddress != nil {
+		info.Instructions = &cloudriftapi.InstanceUserInstructions{
+			InstructionsTemplate: base64.StdEncoding.EncodeToString([]byte(instructionsTemplate)),
+			PlaceholderValues: [][]interface{}{
+				{"{user}", LoginUsername},
+				{"{host}", *info.HostAddress},
+				{"{password}", i.password},
+			},
+		}
+	}
+}
+
+// withoutCredentials sets the login info of the Virtual Machines of a
+// snapshot, withholding the password as the API does without the credentials
+// mask.
+func (i *instance) withoutCredentials(info *cloudriftapi.InstanceAndUsageInfo) {
+	for vm := range info.VirtualMachines {
+		var login cloudriftapi.InstanceLoginInfo
+		var hidden cloudriftapi.InstanceLoginInfo2
+		hidden.HiddenPassword.Username = LoginUsername
+		_ = login.FromInstanceLoginInfo2(hidden)
+		info.VirtualMachines[vm].LoginInfo = &login
+	}
+}
+
+// Instances returns the instances ever rented, in their current status and
+// with their credentials.
+func (s *Server) Instances() []cloudriftapi.InstanceAndUsageInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	out := make([]cloudriftapi.InstanceAndUsageInfo, 0, len(s.instances))
+	for _, i := range s.instances {
+		info := i.snapshot(now)
+		i.withCredentials(&info)
+		out = append(out, info)
+	}
+	return out
+}
+
+// ClusterInstances returns the instances ever rented in the cluster, in their
+// current status.
+func (s *Server) ClusterInstances(cluster string) []cloudriftapi.InstanceAndUsageInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var out []cloudriftapi.InstanceAndUsageInfo
+	for _, i := range s.instances {
+		if i.cluster == cluster {
+			out = append(out, i.snapshot(now))
+		}
+	}
+	return out
+}
+
+// SetInstanceStatus pins the status of the instance, regardless of its
+// Timeline, e.g. to simulate a crash of an active instance.
+func (s *Server) SetInstanceStatus(id string, status cloudriftapi.InstanceStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, i := range s.instances {
+		if i.info.Id == id {
+			i.status = status
+			return nil
+		}
+	}
+	return fmt.Errorf("instance %q not found", id)
+}
+
+func (s *Server) handleRent(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Selector struct {
+			ByInstanceTypeAndLocation *struct {
+				InstanceType string   `json:"instance_type"`
+				Datacenters  []string `json:"datacenters"`
+			} `json:"ByInstanceTypeAndLocation"`
+			ByNodeId *struct {
+				InstanceType string `json:"instance_type"`
+				NodeId       string `json:"node_id"`
+			} `json:"ByNodeId"`
+		} `json:"selector"`
+		Config struct {
+			VirtualMachine *struct {
+				ImageURL string `json:"image_url"`
+			} `json:"VirtualMachine"`
+		} `json:"config"`
+		Name        *string `json:"name"`
+		ClusterName *string `json:"cluster_name"`
+		TeamID      *string `json:"team_id"`
+	}
+	version, ok := s.decodeRequest(w, r, &data)
+	if !ok {
+		return
+	}
+
+	var instanceType, datacenter string
+	switch sel := data.Selector; {
+	case sel.ByInstanceTypeAndLocation != nil:
+		instanceType = sel.ByInstanceTypeAndLocation.InstanceType
+		if len(sel.ByInstanceTypeAndLocation.Datacenters) > 0 {
+			datacenter = sel.ByInstanceTypeAndLocation.Datacenters[0]
+		}
+	case sel.ByNodeId != nil:
+		instanceType = sel.ByNodeId.InstanceType
+	default:
+		writeError(w, http.StatusBadRequest, "unsupported node selector")
+		return
+	}
+	vm := data.Config.VirtualMachine
+	if vm == nil || vm.ImageURL == "" {
+		writeError(w, http.StatusBadRequest, "only Virtual Machines with an image_url are supported")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	variant, ok := s.findVariant(instanceType)
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown instance type %q", instanceType))
+		return
+	}
+	if datacenter != "" && variant.NodesPerDc[datacenter] == 0 {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("no nodes of instance type %q in datacenter %q", instanceType, datacenter))
+		return
+	}
+
+	s.nextID++
+	n := strconv.Itoa(s.nextID)
+	host, internal := "203.0.113."+n, "10.0.0."+n
+	inst := &instance{
+		info: cloudriftapi.InstanceAndUsageInfo{
+			Id:                  "instance-" + n,
+			NodeId:              "node-" + n,
+			NodeMode:            cloudriftapi.VirtualMachine,
+			NodeStatus:          cloudriftapi.Ready,
+			CreatedAt:           time.Now().UTC().Format(time.RFC3339),
+			InstanceName:        data.Name,
+			HostAddress:         &host,
+			InternalHostAddress: &internal,
+			SshKeyAuth:          true,
+			Containers:          []cloudriftapi.InstanceContainerInfo{},
+			ResourceInfo: &cloudriftapi.InstanceResourceInfo{
+				ProviderName: "cloudrifttest",
+				InstanceType: instanceType,
+				CostPerHour:  float32(variant.CostPerHour),
+			},
+			VirtualMachines: []cloudriftapi.InstanceVirtualMachineInfo{{Vmid: int32(100 + s.nextID), Name: "vm-" + n}},
+		},
+		password: "password-" + n,
+		timeline: s.timeline,
+		rentedAt: time.Now(),
+	}
+	if data.ClusterName != nil {
+		inst.cluster = *data.ClusterName
+	}
+	if data.TeamID != nil {
+		inst.teamID = *data.TeamID
+	}
+	s.instances = append(s.instances, inst)
+
+	writeData(w, http.StatusOK, version, map[string]any{"instance_ids": []string{inst.info.Id}})
+}
+
+func (s *Server) findVariant(name string) (cloudriftapi.InstanceVariantInfo, bool) {
+	for _, t := range s.instanceTypes {
+		for _, v := range t.Variants {
+			if v.Name == name {
+				return v, true
+			}
+		}
+	}
+	return cloudriftapi.InstanceVariantInfo{}, false
+}
+
+// instancesSelector is the subset of cloudriftapi.InstancesSelector the fake
+// understands.
+type instancesSelector struct {
+	ById     []string `json:"ById"`
+	ByStatus *struct {
+		Statuses []cloudriftapi.InstanceStatus `json:"statuses"`
+		Scope    json.RawMessage               `json:"scope"`
+	} `json:"ByStatus"`
+	ByClusterName *string `json:"ByClusterName"`
+}
+
+// selectInstances returns the listed instances matched by the selector at
+// now, an error for unsupported selectors.
+func (s *Server) selectInstances(sel instancesSelector, now time.Time) ([]*instance, error) {
+	var match func(i *instance) bool
+	switch {
+	case sel.ById != nil:
+		match = func(i *instance) bool { return slices.Contains(sel.ById, i.info.Id) }
+	case sel.ByClusterName != nil:
+		match = func(i *instance) bool { return i.cluster == *sel.ByClusterName }
+	case sel.ByStatus != nil:
+		inScope, err := scopeMatcher(sel.ByStatus.Scope)
+		if err != nil {
+			return nil, err
+		}
+		match = func(i *instance) bool {
+			return slices.Contains(sel.ByStatus.Statuses, i.currentStatus(now)) && inScope(i)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported instances selector")
+	}
+
+	var out []*instance
+	for _, i := range s.instances {
+		if now.Sub(i.rentedAt) >= i.timeline.Unlisted && match(i) {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
+
+// scopeMatcher matches the instances within the cloudriftapi.SelectorScope,
+// instances of a team are only listed within the scope of the team.
+func scopeMatcher(raw json.RawMessage) (func(i *instance) bool, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		raw = json.RawMessage(`"Personal"`)
+	}
+
+	var named string
+	if json.Unmarshal(raw, &named) == nil {
+		switch named {
+		case string(cloudriftapi.Personal):
+			return func(i *instance) bool { return i.teamID == "" }, nil
+		case string(cloudriftapi.SelectorScope3All):
+			return func(*instance) bool { return true }, nil
+		}
+		return nil, fmt.Errorf("unsupported selector scope %q", named)
+	}
+
+	var scope struct {
+		Teams            []string `json:"Teams"`
+		PersonalAndTeams []string `json:"PersonalAndTeams"`
+	}
+	if err := json.Unmarshal(raw, &scope); err != nil {
+		return nil, fmt.Errorf("invalid selector scope: %w", err)
+	}
+	return func(i *instance) bool {
+		if scope.PersonalAndTeams != nil {
+			return i.teamID == "" || slices.Contains(scope.PersonalAndTeams, i.teamID)
+		}
+		return slices.Contains(scope.Teams, i.teamID)
+	}, nil
+}
+
+func (s *Server) handleListInstances(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Selector instancesSelector `json:"selector"`
+		Mask     *struct {
+			WithConnectionInfo *bool `json:"with_connection_info"`
+			WithCredentials    *bool `json:"with_credentials"`
+		} `json:"mask"`
+	}
+	version, ok := s.decodeRequest(w, r, &data)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	selected, err := s.selectInstances(data.Selector, now)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// As the API, the addresses are only listed with the connection-info mask
+	// and the passwords with the credentials one.
+	withConnectionInfo := data.Mask != nil && data.Mask.WithConnectionInfo != nil && *data.Mask.WithConnectionInfo
+	withCredentials := data.Mask != nil && data.Mask.WithCredentials != nil && *data.Mask.WithCredentials
+	instances := make([]cloudriftapi.InstanceAndUsageInfo, 0, len(selected))
+	for _, i := range selected {
+		info := i.snapshot(now)
+		if !withConnectionInfo {
+			info.HostAddress, info.InternalHostAddress = nil, nil
+		}
+		if withCredentials {
+			i.withCredentials(&info)
+		} else {
+			i.withoutCredentials(&info)
+		}
+		instances = append(instances, info)
+	}
+	writeData(w, http.StatusOK, version, map[string]any{"instances": instances})
+}
+
+func (s *Server) handleTerminate(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Selector instancesSelector `json:"selector"`
+	}
+	version, ok := s.decodeRequest(w, r, &data)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	selected, err := s.selectInstances(data.Selector, now)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	instances := make([]cloudriftapi.InstanceAndUsageInfo, 0, len(selected))
+	for _, i := range selected {
+		if i.terminated.IsZero() {
+			i.terminated = now
+		}
+		instances = append(instances, i.snapshot(now))
+	}
+	writeData(w, http.StatusOK, version, map[string]any{"instances": instances})
+}
+
+func (s *Server) handleListSavedEnvironments(w http.ResponseWriter, r *http.Request) {
+	var data json.RawMessage
+	version, ok := s.decodeRequest(w, r, &data)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	envs := s.savedEnvironments
+	if envs == nil {
+		envs = []cloudriftapi.SavedEnvironment{}
+	}
+	writeData(w, http.StatusOK, version, map[string]any{"saved_environments": envs})
+}
+
+func (s *Server) handleInstanceMetrics(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Selector struct {
+			ById []string `json:"ById"`
+		} `json:"selector"`
+	}
+	version, ok := s.decodeRequest(w, r, &data)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	metrics := make([]cloudriftapi.InstanceMetrics, 0, len(data.Selector.ById))
+	for _, m := range s.metrics {
+		if slices.Contains(data.Selector.ById, m.InstanceId) {
+			metrics = append(metrics, m)
+		}
+	}
+	writeData(w, http.StatusOK, version, map[string]any{"metrics": metrics})
+}
@@ -0,0 +1,559 @@
+// Package cloudrifttest provides a stateful in-memory fake of the CloudRift
+// API, so tests of the provider, and of the modules using it, run offline
+// against realistic behavior:
+//
+//	server := cloudrifttest.NewServer(cloudrifttest.WithTimeline(cloudrifttest.Timeline{
+//		Initializing: 200 * time.Millisecond,
+//	}))
+//	defer server.Close()
+//
+//	client, err := cloudriftapi.NewCustom(server.URL, "token", "", "")
+//
+// The fake covers authentication, capabilities, recipes, instance types, SSH
+// keys, the balance, auto top-up and transactions of the account, teams,
+// saved environments, instance metrics and the rent, list and terminate
+// lifecycle of instances. Latency, rate limiting and failures of single
+// endpoints can be simulated on top.
+//
+// A Cassette records the interactions of a client with the live API instead,
+// and replays them offline.
+package cloudrifttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+)
+
+// Email is the email of the account behind every accepted API token.
+const Email = "test@cloudrift.test"
+
+type ServerOption func(*Server)
+
+// WithToken only accepts requests authenticated with the API token, by
+// default any non-empty token is accepted.
+func WithToken(token string) ServerOption {
+	return func(s *Server) {
+		s.token = token
+	}
+}
+
+// WithLatency delays every response by d.
+func WithLatency(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.latency = d
+	}
+}
+
+// WithRateLimit answers with 429 Too Many Requests once more than limit
+// requests were received within window.
+func WithRateLimit(limit int, window time.Duration) ServerOption {
+	return func(s *Server) {
+		s.rateLimit, s.rateWindow = limit, window
+	}
+}
+
+// WithProtoVersions only accepts requests in one of the protocol versions,
+// newest first, and rejects the others as "unsupported version". By default
+// every version is accepted.
+func WithProtoVersions(versions ...string) ServerOption {
+	return func(s *Server) {
+		s.protoVersions = versions
+	}
+}
+
+// WithTimeline sets the Timeline of the rented instances.
+func WithTimeline(timeline Timeline) ServerOption {
+	return func(s *Server) {
+		s.timeline = timeline
+	}
+}
+
+// WithRecipes replaces the DefaultRecipes of the catalog.
+func WithRecipes(recipes ...Recipe) ServerOption {
+	return func(s *Server) {
+		s.recipes = recipes
+	}
+}
+
+// WithInstanceTypes replaces the DefaultInstanceTypes of the catalog.
+func WithInstanceTypes(instanceTypes ...cloudriftapi.InstanceType) ServerOption {
+	return func(s *Server) {
+		s.instanceTypes = instanceTypes
+	}
+}
+
+// WithSSHKeys adds the SSH keys to the account.
+func WithSSHKeys(keys ...cloudriftapi.SshKey) ServerOption {
+	return func(s *Server) {
+		s.sshKeys = append(s.sshKeys, keys...)
+	}
+}
+
+// WithBalance sets the balance of the account in USD, 0 by default.
+func WithBalance(usd float64) ServerOption {
+	return func(s *Server) {
+		s.balance = usd
+	}
+}
+
+// WithSavedEnvironments adds the saved environments to the account.
+func WithSavedEnvironments(envs ...cloudriftapi.SavedEnvironment) ServerOption {
+	return func(s *Server) {
+		s.savedEnvironments = append(s.savedEnvironments, envs...)
+	}
+}
+
+// WithTeams adds the teams to the ones the owner of the API token is a
+// member of.
+func WithTeams(teams ...cloudriftapi.TeamInfo) ServerOption {
+	return func(s *Server) {
+		s.teams = append(s.teams, teams...)
+	}
+}
+
+// WithTransactions adds the transactions to the ledger of the account, which
+// its teams share.
+func WithTransactions(transactions ...cloudriftapi.Transaction) ServerOption {
+	return func(s *Server) {
+		s.transactions = append(s.transactions, transactions...)
+	}
+}
+
+// WithInstanceMetrics sets the hardware metrics reported for the instances
+// of the metrics, the other instances report none.
+func WithInstanceMetrics(metrics ...cloudriftapi.InstanceMetrics) ServerOption {
+	return func(s *Server) {
+		s.metrics = append(s.metrics, metrics...)
+	}
+}
+
+// Recipe is a Virtual Machine recipe of the catalog.
+type Recipe struct {
+	Name         string
+	Description  string
+	ImageURL     string
+	CloudInitURL string
+	Tags         []string
+}
+
+// DefaultRecipes are the recipes of the catalog unless set with WithRecipes.
+var DefaultRecipes = []Recipe{
+	{
+		Name:         "Ubuntu 24.04 Server",
+		Description:  "Ubuntu 24.04 LTS",
+		ImageURL:     "https://images.cloudrift.test/ubuntu-24.04.img",
+		CloudInitURL: "https://images.cloudrift.test/ubuntu-24.04.yaml",
+	},
+	{
+		Name:         "Ubuntu 22.04 Server",
+		Description:  "Ubuntu 22.04 LTS",
+		ImageURL:     "https://images.cloudrift.test/ubuntu-22.04.img",
+		CloudInitURL: "https://images.cloudrift.test/ubuntu-22.04.yaml",
+	},
+}
+
+// DefaultDatacenter is the datacenter of the DefaultInstanceTypes.
+const DefaultDatacenter = "us-east-nc-nr-1"
+
+// DefaultInstanceTypes are the instance types of the catalog unless set with
+// WithInstanceTypes.
+var DefaultInstanceTypes = []cloudriftapi.InstanceType{
+	{
+		Name: "rtx49",
+		Variants: []cloudriftapi.InstanceVariantInfo{{
+			Name:                "rtx49-10c-kn.1",
+			CpuCount:            10,
+			LogicalCpuCount:     20,
+			GpuCount:            ptr[int32](1),
+			Disk:                1 << 40,
+			Dram:                64 << 30,
+			Vram:                24 << 30,
+			CostPerHour:         0.85,
+			AvailableNodes:      2,
+			AvailableNodesPerDc: map[string]int32{DefaultDatacenter: 2},
+			Nodes:               2,
+			NodesPerDc:          map[string]int32{DefaultDatacenter: 2},
+			IpAvailabilityPerDc: map[string]cloudriftapi.IpAvailability{DefaultDatacenter: {PublicIps: true}},
+		}},
+	},
+}
+
+// Fault makes the server fail requests to Path, e.g. "/api/v1/instances/rent",
+// with Status and Body.
+type Fault struct {
+	Path   string
+	Status int
+	Body   string
+	// Match restricts the fault to the requests whose body contains it, e.g.
+	// an instance ID, every request to Path fails if empty.
+	Match string
+	// Times is the number of requests to fail, every request if 0.
+	Times int
+}
+
+// Server is the fake CloudRift API, close it when done.
+type Server struct {
+	*httptest.Server
+
+	mux *http.ServeMux
+
+	mu            sync.Mutex
+	token         string
+	latency       time.Duration
+	rateLimit     int
+	rateWindow    time.Duration
+	rateStart     time.Time
+	rateCount     int
+	protoVersions []string
+	timeline      Timeline
+	faults        []*Fault
+	requests      map[string]int
+	lastBodies    map[string][]byte
+	lastHeaders   map[string]http.Header
+
+	recipes           []Recipe
+	instanceTypes     []cloudriftapi.InstanceType
+	sshKeys           []cloudriftapi.SshKey
+	balance           float64
+	autoTopUp         *cloudriftapi.AutoTopUpSettings
+	savedEnvironments []cloudriftapi.SavedEnvironment
+	teams             []cloudriftapi.TeamInfo
+	transactions      []cloudriftapi.Transaction
+	metrics           []cloudriftapi.InstanceMetrics
+	instances         []*instance
+	nextID            int
+}
+
+// NewServer starts the fake CloudRift API.
+func NewServer(opts ...ServerOption) *Server {
+	s := &Server{
+		mux:           http.NewServeMux(),
+		requests:      make(map[string]int),
+		lastBodies:    make(map[string][]byte),
+		lastHeaders:   make(map[string]http.Header),
+		recipes:       DefaultRecipes,
+		instanceTypes: DefaultInstanceTypes,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+
+	s.mux.HandleFunc("POST /api/v1/auth/me", s.handleAuth)
+	s.mux.HandleFunc("POST /api/v1/capabilities/list", s.handleCapabilities)
+	s.mux.HandleFunc("POST /api/v1/recipes/list", s.handleListRecipes)
+	s.mux.HandleFunc("POST /api/v1/instance-types/list", s.handleListInstanceTypes)
+	s.mux.HandleFunc("POST /api/v1/ssh-keys/list", s.handleListSSHKeys)
+	s.mux.HandleFunc("POST /api/v1/ssh-keys/add", s.handleAddSSHKey)
+	s.mux.HandleFunc("DELETE /api/v1/ssh-keys/{id}", s.handleDeleteSSHKey)
+	s.mux.HandleFunc("POST /api/v1/instances/rent", s.handleRent)
+	s.mux.HandleFunc("POST /api/v1/instances/list", s.handleListInstances)
+	s.mux.HandleFunc("POST /api/v1/instances/terminate", s.handleTerminate)
+	s.mux.HandleFunc("POST /api/v1/instances/saved-environments/list", s.handleListSavedEnvironments)
+	s.mux.HandleFunc("POST /api/v1/instances/metrics", s.handleInstanceMetrics)
+	s.mux.HandleFunc("POST /api/v1/account/info", s.handleAccountInfo)
+	s.mux.HandleFunc("POST /api/v1/account/auto-top-up/update", s.handleUpdateAutoTopUp)
+	s.mux.HandleFunc("POST /api/v1/account/transactions/list", s.handleListTransactions)
+	s.mux.HandleFunc("POST /api/v1/teams/list", s.handleListTeams)
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// InjectFault fails the next requests to the path of the fault, after the
+// faults injected before for the same path are used up.
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// SetLatency delays every response from now on by d, e.g. once a client is
+// set up, to slow down only the requests under test.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// Requests returns the number of requests received for the path.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// LastRequest decodes the data of the last request to the path, e.g.
+// "/api/v1/instances/rent", into data.
+func (s *Server) LastRequest(path string, data any) error {
+	s.mu.Lock()
+	b, ok := s.lastBodies[path]
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no request to %s", path)
+	}
+
+	var body struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		return fmt.Errorf("decoding the last request to %s: %w", path, err)
+	}
+	return json.Unmarshal(body.Data, data)
+}
+
+// LastRequestHeader returns the headers of the last request to the path, nil
+// if there was none.
+func (s *Server) LastRequestHeader(path string) http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastHeaders[path].Clone()
+}
+
+// SSHKeys returns the SSH keys of the account.
+func (s *Server) SSHKeys() []cloudriftapi.SshKey {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.sshKeys)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "reading request body: "+err.Error())
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	s.lastBodies[r.URL.Path] = body
+	s.lastHeaders[r.URL.Path] = r.Header.Clone()
+	latency := s.latency
+	limited := s.rateLimited(time.Now())
+	fault := s.takeFault(r.URL.Path, body)
+	s.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	switch {
+	case limited:
+		w.Header().Set("Retry-After", strconv.Itoa(int(s.rateWindow.Seconds())+1))
+		writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+	case fault != nil:
+		w.WriteHeader(fault.Status)
+		_, _ = io.WriteString(w, fault.Body)
+	case r.Header.Get("X-API-KEY") == "" || (s.token != "" && r.Header.Get("X-API-KEY") != s.token):
+		writeError(w, http.StatusUnauthorized, "invalid api key")
+	default:
+		s.mux.ServeHTTP(w, r)
+	}
+}
+
+// rateLimited counts the request in the current window of the rate limit.
+func (s *Server) rateLimited(now time.Time) bool {
+	if s.rateLimit <= 0 {
+		return false
+	}
+	if now.Sub(s.rateStart) >= s.rateWindow {
+		s.rateStart, s.rateCount = now, 0
+	}
+	s.rateCount++
+	return s.rateCount > s.rateLimit
+}
+
+func (s *Server) takeFault(path string, body []byte) *Fault {
+	for i, f := range s.faults {
+		if f.Path != path || !bytes.Contains(body, []byte(f.Match)) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = slices.Delete(s.faults, i, i+1)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// decodeRequest decodes the data of a versioned request, writing the error
+// response if the body is invalid or the version is not accepted.
+func (s *Server) decodeRequest(w http.ResponseWriter, r *http.Request, data any) (string, bool) {
+	var body struct {
+		Version string          `json:"version"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return "", false
+	}
+
+	s.mu.Lock()
+	accepted := len(s.protoVersions) == 0 || slices.Contains(s.protoVersions, body.Version)
+	s.mu.Unlock()
+	if !accepted {
+		writeError(w, http.StatusBadRequest, "unsupported version "+body.Version)
+		return "", false
+	}
+
+	if err := json.Unmarshal(body.Data, data); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request data: "+err.Error())
+		return "", false
+	}
+	return body.Version, true
+}
+
+func (s *Server) latestProtoVersion() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.protoVersions) == 0 {
+		return cloudriftapi.ProtoUpcoming
+	}
+	return s.protoVersions[0]
+}
+
+func (s *Server) handleAuth(w http.ResponseWriter, _ *http.Request) {
+	writeData(w, http.StatusOK, "", map[string]any{"email": Email})
+}
+
+func (s *Server) handleCapabilities(w http.ResponseWriter, _ *http.Request) {
+	writeData(w, http.StatusOK, s.latestProtoVersion(), map[string]any{
+		"features": []any{},
+		"is_admin": false,
+	})
+}
+
+func (s *Server) handleListRecipes(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	recipes := make([]map[string]any, 0, len(s.recipes))
+	for _, r := range s.recipes {
+		tags := r.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		recipes = append(recipes, map[string]any{
+			"name":        r.Name,
+			"description": r.Description,
+			"tags":        tags,
+			"details": map[string]any{
+				"VirtualMachine": map[string]string{
+					"image_url":     r.ImageURL,
+					"cloudinit_url": r.CloudInitURL,
+				},
+			},
+		})
+	}
+	s.mu.Unlock()
+
+	writeData(w, http.StatusOK, "", map[string]any{
+		"groups": []map[string]any{{
+			"name":        "Linux",
+			"description": "Linux distributions",
+			"tags":        []string{},
+			"recipes":     recipes,
+		}},
+		"other_recipes": []any{},
+	})
+}
+
+func (s *Server) handleListInstanceTypes(w http.ResponseWriter, r *http.Request) {
+	var data json.RawMessage
+	version, ok := s.decodeRequest(w, r, &data)
+	if !ok {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeData(w, http.StatusOK, version, map[string]any{"instance_types": s.instanceTypes})
+}
+
+func (s *Server) handleListSSHKeys(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := s.sshKeys
+	if keys == nil {
+		keys = []cloudriftapi.SshKey{}
+	}
+	writeData(w, http.StatusOK, "", map[string]any{"keys": keys})
+}
+
+func (s *Server) handleAddSSHKey(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Name      string `json:"name"`
+		PublicKey string `json:"public_key"`
+	}
+	version, ok := s.decodeRequest(w, r, &data)
+	if !ok {
+		return
+	}
+	if data.Name == "" || data.PublicKey == "" {
+		writeError(w, http.StatusBadRequest, "name and public_key are required")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slices.ContainsFunc(s.sshKeys, func(k cloudriftapi.SshKey) bool { return k.Name == data.Name }) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("ssh key %q already exists", data.Name))
+		return
+	}
+	s.nextID++
+	key := cloudriftapi.SshKey{Id: "key-" + strconv.Itoa(s.nextID), Name: data.Name, PublicKey: data.PublicKey}
+	s.sshKeys = append(s.sshKeys, key)
+	writeData(w, http.StatusCreated, version, map[string]any{"public_key": key})
+}
+
+func (s *Server) handleDeleteSSHKey(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.sshKeys, func(k cloudriftapi.SshKey) bool { return k.Id == id })
+	if i < 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("ssh key %q not found", id))
+		return
+	}
+	s.sshKeys = slices.Delete(s.sshKeys, i, i+1)
+	w.WriteHeader(http.StatusOK)
+}
+
+func writeData(w http.ResponseWriter, status int, version string, data any) {
+	if version == "" {
+		version = cloudriftapi.ProtoUpcoming
+	}
+	b, err := json.Marshal(map[string]any{"version": version, "data": data})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(b)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	b, _ := json.Marshal(map[string]string{"error": message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(b)
+}
+
+func ptr[T any](v T) *T { return &v }
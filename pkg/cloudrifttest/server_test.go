@@ -0,0 +1,391 @@
+package cloudrifttest
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/berops/terraform-provider-cloudrift/pkg/cloudriftapi"
+)
+
+func newClient(t *testing.T, s *Server) *cloudriftapi.HttpClient {
+	t.Helper()
+
+	client, err := cloudriftapi.NewCustom(s.URL, "token", "", "", cloudriftapi.WithPollInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	return client
+}
+
+func rent(t *testing.T, client *cloudriftapi.HttpClient) string {
+	t.Helper()
+
+	resp, err := client.RentPublicInstanceVM(cloudriftapi.RentVMOptions{
+		Recipe:       DefaultRecipes[0].Name,
+		Datacenter:   DefaultDatacenter,
+		InstanceType: "rtx49-10c-kn.1",
+		PublicKeys:   []string{"ssh-ed25519 AAAA test"},
+	})
+	if err != nil {
+		t.Fatalf("RentPublicInstanceVM: %v", err)
+	}
+	return resp.Data.InstanceIds[0]
+}
+
+// poll looks the instance up the way the provider waits on it, whatever its
+// status.
+func poll(client *cloudriftapi.HttpClient, id string) (*cloudriftapi.InstanceAndUsageInfo, error) {
+	res := <-client.PollInstance(id)
+	return res.Instance, res.Err
+}
+
+func wantStatus(t *testing.T, client *cloudriftapi.HttpClient, id string, want cloudriftapi.InstanceStatus) *cloudriftapi.InstanceAndUsageInfo {
+	t.Helper()
+
+	inst, err := poll(client, id)
+	if err != nil {
+		t.Fatalf("PollInstance(%q): %v", id, err)
+	}
+	if inst.Status != want {
+		t.Fatalf("expected instance %q to be %s, got %s", id, want, inst.Status)
+	}
+	return inst
+}
+
+func Test_Server_InstanceLifecycle(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer(WithTimeline(Timeline{Initializing: 100 * time.Millisecond, Deactivating: 100 * time.Millisecond}))
+	defer s.Close()
+	client := newClient(t, s)
+
+	id := rent(t, client)
+	if inst := wantStatus(t, client, id, cloudriftapi.InstanceStatusInitializing); inst.HostAddress != nil {
+		t.Errorf("an initializing instance must not have an address, got %q", *inst.HostAddress)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	inst := wantStatus(t, client, id, cloudriftapi.InstanceStatusActive)
+	if inst.HostAddress == nil || len(inst.VirtualMachines) != 1 || !inst.VirtualMachines[0].Ready {
+		t.Errorf("expected a ready instance with an address, got %+v", inst)
+	}
+
+	if err := client.TerminateInstance(id); err != nil {
+		t.Fatalf("TerminateInstance: %v", err)
+	}
+	wantStatus(t, client, id, cloudriftapi.InstanceStatusDeactivating)
+
+	time.Sleep(100 * time.Millisecond)
+	wantStatus(t, client, id, cloudriftapi.InstanceStatusInactive)
+	if _, err := client.GetInstance(id); !errors.Is(err, cloudriftapi.ErrNotFound) {
+		t.Errorf("expected an inactive instance to be not found, got %v", err)
+	}
+}
+
+func Test_Server_TimelineVariants(t *testing.T) {
+	t.Parallel()
+
+	failing := NewServer(WithTimeline(Timeline{Outcome: cloudriftapi.InstanceStatusFailed}))
+	defer failing.Close()
+	client := newClient(t, failing)
+	wantStatus(t, client, rent(t, client), cloudriftapi.InstanceStatusFailed)
+
+	delayed := NewServer(WithTimeline(Timeline{Unlisted: 100 * time.Millisecond}))
+	defer delayed.Close()
+	client = newClient(t, delayed)
+	id := rent(t, client)
+	if _, err := poll(client, id); !errors.Is(err, cloudriftapi.ErrNotFound) {
+		t.Errorf("expected a just rented instance to be unlisted, got %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	wantStatus(t, client, id, cloudriftapi.InstanceStatusActive)
+
+	stuck := NewServer(WithTimeline(Timeline{Deactivating: -1}))
+	defer stuck.Close()
+	client = newClient(t, stuck)
+	id = rent(t, client)
+	if err := client.TerminateInstance(id); err != nil {
+		t.Fatalf("TerminateInstance: %v", err)
+	}
+	wantStatus(t, client, id, cloudriftapi.InstanceStatusDeactivating)
+
+	if err := stuck.SetInstanceStatus(id, cloudriftapi.InstanceStatusInactive); err != nil {
+		t.Fatal(err)
+	}
+	wantStatus(t, client, id, cloudriftapi.InstanceStatusInactive)
+}
+
+func Test_Server_ClustersAndTeams(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer()
+	defer s.Close()
+
+	team, err := cloudriftapi.NewCustom(s.URL, "token", "", "team-1")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	personal := newClient(t, s)
+
+	for range 2 {
+		if _, err := team.RentPublicInstanceVM(cloudriftapi.RentVMOptions{
+			Recipe:       DefaultRecipes[1].Name,
+			Datacenter:   DefaultDatacenter,
+			InstanceType: "rtx49-10c-kn.1",
+			PublicKeys:   []string{"ssh-ed25519 AAAA test"},
+			ClusterName:  "workers",
+		}); err != nil {
+			t.Fatalf("RentPublicInstanceVM: %v", err)
+		}
+	}
+	rent(t, personal)
+
+	var rented struct {
+		TeamID *string `json:"team_id"`
+	}
+	if err := s.LastRequest("/api/v1/instances/rent", &rented); err != nil || rented.TeamID != nil {
+		t.Errorf("expected the last rent to be personal, got %v, %v", rented.TeamID, err)
+	}
+	if cluster := s.ClusterInstances("workers"); len(cluster) != 2 {
+		t.Errorf("expected the 2 instances of the cluster, got %v", cluster)
+	}
+
+	if listed, err := team.ListInstances(); err != nil || len(listed.Data.Instances) != 2 {
+		t.Errorf("expected the 2 instances of the team, got %v, %v", listed, err)
+	}
+	if listed, err := personal.ListInstances(); err != nil || len(listed.Data.Instances) != 1 {
+		t.Errorf("expected the personal instance, got %v, %v", listed, err)
+	}
+
+	cluster, err := team.ListClusterInstances("workers")
+	if err != nil || len(cluster) != 2 {
+		t.Fatalf("expected the 2 instances of the cluster, got %v, %v", cluster, err)
+	}
+	if err := team.TerminateInstances([]string{cluster[0].Id, cluster[1].Id}); err != nil {
+		t.Fatalf("TerminateInstances: %v", err)
+	}
+	cluster, err = team.ListClusterInstances("workers")
+	if err != nil {
+		t.Fatalf("ListClusterInstances: %v", err)
+	}
+	for _, inst := range cluster {
+		if inst.Status != cloudriftapi.InstanceStatusInactive {
+			t.Errorf("expected instance %q of the terminated cluster to be inactive, got %s", inst.Id, inst.Status)
+		}
+	}
+}
+
+func Test_Server_SSHKeys(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer()
+	defer s.Close()
+	client := newClient(t, s)
+
+	added, err := client.AddSSHKey("primary", "ssh-ed25519 AAAA primary")
+	if err != nil {
+		t.Fatalf("AddSSHKey: %v", err)
+	}
+	if _, err := client.AddSSHKey("primary", "ssh-ed25519 AAAA other"); err == nil {
+		t.Error("expected a duplicate key name to be rejected")
+	}
+
+	keys, err := client.ListSSHKeys()
+	if err != nil || len(keys) != 1 || keys[0] != added.Data.PublicKey {
+		t.Fatalf("expected the added key, got %v, %v", keys, err)
+	}
+
+	if err := client.DeleteSSHKey(added.Data.PublicKey.Id); err != nil {
+		t.Fatalf("DeleteSSHKey: %v", err)
+	}
+	if err := client.DeleteSSHKey(added.Data.PublicKey.Id); !errors.Is(err, cloudriftapi.ErrNotFound) {
+		t.Errorf("expected deleting a missing key to be not found, got %v", err)
+	}
+}
+
+func Test_Server_Credentials(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer()
+	defer s.Close()
+	client := newClient(t, s)
+
+	id := rent(t, client)
+	listed, err := client.GetInstance(id)
+	if err != nil {
+		t.Fatalf("GetInstance: %v", err)
+	}
+	if hidden, err := listed.VirtualMachines[0].LoginInfo.AsInstanceLoginInfo2(); err != nil || hidden.HiddenPassword.Username != LoginUsername {
+		t.Errorf("expected the password to be withheld without the credentials mask, got %+v", listed.VirtualMachines[0].LoginInfo)
+	}
+
+	inst, err := client.GetInstanceCredentials(id)
+	if err != nil {
+		t.Fatalf("GetInstanceCredentials: %v", err)
+	}
+	login, err := inst.VirtualMachines[0].LoginInfo.AsInstanceLoginInfo0()
+	if err != nil || login.UsernameAndPassword.Username != LoginUsername || login.UsernameAndPassword.Password == "" {
+		t.Errorf("expected the password with the credentials mask, got %+v", inst.VirtualMachines[0].LoginInfo)
+	}
+	if inst.Instructions == nil || len(inst.Instructions.PlaceholderValues) != 3 {
+		t.Errorf("expected the instructions to log in, got %+v", inst.Instructions)
+	}
+}
+
+func Test_Server_Account(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer(
+		WithBalance(12.5),
+		WithSavedEnvironments(cloudriftapi.SavedEnvironment{Id: "env-1", NodeId: "node-1"}),
+		WithTeams(cloudriftapi.TeamInfo{Id: "team-1", Name: "research", AccountInfo: &cloudriftapi.TeamAccountInfo{Balance: 500}}),
+		WithTransactions(
+			cloudriftapi.Transaction{Amount: 100, CreatedAt: "2025-01-02T00:00:00Z"},
+			cloudriftapi.Transaction{Amount: 200, CreatedAt: "2025-02-02T00:00:00Z"},
+		),
+		WithInstanceMetrics(cloudriftapi.InstanceMetrics{InstanceId: "instance-1", NodeId: "node-1"}),
+	)
+	defer s.Close()
+	client := newClient(t, s)
+
+	if balance, err := client.Balance(); err != nil || balance != 12.5 {
+		t.Errorf("expected the balance 12.5, got %v, %v", balance, err)
+	}
+
+	state, err := client.GetAutoTopUp()
+	if err != nil || state.Data.Settings != nil || state.Data.Status != cloudriftapi.AutoTopUpStatusOff {
+		t.Fatalf("expected auto top-up to be off, got %+v, %v", state, err)
+	}
+	state, err = client.UpdateAutoTopUp(cloudriftapi.AutoTopUpSettings{Enabled: true, ThresholdCents: 1000, TopUpAmountCents: 5000})
+	if err != nil || state.Data.Status != cloudriftapi.AutoTopUpStatusActive {
+		t.Fatalf("expected auto top-up to be active, got %+v, %v", state, err)
+	}
+	if settings := s.AutoTopUp(); settings == nil || settings.ThresholdCents != 1000 {
+		t.Errorf("expected the updated settings, got %+v", settings)
+	}
+
+	if envs, err := client.ListSavedEnvironments(); err != nil || len(envs) != 1 || envs[0].Id != "env-1" {
+		t.Errorf("expected the saved environment, got %v, %v", envs, err)
+	}
+
+	if info, err := client.GetTeamAccountInfo("team-1"); err != nil || info.Balance != 500 {
+		t.Errorf("expected the account info of the team, got %+v, %v", info, err)
+	}
+	if _, err := client.GetTeamAccountInfo("team-2"); !errors.Is(err, cloudriftapi.ErrNotFound) {
+		t.Errorf("expected an unknown team to be not found, got %v", err)
+	}
+
+	january := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if txs, err := client.ListTransactions(january, january.AddDate(0, 1, 0)); err != nil || len(txs) != 1 || txs[0].Amount != 100 {
+		t.Errorf("expected the transaction of January, got %v, %v", txs, err)
+	}
+
+	if metrics, err := client.GetInstanceMetrics([]string{"instance-1", "instance-2"}); err != nil || len(metrics) != 1 || metrics[0].NodeId != "node-1" {
+		t.Errorf("expected the metrics of instance-1, got %v, %v", metrics, err)
+	}
+}
+
+func Test_Server_RentValidation(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer()
+	defer s.Close()
+	client := newClient(t, s)
+
+	for _, opts := range []cloudriftapi.RentVMOptions{
+		{InstanceType: "h100-80c.1", Datacenter: DefaultDatacenter},
+		{InstanceType: "rtx49-10c-kn.1", Datacenter: "eu-west-1"},
+	} {
+		opts.Recipe, opts.PublicKeys = DefaultRecipes[0].Name, []string{"ssh-ed25519 AAAA test"}
+		if _, err := client.RentPublicInstanceVM(opts); err == nil || !strings.Contains(err.Error(), "400") {
+			t.Errorf("expected renting %s in %s to be rejected, got %v", opts.InstanceType, opts.Datacenter, err)
+		}
+	}
+	if len(s.Instances()) != 0 {
+		t.Errorf("expected no instance to be rented, got %v", s.Instances())
+	}
+}
+
+func Test_Server_Faults(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer()
+	defer s.Close()
+	client := newClient(t, s)
+
+	s.InjectFault(Fault{Path: "/api/v1/instances/rent", Status: http.StatusServiceUnavailable, Body: "capacity", Times: 1})
+	if _, err := client.RentPublicInstanceVM(cloudriftapi.RentVMOptions{
+		Recipe: DefaultRecipes[0].Name, Datacenter: DefaultDatacenter, InstanceType: "rtx49-10c-kn.1", PublicKeys: []string{"k"},
+	}); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("expected the injected fault, got %v", err)
+	}
+	rent(t, client)
+
+	if got := s.Requests("/api/v1/instances/rent"); got != 2 {
+		t.Errorf("expected 2 rent requests, got %d", got)
+	}
+
+	s.InjectFault(Fault{Path: "/api/v1/instances/list", Status: http.StatusForbidden, Match: `"instance-9"`})
+	if _, err := client.GetInstance("instance-9"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected the fault matching the instance, got %v", err)
+	}
+	if _, err := client.ListInstances(); err != nil {
+		t.Errorf("expected the fault to spare the other requests, got %v", err)
+	}
+	if header := s.LastRequestHeader("/api/v1/instances/list"); header.Get("X-API-KEY") != "token" {
+		t.Errorf("expected the headers of the last request, got %v", header)
+	}
+}
+
+func Test_Server_RateLimitAndLatency(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer(WithRateLimit(1, time.Minute))
+	defer s.Close()
+
+	// NewCustom sends more than one request.
+	_, err := cloudriftapi.NewCustom(s.URL, "token", "", "")
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("expected the rate limit to be hit, got %v", err)
+	}
+
+	slow := NewServer(WithLatency(100 * time.Millisecond))
+	defer slow.Close()
+	_, err = cloudriftapi.NewCustom(slow.URL, "token", "", "", cloudriftapi.WithTimeout(50*time.Millisecond))
+	if err == nil {
+		t.Fatal("expected the request to time out")
+	}
+
+	slowed := NewServer()
+	defer slowed.Close()
+	client, err := cloudriftapi.NewCustom(slowed.URL, "token", "", "", cloudriftapi.WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	slowed.SetLatency(100 * time.Millisecond)
+	if _, err := client.ListInstances(); err == nil {
+		t.Error("expected the request to time out once slowed down")
+	}
+}
+
+func Test_Server_AuthAndProtoVersions(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer(WithToken("secret"), WithProtoVersions(cloudriftapi.Proto20250610, cloudriftapi.Proto20250529))
+	defer s.Close()
+
+	if _, err := cloudriftapi.NewCustom(s.URL, "wrong", "", ""); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected a wrong token to be rejected, got %v", err)
+	}
+
+	client, err := cloudriftapi.NewCustom(s.URL, "secret", "", "")
+	if err != nil {
+		t.Fatalf("NewCustom: %v", err)
+	}
+	if client.ProtoVersion != cloudriftapi.Proto20250610 {
+		t.Errorf("expected the negotiated version %q, got %q", cloudriftapi.Proto20250610, client.ProtoVersion)
+	}
+}